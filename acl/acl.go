@@ -0,0 +1,145 @@
+// Package acl implements the users and permissions of tiny-redis, modelled
+// after the ACL system of Redis 6. A user has passwords and rules that
+// grant commands, keys and Pub/Sub channels.
+package acl
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultUser is the user new connections are authenticated as when it
+// has no password.
+const DefaultUser = "default"
+
+var (
+	ErrDeleteDefault   = errors.New("The 'default' user cannot be removed")
+	ErrInvalidUsername = errors.New("Usernames can't contain spaces or null characters")
+)
+
+// Categories are the command categories rules refer to as @name.
+var Categories = []string{
+	"keyspace", "read", "write", "set", "sortedset", "list", "hash", "string",
+	"bitmap", "hyperloglog", "geo", "stream", "pubsub", "admin", "fast",
+	"slow", "blocking", "dangerous", "connection", "transaction", "scripting",
+}
+
+// Resolver returns the categories of a command, e.g. "@read", "@string" and
+// "@fast" for "get". Subcommands are named like "config|get". ok is false
+// for unknown commands.
+type Resolver func(name string) (categories []string, ok bool)
+
+// ACL holds the users of a server and the log of denied commands. It is
+// safe for concurrent use. Users are never modified in place, SetUser
+// replaces them, so a *User can be read without locking.
+type ACL struct {
+	mu      sync.RWMutex
+	users   map[string]*User
+	resolve Resolver
+	log     []*LogEntry
+	lastID  int64
+}
+
+// New returns an ACL with only the default user, which can run every
+// command without a password. resolve validates the command rules.
+func New(resolve Resolver) *ACL {
+	return &ACL{
+		users:   map[string]*User{DefaultUser: newDefaultUser()},
+		resolve: resolve,
+	}
+}
+
+func newDefaultUser() *User {
+	u := newUser(DefaultUser)
+	u.enabled = true
+	u.nopass = true
+	u.keys = []keyPattern{{pattern: "*", perm: Read | Write}}
+	u.channels = []string{"*"}
+	u.commands = []string{"+@all"}
+	return u
+}
+
+// User returns the user called name.
+func (a *ACL) User(name string) (*User, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	u, ok := a.users[name]
+	return u, ok
+}
+
+// Users returns every user sorted by name.
+func (a *ACL) Users() []*User {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	users := make([]*User, 0, len(a.users))
+	for _, u := range a.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	return users
+}
+
+// SetUser applies rules to the user called name, creating it if needed. A
+// new user starts disabled, without passwords and permissions. Either all
+// rules are applied or, on error, none.
+func (a *ACL) SetUser(name string, rules ...string) error {
+	if strings.ContainsAny(name, " \x00") {
+		return ErrInvalidUsername
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var u *User
+	if old, ok := a.users[name]; ok {
+		u = old.clone()
+	} else {
+		u = newUser(name)
+	}
+	for _, r := range rules {
+		if err := u.apply(r, a.resolve); err != nil {
+			return err
+		}
+	}
+	a.users[name] = u
+	return nil
+}
+
+// DelUser removes the user called name and reports whether it existed.
+func (a *ACL) DelUser(name string) (bool, error) {
+	if name == DefaultUser {
+		return false, ErrDeleteDefault
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.users[name]
+	delete(a.users, name)
+	return ok, nil
+}
+
+// Authenticate reports whether pass is a password of the user called name,
+// which must be enabled.
+func (a *ACL) Authenticate(name, pass string) bool {
+	u, ok := a.User(name)
+	return ok && u.enabled && u.CheckPassword(pass)
+}
+
+// NoAuthUser returns the user new connections are authenticated as: the
+// default user when it is enabled and has no password, and "" otherwise.
+func (a *ACL) NoAuthUser() string {
+	u, ok := a.User(DefaultUser)
+	if ok && u.enabled && u.nopass {
+		return DefaultUser
+	}
+	return ""
+}
+
+// SetRequirePass sets the password of the default user, like the
+// requirepass configuration of Redis. An empty pass removes it.
+func (a *ACL) SetRequirePass(pass string) {
+	rule := "nopass"
+	if pass != "" {
+		rule = ">" + pass
+	}
+	a.SetUser(DefaultUser, "resetpass", rule)
+}
@@ -0,0 +1,220 @@
+package acl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// resolve knows a few commands, in the format of the command table.
+func resolve(name string) ([]string, bool) {
+	cats, ok := map[string][]string{
+		"get":        {"@read", "@string", "@fast"},
+		"set":        {"@write", "@string", "@slow"},
+		"config":     {"@slow"},
+		"config|get": {"@admin", "@slow", "@dangerous"},
+		"config|set": {"@admin", "@slow", "@dangerous"},
+	}[name]
+	return cats, ok
+}
+
+func Test__SetUser(t *testing.T) {
+	tests := []struct {
+		rules    []string
+		expected string
+	}{
+		{nil, "user alice off resetchannels -@all"},
+		{[]string{"on", ">pw", "~cache:*", "+get"}, "user alice on #" + HashPassword("pw") + " ~cache:* resetchannels -@all +get"},
+		{[]string{"on", "nopass", "allkeys", "allchannels", "allcommands"}, "user alice on nopass ~* &* +@all"},
+		{[]string{"%R~r:*", "%W~w:*", "%RW~rw:*", "&news.*"}, "user alice off %R~r:* %W~w:* ~rw:* &news.* -@all"},
+		{[]string{"+@all", "-config|set", "-@write"}, "user alice off resetchannels +@all -config|set -@write"},
+		{[]string{"+get", "-@all", "+set"}, "user alice off resetchannels -@all +set"},
+		{[]string{"on", ">a", ">b", "<a", "~x", "reset"}, "user alice off resetchannels -@all"},
+	}
+	for _, tt := range tests {
+		a := New(resolve)
+		if err := a.SetUser("alice", tt.rules...); err != nil {
+			t.Fatalf("SetUser(%q): %v", tt.rules, err)
+		}
+		u, _ := a.User("alice")
+		if got := u.String(); got != tt.expected {
+			t.Errorf("SetUser(%q): got %q want %q", tt.rules, got, tt.expected)
+		}
+	}
+}
+
+func Test__SetUserErrors(t *testing.T) {
+	tests := []struct {
+		rule   string
+		reason string
+	}{
+		{"+nosuchcommand", reasonUnknown},
+		{"+@nosuchcategory", reasonUnknown},
+		{"+config|nosuch", reasonNoSubcommand},
+		{"<notapassword", reasonNoPassword},
+		{"#abc", reasonInvalidHash},
+		{"%X~key", reasonSyntax},
+		{"bogus", reasonSyntax},
+	}
+	for _, tt := range tests {
+		a := New(resolve)
+		a.SetUser("alice", "on", "+get")
+		err := a.SetUser("alice", "off", tt.rule)
+		re, ok := err.(*RuleError)
+		if !ok || re.Reason != tt.reason || re.Rule != tt.rule {
+			t.Errorf("SetUser(%q): got %v want %q", tt.rule, err, tt.reason)
+		}
+		// nothing is applied when a rule fails
+		if u, _ := a.User("alice"); !u.Enabled() {
+			t.Errorf("SetUser(%q): rules before the error were applied", tt.rule)
+		}
+	}
+	if err := New(resolve).SetUser("has space"); err != ErrInvalidUsername {
+		t.Errorf("SetUser(has space): got %v want %v", err, ErrInvalidUsername)
+	}
+}
+
+func Test__Permissions(t *testing.T) {
+	a := New(resolve)
+	a.SetUser("alice", "on", "+@all", "-@dangerous", "+config|get", "%R~shared:*", "~alice:*", "&news.*")
+	u, _ := a.User("alice")
+
+	commands := []struct {
+		name     string
+		expected bool
+	}{
+		{"get", true},
+		{"config|get", true},
+		{"config|set", false},
+	}
+	for _, tt := range commands {
+		cats, _ := resolve(tt.name)
+		if got := u.CanRun(tt.name, cats); got != tt.expected {
+			t.Errorf("CanRun(%s): got %v want %v", tt.name, got, tt.expected)
+		}
+	}
+
+	keys := []struct {
+		key      string
+		perm     Perm
+		expected bool
+	}{
+		{"alice:1", Read | Write, true},
+		{"shared:1", Read, true},
+		{"shared:1", Write, false},
+		{"bob:1", Read, false},
+	}
+	for _, tt := range keys {
+		if got := u.CanAccessKey([]byte(tt.key), tt.perm); got != tt.expected {
+			t.Errorf("CanAccessKey(%s, %d): got %v want %v", tt.key, tt.perm, got, tt.expected)
+		}
+	}
+	if !u.CanAccessChannel([]byte("news.tech")) || u.CanAccessChannel([]byte("sport")) {
+		t.Errorf("CanAccessChannel: got the wrong permissions for &news.*")
+	}
+	// a container rule covers its subcommands
+	a.SetUser("bob", "on", "+config")
+	bob, _ := a.User("bob")
+	if !bob.CanRun("config|set", nil) || bob.CanRun("get", nil) {
+		t.Errorf("+config: got the wrong permissions")
+	}
+}
+
+func Test__Authenticate(t *testing.T) {
+	a := New(resolve)
+	if a.NoAuthUser() != DefaultUser || !a.Authenticate(DefaultUser, "anything") {
+		t.Fatalf("the default user must not need a password")
+	}
+	a.SetRequirePass("secret")
+	if a.NoAuthUser() != "" || a.Authenticate(DefaultUser, "wrong") || !a.Authenticate(DefaultUser, "secret") {
+		t.Errorf("requirepass: got the wrong authentication")
+	}
+	a.SetUser("alice", ">pw")
+	if a.Authenticate("alice", "pw") {
+		t.Errorf("a disabled user authenticated")
+	}
+	a.SetUser("alice", "on")
+	if !a.Authenticate("alice", "pw") || a.Authenticate("nobody", "pw") {
+		t.Errorf("alice: got the wrong authentication")
+	}
+	if _, err := a.DelUser(DefaultUser); err != ErrDeleteDefault {
+		t.Errorf("DelUser(default): got %v", err)
+	}
+}
+
+func Test__Log(t *testing.T) {
+	a := New(resolve)
+	a.AddLog(LogEntry{Reason: ReasonCommand, Object: "get", Username: "alice"}, 2)
+	a.AddLog(LogEntry{Reason: ReasonKey, Object: "k", Username: "alice"}, 2)
+	a.AddLog(LogEntry{Reason: ReasonCommand, Object: "get", Username: "alice", ClientInfo: "id=2"}, 2)
+	log := a.Log(10)
+	if len(log) != 2 || log[0].Object != "get" || log[0].Count != 2 || log[0].ClientInfo != "id=2" || log[1].Object != "k" {
+		t.Fatalf("similar entries weren't merged: %+v", log)
+	}
+	a.AddLog(LogEntry{Reason: ReasonAuth, Object: "AUTH", Username: "bob"}, 2)
+	log = a.Log(10)
+	if len(log) != 2 || log[0].Reason != ReasonAuth || log[0].EntryID != 2 || log[1].Object != "get" {
+		t.Errorf("the log wasn't trimmed to its max length: %+v", log)
+	}
+	a.ResetLog()
+	if len(a.Log(10)) != 0 {
+		t.Errorf("ResetLog: the log isn't empty")
+	}
+}
+
+func Test__SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "users.acl")
+
+	a := New(resolve)
+	a.SetRequirePass("secret")
+	a.SetUser("alice", "on", ">pw", "%R~shared:*", "&news.*", "-@all", "+get")
+	if err := a.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	b := New(resolve)
+	if err := b.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{DefaultUser, "alice"} {
+		ua, _ := a.User(name)
+		ub, ok := b.User(name)
+		if !ok || ua.String() != ub.String() {
+			t.Errorf("%s: got %v want %q", name, ub, ua.String())
+		}
+	}
+
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"user alice on +nosuch\n", "users.acl:1: Unknown command or category name in ACL '+nosuch'"},
+		{"# users\n\nuser alice on\nuser alice off\n", "users.acl:4: Duplicate user 'alice' found"},
+		{"alice on\n", "users.acl:1: should start with user keyword followed by the username"},
+	}
+	for _, tt := range tests {
+		ioutil.WriteFile(path, []byte(tt.content), 0600)
+		err := b.Load(path)
+		if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+			t.Errorf("Load(%q): got %v want %q", tt.content, err, tt.err)
+		}
+	}
+	// a failed load keeps the users
+	if _, ok := b.User("alice"); !ok {
+		t.Errorf("a failed Load removed the users")
+	}
+	// the default user is created when missing
+	ioutil.WriteFile(path, []byte("user alice on nopass +@all\n"), 0600)
+	if err := b.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if b.NoAuthUser() != DefaultUser {
+		t.Errorf("Load without a default user: got %q", b.NoAuthUser())
+	}
+}
@@ -0,0 +1,100 @@
+package acl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/config"
+)
+
+// Load replaces the users with the ones defined in the ACL file at path,
+// one "user <name> <rules...>" line each as written by Save. A missing
+// default user is created with its initial permissions. Nothing changes
+// if the file has an error.
+func (a *ACL) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	users := make(map[string]*User)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		fail := func(msg string) error {
+			return fmt.Errorf("%s:%d: %s", path, n, msg)
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		args, err := config.SplitArgs(line)
+		if err != nil {
+			return fail(err.Error())
+		}
+		if args[0] != "user" || len(args) < 2 {
+			return fail("should start with user keyword followed by the username")
+		}
+		name := args[1]
+		if strings.ContainsAny(name, " \x00") {
+			return fail(ErrInvalidUsername.Error())
+		}
+		if _, ok := users[name]; ok {
+			return fail(fmt.Sprintf("Duplicate user '%s' found", name))
+		}
+		u := newUser(name)
+		for _, r := range args[2:] {
+			if err := u.apply(r, a.resolve); err != nil {
+				var re *RuleError
+				if errors.As(err, &re) {
+					return fail(fmt.Sprintf("%s '%s'", re.Reason, re.Rule))
+				}
+				return fail(err.Error())
+			}
+		}
+		users[name] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if _, ok := users[DefaultUser]; !ok {
+		users[DefaultUser] = newDefaultUser()
+	}
+
+	a.mu.Lock()
+	a.users = users
+	a.mu.Unlock()
+	return nil
+}
+
+// Save writes every user to the ACL file at path. The file is replaced
+// atomically.
+func (a *ACL) Save(path string) error {
+	var sb strings.Builder
+	for _, u := range a.Users() {
+		sb.WriteString(u.String())
+		sb.WriteByte('\n')
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "temp-acl-*.acl")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(sb.String()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
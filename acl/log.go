@@ -0,0 +1,84 @@
+package acl
+
+import "time"
+
+// Reasons an entry is logged for.
+const (
+	ReasonAuth    = "auth"
+	ReasonCommand = "command"
+	ReasonKey     = "key"
+	ReasonChannel = "channel"
+)
+
+// logGroupTime is how long a denial is merged into a similar entry rather
+// than logged anew, as in Redis.
+const logGroupTime = 60 * time.Second
+
+// LogEntry records a denied command or a failed authentication, see
+// ACL LOG.
+type LogEntry struct {
+	// Count is the number of similar denials merged into the entry.
+	Count int
+	// Reason is one of the Reason constants.
+	Reason string
+	// Context is "toplevel" for commands sent by clients.
+	Context string
+	// Object is the denied command, key or channel.
+	Object   string
+	Username string
+	// ClientInfo describes the connection, like CLIENT INFO.
+	ClientInfo string
+	EntryID    int64
+	Created    time.Time
+	Updated    time.Time
+}
+
+func (e *LogEntry) similar(o *LogEntry) bool {
+	return e.Reason == o.Reason && e.Context == o.Context && e.Object == o.Object && e.Username == o.Username
+}
+
+// AddLog records e, keeping at most max entries. An entry similar to a
+// recent one increments its Count instead.
+func (a *ACL) AddLog(e LogEntry, max int) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, old := range a.log {
+		if old.similar(&e) && now.Sub(old.Updated) < logGroupTime {
+			old.Count++
+			old.Updated = now
+			old.ClientInfo = e.ClientInfo
+			// move it to the front, the log is newest first
+			copy(a.log[1:i+1], a.log[:i])
+			a.log[0] = old
+			return
+		}
+	}
+	a.lastID++
+	e.Count, e.EntryID, e.Created, e.Updated = 1, a.lastID-1, now, now
+	a.log = append([]*LogEntry{&e}, a.log...)
+	if len(a.log) > max {
+		a.log = a.log[:max]
+	}
+}
+
+// Log returns up to count entries, newest first.
+func (a *ACL) Log(count int) []LogEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if count > len(a.log) {
+		count = len(a.log)
+	}
+	entries := make([]LogEntry, count)
+	for i := range entries {
+		entries[i] = *a.log[i]
+	}
+	return entries
+}
+
+// ResetLog clears the log.
+func (a *ACL) ResetLog() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.log = nil
+}
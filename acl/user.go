@@ -0,0 +1,359 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/glob"
+)
+
+// Perm is the access a command needs to a key.
+type Perm int
+
+const (
+	Read Perm = 1 << iota
+	Write
+)
+
+// RuleError reports a rule that couldn't be applied to a user.
+type RuleError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("Error in ACL SETUSER modifier '%s': %s", e.Rule, e.Reason)
+}
+
+const (
+	reasonSyntax       = "Syntax error"
+	reasonUnknown      = "Unknown command or category name in ACL"
+	reasonNoPassword   = "The password you are trying to remove from the user does not exist"
+	reasonInvalidHash  = "The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters"
+	reasonNoSubcommand = "Allowing first-arg of a subcommand is not supported"
+)
+
+type keyPattern struct {
+	pattern string
+	perm    Perm
+}
+
+func (k keyPattern) String() string {
+	switch k.perm {
+	case Read:
+		return "%R~" + k.pattern
+	case Write:
+		return "%W~" + k.pattern
+	}
+	return "~" + k.pattern
+}
+
+// User is a set of credentials and permissions. It is immutable once
+// stored in an ACL.
+type User struct {
+	Name string
+
+	enabled bool
+	nopass  bool
+	// passwords holds the SHA-256 of each password in hex
+	passwords []string
+	keys      []keyPattern
+	channels  []string
+	// commands holds the command rules in the order they were given, such
+	// as "+@all" or "-config|set". The last rule matching a command wins.
+	commands []string
+}
+
+func newUser(name string) *User {
+	return &User{Name: name, commands: []string{"-@all"}}
+}
+
+func (u *User) clone() *User {
+	c := *u
+	c.passwords = append([]string(nil), u.passwords...)
+	c.keys = append([]keyPattern(nil), u.keys...)
+	c.channels = append([]string(nil), u.channels...)
+	c.commands = append([]string(nil), u.commands...)
+	return &c
+}
+
+// HashPassword returns the hex encoded SHA-256 of pass, the form passwords
+// are stored and listed in.
+func HashPassword(pass string) string {
+	sum := sha256.Sum256([]byte(pass))
+	return hex.EncodeToString(sum[:])
+}
+
+// apply changes u according to a single rule, see ACL SETUSER.
+func (u *User) apply(rule string, resolve Resolver) error {
+	fail := func(reason string) error {
+		return &RuleError{Rule: rule, Reason: reason}
+	}
+	switch lower := strings.ToLower(rule); {
+	case lower == "on":
+		u.enabled = true
+	case lower == "off":
+		u.enabled = false
+	case lower == "nopass":
+		u.nopass = true
+		u.passwords = nil
+	case lower == "resetpass":
+		u.nopass = false
+		u.passwords = nil
+	case lower == "allkeys":
+		u.keys = []keyPattern{{pattern: "*", perm: Read | Write}}
+	case lower == "resetkeys":
+		u.keys = nil
+	case lower == "allchannels":
+		u.channels = []string{"*"}
+	case lower == "resetchannels":
+		u.channels = nil
+	case lower == "allcommands":
+		u.commands = []string{"+@all"}
+	case lower == "nocommands":
+		u.commands = []string{"-@all"}
+	case lower == "reset":
+		*u = *newUser(u.Name)
+	case lower == "sanitize-payload", lower == "skip-sanitize-payload":
+		// there is no RESTORE to sanitize payloads for
+	case strings.HasPrefix(rule, ">"):
+		u.addPassword(HashPassword(rule[1:]))
+	case strings.HasPrefix(rule, "#"):
+		if !validHash(rule[1:]) {
+			return fail(reasonInvalidHash)
+		}
+		u.addPassword(rule[1:])
+	case strings.HasPrefix(rule, "<"):
+		if !u.removePassword(HashPassword(rule[1:])) {
+			return fail(reasonNoPassword)
+		}
+	case strings.HasPrefix(rule, "!"):
+		if !validHash(rule[1:]) {
+			return fail(reasonInvalidHash)
+		}
+		if !u.removePassword(rule[1:]) {
+			return fail(reasonNoPassword)
+		}
+	case strings.HasPrefix(rule, "~"), strings.HasPrefix(rule, "%"):
+		k, ok := parseKeyPattern(rule)
+		if !ok {
+			return fail(reasonSyntax)
+		}
+		u.keys = append(u.keys, k)
+	case strings.HasPrefix(rule, "&"):
+		u.channels = append(u.channels, rule[1:])
+	case strings.HasPrefix(rule, "+"), strings.HasPrefix(rule, "-"):
+		target := lower[1:]
+		if target == "@all" {
+			u.commands = []string{lower}
+			return nil
+		}
+		if strings.HasPrefix(target, "@") {
+			if !isCategory(target[1:]) {
+				return fail(reasonUnknown)
+			}
+		} else if _, ok := resolve(target); !ok {
+			if i := strings.IndexByte(target, '|'); i > 0 {
+				if _, ok := resolve(target[:i]); ok {
+					return fail(reasonNoSubcommand)
+				}
+			}
+			return fail(reasonUnknown)
+		}
+		u.commands = append(u.commands, lower)
+	default:
+		return fail(reasonSyntax)
+	}
+	return nil
+}
+
+func (u *User) addPassword(hash string) {
+	u.nopass = false
+	for _, p := range u.passwords {
+		if p == hash {
+			return
+		}
+	}
+	u.passwords = append(u.passwords, hash)
+}
+
+func (u *User) removePassword(hash string) bool {
+	for i, p := range u.passwords {
+		if p == hash {
+			u.passwords = append(u.passwords[:i:i], u.passwords[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func validHash(h string) bool {
+	if len(h) != 2*sha256.Size {
+		return false
+	}
+	for _, c := range h {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseKeyPattern parses "~pattern" and the "%R~", "%W~" and "%RW~" forms
+// of Redis 7 that grant read or write access only.
+func parseKeyPattern(rule string) (keyPattern, bool) {
+	if rule[0] == '~' {
+		return keyPattern{pattern: rule[1:], perm: Read | Write}, true
+	}
+	i := strings.IndexByte(rule, '~')
+	if i < 2 {
+		return keyPattern{}, false
+	}
+	var perm Perm
+	for _, c := range strings.ToUpper(rule[1:i]) {
+		switch c {
+		case 'R':
+			perm |= Read
+		case 'W':
+			perm |= Write
+		default:
+			return keyPattern{}, false
+		}
+	}
+	return keyPattern{pattern: rule[i+1:], perm: perm}, true
+}
+
+func isCategory(name string) bool {
+	for _, c := range Categories {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled reports whether the user can authenticate.
+func (u *User) Enabled() bool {
+	return u.enabled
+}
+
+// NoPass reports whether any password authenticates the user.
+func (u *User) NoPass() bool {
+	return u.nopass
+}
+
+// Passwords returns the hashes of the passwords of the user.
+func (u *User) Passwords() []string {
+	return append([]string(nil), u.passwords...)
+}
+
+// CheckPassword reports whether pass authenticates the user.
+func (u *User) CheckPassword(pass string) bool {
+	if u.nopass {
+		return true
+	}
+	h := HashPassword(pass)
+	for _, p := range u.passwords {
+		if p == h {
+			return true
+		}
+	}
+	return false
+}
+
+// CanRun reports whether the user may run the command called name, which
+// belongs to categories. The rules for a container command, e.g. "+config",
+// apply to its subcommands too.
+func (u *User) CanRun(name string, categories []string) bool {
+	for i := len(u.commands) - 1; i >= 0; i-- {
+		r := u.commands[i]
+		allow, target := r[0] == '+', r[1:]
+		switch {
+		case target == "@all", target == name, strings.HasPrefix(name, target+"|"):
+			return allow
+		case target[0] == '@':
+			for _, c := range categories {
+				if c == target {
+					return allow
+				}
+			}
+		}
+	}
+	return false
+}
+
+// CanAccessKey reports whether the user has the perm access to key.
+func (u *User) CanAccessKey(key []byte, perm Perm) bool {
+	for _, k := range u.keys {
+		if k.perm&perm == perm && (k.pattern == "*" || glob.Match([]byte(k.pattern), key, false)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessChannel reports whether the user may publish or subscribe to
+// channel.
+func (u *User) CanAccessChannel(channel []byte) bool {
+	for _, c := range u.channels {
+		if c == "*" || glob.Match([]byte(c), channel, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// Flags returns "on" or "off", followed by "nopass" if set.
+func (u *User) Flags() []string {
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+// CommandRules returns the command rules, e.g. "-@all +get".
+func (u *User) CommandRules() string {
+	return strings.Join(u.commands, " ")
+}
+
+// KeyRules returns the key patterns, e.g. "~cache:* %R~config:*".
+func (u *User) KeyRules() string {
+	s := make([]string, len(u.keys))
+	for i, k := range u.keys {
+		s[i] = k.String()
+	}
+	return strings.Join(s, " ")
+}
+
+// ChannelRules returns the channel patterns, e.g. "&news.*".
+func (u *User) ChannelRules() string {
+	s := make([]string, len(u.channels))
+	for i, c := range u.channels {
+		s[i] = "&" + c
+	}
+	return strings.Join(s, " ")
+}
+
+// String describes the user as the rules that recreate it, in the format
+// of ACL LIST and the ACL file.
+func (u *User) String() string {
+	parts := append([]string{"user", u.Name}, u.Flags()...)
+	for _, p := range u.passwords {
+		parts = append(parts, "#"+p)
+	}
+	if len(u.keys) > 0 {
+		parts = append(parts, u.KeyRules())
+	}
+	if len(u.channels) > 0 {
+		parts = append(parts, u.ChannelRules())
+	} else {
+		parts = append(parts, "resetchannels")
+	}
+	return strings.Join(append(parts, u.CommandRules()), " ")
+}
@@ -0,0 +1,213 @@
+// Package client is a Go client for tiny-redis, and Redis servers in
+// general, built on the resp package. A Client keeps a pool of connections
+// and is safe for concurrent use.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+var (
+	// ErrNil is returned by the typed helpers when the server replies with
+	// a null, e.g. GET of a missing key.
+	ErrNil    = errors.New("client: nil reply")
+	ErrClosed = errors.New("client: closed")
+)
+
+// Error is an error reply sent by the server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Options configure a Client.
+type Options struct {
+	// Network is "tcp" or "unix", "tcp" if empty
+	Network string
+	// Addr is host:port for TCP or the socket path, "localhost:8001" if
+	// empty
+	Addr string
+	// Protocol is the RESP version, 2 or 3. Connections switch to RESP3 with
+	// HELLO 3.
+	Protocol int
+	// Username and Password authenticate connections with HELLO. Username
+	// defaults to "default" when only Password is set.
+	Username string
+	Password string
+	// ClientName is set on every connection with HELLO SETNAME.
+	ClientName string
+	// DB is the database selected on every connection, 0 by default. A
+	// connection that runs SELECT is switched back to it when released.
+	DB int
+	// PoolSize is the maximum number of open connections, 10 if 0.
+	PoolSize int
+	// DialTimeout limits connecting and the handshake, 5s if 0.
+	DialTimeout time.Duration
+	// OnPush is called with the RESP3 push messages, e.g. client side
+	// caching invalidations, received by regular connections. They are
+	// dropped if nil.
+	OnPush func(resp.Value)
+	// Dialer replaces net.Dialer, e.g. to connect over TLS.
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// Client is a pool of connections to a server.
+type Client struct {
+	opts Options
+	// sem holds a token for every connection in use
+	sem chan struct{}
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// New returns a client for the server described by opts. Connections are
+// opened on demand.
+func New(opts Options) *Client {
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	if opts.Addr == "" {
+		opts.Addr = "localhost:8001"
+	}
+	if opts.Protocol == 0 {
+		opts.Protocol = 2
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	return &Client{opts: opts, sem: make(chan struct{}, opts.PoolSize)}
+}
+
+// Close closes the idle connections. Connections in use are closed when
+// they are released.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle, c.closed = nil, true
+	c.mu.Unlock()
+	for _, cn := range idle {
+		cn.close()
+	}
+	return nil
+}
+
+// get takes a connection from the pool, dialing one if none is idle. It
+// blocks while PoolSize connections are in use.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case c.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		<-c.sem
+		return nil, ErrClosed
+	}
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+	cn, err := c.dial(ctx)
+	if err != nil {
+		<-c.sem
+		return nil, err
+	}
+	return cn, nil
+}
+
+// put returns cn to the pool, or closes it if it can't be reused. A
+// connection that ran SELECT is switched back to Options.DB first.
+func (c *Client) put(cn *conn) {
+	if cn.selected && !cn.broken && !cn.selectDB(c.opts.DialTimeout, c.opts.DB) {
+		cn.broken = true
+	}
+	c.mu.Lock()
+	if cn.broken || c.closed {
+		c.mu.Unlock()
+		cn.close()
+	} else {
+		c.idle = append(c.idle, cn)
+		c.mu.Unlock()
+	}
+	<-c.sem
+}
+
+// Do sends a command and returns its reply. Arguments may be strings,
+// []byte, integers, floats or booleans. An error reply is returned as an
+// Error along with the reply itself.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (resp.Value, error) {
+	cmd, err := command(args)
+	if err != nil {
+		return resp.Value{}, err
+	}
+	cn, err := c.get(ctx)
+	if err != nil {
+		return resp.Value{}, err
+	}
+	defer c.put(cn)
+	replies, err := cn.roundTrip(ctx, [][]interface{}{cmd})
+	if err != nil {
+		return resp.Value{}, err
+	}
+	return replies[0], replyErr(replies[0])
+}
+
+func replyErr(v resp.Value) error {
+	if err := v.Err(); err != nil {
+		return Error(err.Error())
+	}
+	return nil
+}
+
+// command converts the arguments of a command to bulk strings.
+func command(args []interface{}) ([]interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("client: empty command")
+	}
+	cmd := make([]interface{}, len(args))
+	for i, a := range args {
+		var b []byte
+		switch v := a.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		case int:
+			b = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(nil, v, 10)
+		case uint64:
+			b = strconv.AppendUint(nil, v, 10)
+		case float64:
+			b = strconv.AppendFloat(nil, v, 'f', -1, 64)
+		case bool:
+			b = []byte("0")
+			if v {
+				b = []byte("1")
+			}
+		default:
+			return nil, fmt.Errorf("client: unsupported argument type %T", a)
+		}
+		cmd[i] = b
+	}
+	return cmd, nil
+}
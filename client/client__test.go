@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/server"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func startServer(t *testing.T) string {
+	t.Helper()
+	srv := server.New(server.Config{Bind: "127.0.0.1", Store: store.Open(filepath.Join(t.TempDir(), "dump.trdb"))})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv.Addr().String()
+}
+
+// fakeServer answers every command with the raw reply returned by handle,
+// for behaviour tiny-redis doesn't have, e.g. Pub/Sub.
+func fakeServer(t *testing.T, handle func(args []resp.Value) string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				var buf []byte
+				tmp := make([]byte, 1024)
+				for {
+					n, err := c.Read(tmp)
+					if err != nil {
+						return
+					}
+					buf = append(buf, tmp[:n]...)
+					for {
+						v, n, err := resp.ParseValue(buf)
+						if err != nil {
+							break
+						}
+						buf = buf[n:]
+						c.Write([]byte(handle(v.Array())))
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func Test__Client(t *testing.T) {
+	ctx := context.Background()
+	for _, proto := range []int{2, 3} {
+		c := New(Options{Addr: startServer(t), Protocol: proto})
+		defer c.Close()
+
+		if err := c.Ping(ctx); err != nil {
+			t.Fatalf("RESP%d Ping: %v", proto, err)
+		}
+		if err := c.Set(ctx, "k", "v\r\n"); err != nil {
+			t.Fatalf("RESP%d Set: %v", proto, err)
+		}
+		if v, err := c.Get(ctx, "k"); err != nil || string(v) != "v\r\n" {
+			t.Errorf("RESP%d Get: got %q, %v", proto, v, err)
+		}
+		if _, err := c.Get(ctx, "missing"); err != ErrNil {
+			t.Errorf("RESP%d Get(missing): got %v want %v", proto, err, ErrNil)
+		}
+		if n, err := c.IncrBy(ctx, "n", 5); err != nil || n != 5 {
+			t.Errorf("RESP%d IncrBy: got %d, %v", proto, n, err)
+		}
+		var replyErr Error
+		if _, err := c.Incr(ctx, "k"); !errors.As(err, &replyErr) {
+			t.Errorf("RESP%d Incr of a string: got %v, want an Error", proto, err)
+		}
+		if vs, err := c.MGet(ctx, "k", "missing"); err != nil || !reflect.DeepEqual(vs, [][]byte{[]byte("v\r\n"), nil}) {
+			t.Errorf("RESP%d MGet: got %q, %v", proto, vs, err)
+		}
+		if ok, err := c.SetNX(ctx, "k", "x"); err != nil || ok {
+			t.Errorf("RESP%d SetNX: got %v, %v", proto, ok, err)
+		}
+		if n, err := c.Del(ctx, "k", "n", "missing"); err != nil || n != 2 {
+			t.Errorf("RESP%d Del: got %d, %v", proto, n, err)
+		}
+		cfg, err := c.ConfigGet(ctx, "dbfile*")
+		if err != nil || cfg["dbfilename"] != "dump.trdb" {
+			t.Errorf("RESP%d ConfigGet: got %v, %v", proto, cfg, err)
+		}
+	}
+}
+
+func Test__Pipeline(t *testing.T) {
+	ctx := context.Background()
+	c := New(Options{Addr: startServer(t)})
+	defer c.Close()
+
+	p := c.Pipeline()
+	p.Do("SET", "k", 1)
+	p.Do("INCR", "k")
+	p.Do("GET", "nosuchcommand", "x")
+	p.Do("GET", "k")
+	replies, err := p.Exec(ctx)
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if len(replies) != 4 || p.Len() != 0 {
+		t.Fatalf("got %d replies, %d left queued", len(replies), p.Len())
+	}
+	if n, _ := replies[1].Int(); n != 2 {
+		t.Errorf("INCR: got %s", replies[1])
+	}
+	if replies[2].Err() == nil {
+		t.Errorf("GET with 2 keys: expected error reply, got %s", replies[2])
+	}
+	if replies[3].String() != "2" {
+		t.Errorf("GET: got %s", replies[3])
+	}
+
+	p.Do("SET", "k", struct{}{})
+	if _, err := p.Exec(ctx); err == nil {
+		t.Errorf("Exec with invalid argument: expected error")
+	}
+}
+
+func Test__ClientPool(t *testing.T) {
+	ctx := context.Background()
+	c := New(Options{Addr: startServer(t), PoolSize: 2})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Incr(ctx, "counter"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if v, err := c.Get(ctx, "counter"); err != nil || string(v) != "20" {
+		t.Errorf("counter: got %q, %v", v, err)
+	}
+	if len(c.idle) > 2 {
+		t.Errorf("pool holds %d connections, want at most 2", len(c.idle))
+	}
+	c.Close()
+	if err := c.Ping(ctx); err != ErrClosed {
+		t.Errorf("Ping after Close: got %v want %v", err, ErrClosed)
+	}
+}
+
+func Test__ClientDB(t *testing.T) {
+	ctx := context.Background()
+	addr := startServer(t)
+	open := func(db int) *Client {
+		c := New(Options{Addr: addr, DB: db, PoolSize: 1})
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	c := open(3)
+	if err := c.Set(ctx, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := open(0).Get(ctx, "k"); err != ErrNil {
+		t.Errorf("Get from DB 0: got %q, %v want %v", v, err, ErrNil)
+	}
+	if v, err := open(3).Get(ctx, "k"); err != nil || string(v) != "v" {
+		t.Errorf("Get from DB 3: got %q, %v", v, err)
+	}
+	if err := open(16).Ping(ctx); err == nil {
+		t.Errorf("DB 16: got no error")
+	}
+
+	// the pooled connection goes back to DB 3 after a SELECT
+	if _, err := c.Do(ctx, "SELECT", 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || string(v) != "v" {
+		t.Errorf("Get after SELECT: got %q, %v", v, err)
+	}
+	p := c.Pipeline()
+	p.Do("SELECT", 1)
+	p.Do("SET", "k", "1")
+	if _, err := p.Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || string(v) != "v" {
+		t.Errorf("Get after a pipelined SELECT: got %q, %v", v, err)
+	}
+	if v, err := open(1).Get(ctx, "k"); err != nil || string(v) != "1" {
+		t.Errorf("Get from DB 1: got %q, %v", v, err)
+	}
+}
+
+func Test__ClientContext(t *testing.T) {
+	// a server that never replies
+	addr := fakeServer(t, func([]resp.Value) string { return "" })
+	c := New(Options{Addr: addr})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Ping(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Ping with timeout: got %v want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := c.Ping(ctx); err != context.Canceled {
+		t.Errorf("Ping canceled: got %v want %v", err, context.Canceled)
+	}
+	if len(c.idle) != 0 {
+		t.Errorf("interrupted connections went back to the pool")
+	}
+}
+
+func Test__ClientPush(t *testing.T) {
+	addr := fakeServer(t, func(args []resp.Value) string {
+		switch args[0].String() {
+		case "HELLO":
+			return "%1\r\n$5\r\nproto\r\n:3\r\n"
+		}
+		// an invalidation message precedes the reply
+		return ">2\r\n$10\r\ninvalidate\r\n*1\r\n$1\r\nk\r\n$1\r\nv\r\n"
+	})
+	var pushes []resp.Value
+	c := New(Options{Addr: addr, Protocol: 3, OnPush: func(v resp.Value) { pushes = append(pushes, v) }})
+	defer c.Close()
+
+	if v, err := c.Get(context.Background(), "k"); err != nil || string(v) != "v" {
+		t.Fatalf("Get: got %q, %v", v, err)
+	}
+	if len(pushes) != 1 || pushes[0].Array()[0].String() != "invalidate" {
+		t.Errorf("pushes: got %v", pushes)
+	}
+}
+
+func Test__PubSub(t *testing.T) {
+	for _, proto := range []int{2, 3} {
+		agg := "*"
+		if proto == 3 {
+			agg = ">"
+		}
+		addr := fakeServer(t, func(args []resp.Value) string {
+			switch args[0].String() {
+			case "HELLO":
+				return "%1\r\n$5\r\nproto\r\n:3\r\n"
+			case "SUBSCRIBE":
+				return agg + "3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n" +
+					agg + "3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$5\r\nhello\r\n" +
+					agg + "4\r\n$8\r\npmessage\r\n$2\r\nc*\r\n$2\r\nch\r\n$3\r\nbye\r\n"
+			}
+			return "-ERR unexpected\r\n"
+		})
+		c := New(Options{Addr: addr, Protocol: proto})
+		defer c.Close()
+
+		ctx := context.Background()
+		ps, err := c.Subscribe(ctx, "ch")
+		if err != nil {
+			t.Fatalf("RESP%d Subscribe: %v", proto, err)
+		}
+		defer ps.Close()
+		want := []Message{
+			{Kind: "subscribe", Channel: "ch", Count: 1},
+			{Kind: "message", Channel: "ch", Payload: []byte("hello")},
+			{Kind: "pmessage", Pattern: "c*", Channel: "ch", Payload: []byte("bye")},
+		}
+		for _, w := range want {
+			m, err := ps.Receive(ctx)
+			if err != nil {
+				t.Fatalf("RESP%d Receive: %v", proto, err)
+			}
+			if !reflect.DeepEqual(*m, w) {
+				t.Errorf("RESP%d Receive: got %+v want %+v", proto, *m, w)
+			}
+		}
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		if _, err := ps.ReceiveMessage(ctx); err != context.DeadlineExceeded {
+			t.Errorf("RESP%d ReceiveMessage: got %v want %v", proto, err, context.DeadlineExceeded)
+		}
+		cancel()
+	}
+}
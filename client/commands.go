@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+// Ping checks that the server is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Echo returns msg as sent back by the server.
+func (c *Client) Echo(ctx context.Context, msg string) (string, error) {
+	b, err := c.bytes(ctx, "ECHO", msg)
+	return string(b), err
+}
+
+// Get returns the value of key, or ErrNil if it doesn't exist.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	return c.bytes(ctx, "GET", key)
+}
+
+// GetDel returns the value of key and deletes it, or ErrNil if it doesn't
+// exist.
+func (c *Client) GetDel(ctx context.Context, key string) ([]byte, error) {
+	return c.bytes(ctx, "GETDEL", key)
+}
+
+// Set sets key to value.
+func (c *Client) Set(ctx context.Context, key string, value interface{}) error {
+	_, err := c.Do(ctx, "SET", key, value)
+	return err
+}
+
+// SetNX sets key to value if it doesn't exist yet, and reports whether it
+// did.
+func (c *Client) SetNX(ctx context.Context, key string, value interface{}) (bool, error) {
+	v, err := c.Do(ctx, "SET", key, value, "NX")
+	return !v.IsNull(), err
+}
+
+// Del deletes keys and returns how many existed.
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	return c.int(ctx, "DEL", stringArgs(keys)...)
+}
+
+// Exists returns how many of keys exist, counting repeated keys each time.
+func (c *Client) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return c.int(ctx, "EXISTS", stringArgs(keys)...)
+}
+
+// Copy copies the value of src to dst, overwriting dst only if replace is
+// set. It reports whether the value was copied.
+func (c *Client) Copy(ctx context.Context, src, dst string, replace bool) (bool, error) {
+	args := []interface{}{src, dst}
+	if replace {
+		args = append(args, "REPLACE")
+	}
+	n, err := c.int(ctx, "COPY", args...)
+	return n == 1, err
+}
+
+// Incr increments the number stored at key and returns the new value.
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	return c.int(ctx, "INCR", key)
+}
+
+// Decr decrements the number stored at key and returns the new value.
+func (c *Client) Decr(ctx context.Context, key string) (int64, error) {
+	return c.int(ctx, "DECR", key)
+}
+
+// IncrBy adds n to the number stored at key and returns the new value.
+func (c *Client) IncrBy(ctx context.Context, key string, n int64) (int64, error) {
+	return c.int(ctx, "INCRBY", key, n)
+}
+
+// DecrBy subtracts n from the number stored at key and returns the new
+// value.
+func (c *Client) DecrBy(ctx context.Context, key string, n int64) (int64, error) {
+	return c.int(ctx, "DECRBY", key, n)
+}
+
+// Append appends value to key and returns the new length.
+func (c *Client) Append(ctx context.Context, key string, value interface{}) (int64, error) {
+	return c.int(ctx, "APPEND", key, value)
+}
+
+// StrLen returns the length of the value of key.
+func (c *Client) StrLen(ctx context.Context, key string) (int64, error) {
+	return c.int(ctx, "STRLEN", key)
+}
+
+// GetRange returns the substring of the value of key between start and
+// end, both included. Negative offsets count from the end.
+func (c *Client) GetRange(ctx context.Context, key string, start, end int64) ([]byte, error) {
+	return c.bytes(ctx, "GETRANGE", key, start, end)
+}
+
+// SetRange overwrites the value of key from offset and returns the new
+// length.
+func (c *Client) SetRange(ctx context.Context, key string, offset int64, value interface{}) (int64, error) {
+	return c.int(ctx, "SETRANGE", key, offset, value)
+}
+
+// GetBit returns the bit at offset of the value of key.
+func (c *Client) GetBit(ctx context.Context, key string, offset int64) (int64, error) {
+	return c.int(ctx, "GETBIT", key, offset)
+}
+
+// MGet returns the values of keys, nil for missing keys.
+func (c *Client) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	v, err := c.Do(ctx, append([]interface{}{"MGET"}, stringArgs(keys)...)...)
+	if err != nil {
+		return nil, err
+	}
+	items := v.Array()
+	r := make([][]byte, len(items))
+	for i, item := range items {
+		if !item.IsNull() {
+			r[i] = item.Bytes()
+		}
+	}
+	return r, nil
+}
+
+// MSet sets several keys, given as alternating keys and values.
+func (c *Client) MSet(ctx context.Context, pairs ...interface{}) error {
+	_, err := c.Do(ctx, append([]interface{}{"MSET"}, pairs...)...)
+	return err
+}
+
+// MSetNX sets several keys unless any of them exists, and reports whether
+// it did.
+func (c *Client) MSetNX(ctx context.Context, pairs ...interface{}) (bool, error) {
+	n, err := c.int(ctx, "MSETNX", pairs...)
+	return n == 1, err
+}
+
+// Save writes a snapshot of the data set to disk.
+func (c *Client) Save(ctx context.Context) error {
+	_, err := c.Do(ctx, "SAVE")
+	return err
+}
+
+// ConfigGet returns the configuration parameters matching pattern.
+func (c *Client) ConfigGet(ctx context.Context, pattern string) (map[string]string, error) {
+	v, err := c.Do(ctx, "CONFIG", "GET", pattern)
+	if err != nil {
+		return nil, err
+	}
+	r := make(map[string]string)
+	return r, v.Unmarshal(&r)
+}
+
+// ConfigSet changes a configuration parameter.
+func (c *Client) ConfigSet(ctx context.Context, param, value string) error {
+	_, err := c.Do(ctx, "CONFIG", "SET", param, value)
+	return err
+}
+
+// int runs a command with an integer reply.
+func (c *Client) int(ctx context.Context, cmd string, args ...interface{}) (int64, error) {
+	v, err := c.Do(ctx, append([]interface{}{cmd}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	return v.Int()
+}
+
+// bytes runs a command with a bulk string reply.
+func (c *Client) bytes(ctx context.Context, args ...interface{}) ([]byte, error) {
+	v, err := c.Do(ctx, args...)
+	return valueBytes(v, err)
+}
+
+func valueBytes(v resp.Value, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	if v.IsNull() {
+		return nil, ErrNil
+	}
+	return v.Bytes(), nil
+}
+
+func stringArgs(ss []string) []interface{} {
+	r := make([]interface{}, len(ss))
+	for i, s := range ss {
+		r[i] = s
+	}
+	return r
+}
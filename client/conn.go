@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+// readSize is how much is read from the socket at a time.
+const readSize = 16 * 1024
+
+// conn is a single connection to the server.
+type conn struct {
+	nc     net.Conn
+	w      *resp.Writer
+	parser resp.Parser
+	onPush func(resp.Value)
+	// buf holds data received but not parsed yet
+	buf []byte
+	tmp []byte
+	// broken is set after an I/O or protocol error, the connection can't
+	// be reused as replies may be out of sync
+	broken bool
+	// selected is set once a SELECT is sent, the connection may be on
+	// another database than Options.DB
+	selected bool
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.DialTimeout)
+	defer cancel()
+	dial := c.opts.Dialer
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	nc, err := dial(ctx, c.opts.Network, c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{
+		nc: nc,
+		w:  resp.NewWriter(nc),
+		// replies are trusted, only requests are limited
+		parser: resp.Parser{MaxBulkLen: math.MaxInt64},
+		onPush: c.opts.OnPush,
+		tmp:    make([]byte, readSize),
+	}
+	if err := cn.handshake(ctx, &c.opts); err != nil {
+		cn.close()
+		return nil, err
+	}
+	cn.selected = false
+	return cn, nil
+}
+
+// handshake negotiates the protocol and authenticates with HELLO, then
+// selects the database. Nothing is sent for RESP2 connections to database 0
+// without credentials or name, so servers without HELLO work too.
+func (cn *conn) handshake(ctx context.Context, opts *Options) error {
+	var cmds [][]interface{}
+	if opts.Protocol != 2 || opts.Password != "" || opts.ClientName != "" {
+		cmd := []interface{}{[]byte("HELLO"), []byte{byte('0' + opts.Protocol)}}
+		if opts.Password != "" {
+			user := opts.Username
+			if user == "" {
+				user = "default"
+			}
+			cmd = append(cmd, []byte("AUTH"), []byte(user), []byte(opts.Password))
+		}
+		if opts.ClientName != "" {
+			cmd = append(cmd, []byte("SETNAME"), []byte(opts.ClientName))
+		}
+		cmds = append(cmds, cmd)
+	}
+	if opts.DB != 0 {
+		cmds = append(cmds, []interface{}{[]byte("SELECT"), []byte(strconv.Itoa(opts.DB))})
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+	replies, err := cn.roundTrip(ctx, cmds)
+	if err != nil {
+		return err
+	}
+	for _, r := range replies {
+		if err := replyErr(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectDB selects the database db again after a SELECT, it reports
+// whether the connection can be reused.
+func (cn *conn) selectDB(timeout time.Duration, db int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := []interface{}{[]byte("SELECT"), []byte(strconv.Itoa(db))}
+	replies, err := cn.roundTrip(ctx, [][]interface{}{cmd})
+	if err != nil || replyErr(replies[0]) != nil {
+		return false
+	}
+	cn.selected = false
+	return true
+}
+
+func (cn *conn) close() error {
+	return cn.nc.Close()
+}
+
+// roundTrip writes cmds in a single batch and reads one reply for each.
+func (cn *conn) roundTrip(ctx context.Context, cmds [][]interface{}) (replies []resp.Value, err error) {
+	stop := cn.watch(ctx)
+	defer func() {
+		if cerr := stop(); cerr != nil && err != nil {
+			err = cerr
+		}
+		if err != nil {
+			cn.broken = true
+		}
+	}()
+	for _, cmd := range cmds {
+		if name, ok := cmd[0].([]byte); ok && strings.EqualFold(string(name), "select") {
+			cn.selected = true
+		}
+		if err := cn.w.Encode(cmd); err != nil {
+			return nil, err
+		}
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+	replies = make([]resp.Value, len(cmds))
+	for i := range replies {
+		if replies[i], err = cn.readReply(); err != nil {
+			return nil, err
+		}
+	}
+	return replies, nil
+}
+
+// readReply reads the next reply, handing push messages to onPush.
+func (cn *conn) readReply() (resp.Value, error) {
+	for {
+		v, err := cn.readValue()
+		if err != nil || v.Kind() != resp.KindPush {
+			return v, err
+		}
+		if cn.onPush != nil {
+			cn.onPush(v)
+		}
+	}
+}
+
+// readValue reads the next value from the connection.
+func (cn *conn) readValue() (resp.Value, error) {
+	for {
+		if len(cn.buf) > 0 {
+			v, n, err := cn.parser.ParseValue(cn.buf)
+			if err == nil {
+				cn.buf = cn.buf[:copy(cn.buf, cn.buf[n:])]
+				return v, nil
+			}
+			if err != resp.ErrIncomplete {
+				return resp.Value{}, err
+			}
+		}
+		n, err := cn.nc.Read(cn.tmp)
+		cn.buf = append(cn.buf, cn.tmp[:n]...)
+		if err != nil {
+			return resp.Value{}, err
+		}
+	}
+}
+
+// watch applies the deadline of ctx to the connection and interrupts
+// blocked reads and writes when ctx is canceled. The returned function
+// stops watching and returns the error of ctx if it interrupted the
+// connection.
+func (cn *conn) watch(ctx context.Context) func() error {
+	dl, hasDeadline := ctx.Deadline()
+	cn.nc.SetDeadline(dl)
+	if ctx.Done() == nil {
+		return func() error { return nil }
+	}
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cn.nc.SetDeadline(time.Unix(1, 0))
+			interrupted <- true
+		case <-done:
+			interrupted <- false
+		}
+	}()
+	return func() error {
+		close(done)
+		if <-interrupted {
+			return ctx.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// the connection deadline may expire just before the context
+		if hasDeadline && !time.Now().Before(dl) {
+			return context.DeadlineExceeded
+		}
+		return nil
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+// Pipeline queues commands and sends them together, saving a round trip
+// per command. It is not safe for concurrent use.
+type Pipeline struct {
+	c    *Client
+	cmds [][]interface{}
+	err  error
+}
+
+// Pipeline returns an empty pipeline.
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// Do queues a command. Argument errors are reported by Exec.
+func (p *Pipeline) Do(args ...interface{}) {
+	cmd, err := command(args)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	p.cmds = append(p.cmds, cmd)
+}
+
+// Len returns the number of queued commands.
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Exec sends the queued commands over a single connection and returns their
+// replies in order. The returned error reports I/O failures only, error
+// replies are found with Value.Err. The pipeline is empty afterwards.
+func (p *Pipeline) Exec(ctx context.Context) ([]resp.Value, error) {
+	cmds, err := p.cmds, p.err
+	p.cmds, p.err = nil, nil
+	if err != nil {
+		return nil, err
+	}
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	cn, err := p.c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.c.put(cn)
+	return cn.roundTrip(ctx, cmds)
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+// Message is a Pub/Sub event. Kind is "message" or "pmessage" for published
+// messages, and "subscribe", "unsubscribe", "psubscribe" or "punsubscribe"
+// for the confirmations of subscription changes.
+type Message struct {
+	Kind    string
+	Channel string
+	// Pattern is the matching pattern of a pmessage
+	Pattern string
+	Payload []byte
+	// Count is the number of subscriptions left after a confirmation
+	Count int64
+}
+
+// PubSub is a connection in subscribed mode. It is not safe for concurrent
+// use, except for Close.
+type PubSub struct {
+	c  *Client
+	cn *conn
+}
+
+// Subscribe opens a dedicated connection subscribed to channels. The
+// connection doesn't count towards the pool size.
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (*PubSub, error) {
+	return c.subscribe(ctx, "SUBSCRIBE", channels)
+}
+
+// PSubscribe opens a dedicated connection subscribed to the channels
+// matching patterns.
+func (c *Client) PSubscribe(ctx context.Context, patterns ...string) (*PubSub, error) {
+	return c.subscribe(ctx, "PSUBSCRIBE", patterns)
+}
+
+func (c *Client) subscribe(ctx context.Context, cmd string, names []string) (*PubSub, error) {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ps := &PubSub{c: c, cn: cn}
+	if err := ps.send(ctx, cmd, names); err != nil {
+		cn.close()
+		return nil, err
+	}
+	return ps, nil
+}
+
+// Publish posts msg to channel and returns the number of receivers.
+func (c *Client) Publish(ctx context.Context, channel string, msg interface{}) (int64, error) {
+	return c.int(ctx, "PUBLISH", channel, msg)
+}
+
+// Subscribe adds channels to the subscription.
+func (ps *PubSub) Subscribe(ctx context.Context, channels ...string) error {
+	return ps.send(ctx, "SUBSCRIBE", channels)
+}
+
+// Unsubscribe removes channels, or every channel if none is given.
+func (ps *PubSub) Unsubscribe(ctx context.Context, channels ...string) error {
+	return ps.send(ctx, "UNSUBSCRIBE", channels)
+}
+
+// PSubscribe adds patterns to the subscription.
+func (ps *PubSub) PSubscribe(ctx context.Context, patterns ...string) error {
+	return ps.send(ctx, "PSUBSCRIBE", patterns)
+}
+
+// PUnsubscribe removes patterns, or every pattern if none is given.
+func (ps *PubSub) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	return ps.send(ctx, "PUNSUBSCRIBE", patterns)
+}
+
+// send writes a subscription command. The confirmations are delivered by
+// Receive, since messages may arrive before them.
+func (ps *PubSub) send(ctx context.Context, cmd string, names []string) (err error) {
+	args := make([]interface{}, 0, len(names)+1)
+	args = append(args, cmd)
+	for _, n := range names {
+		args = append(args, n)
+	}
+	c, _ := command(args)
+	stop := ps.cn.watch(ctx)
+	defer func() {
+		if cerr := stop(); cerr != nil && err != nil {
+			err = cerr
+		}
+	}()
+	if err := ps.cn.w.Encode(c); err != nil {
+		return err
+	}
+	return ps.cn.w.Flush()
+}
+
+// Receive waits for the next message or subscription confirmation.
+// Messages arrive as RESP3 pushes, or as arrays over RESP2.
+func (ps *PubSub) Receive(ctx context.Context) (m *Message, err error) {
+	stop := ps.cn.watch(ctx)
+	defer func() {
+		if cerr := stop(); cerr != nil && err != nil {
+			err = cerr
+		}
+	}()
+	v, err := ps.cn.readValue()
+	if err != nil {
+		return nil, err
+	}
+	if err := replyErr(v); err != nil {
+		return nil, err
+	}
+	return parseMessage(v)
+}
+
+// ReceiveMessage waits for the next published message, skipping
+// subscription confirmations.
+func (ps *PubSub) ReceiveMessage(ctx context.Context) (*Message, error) {
+	for {
+		m, err := ps.Receive(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if m.Kind == "message" || m.Kind == "pmessage" {
+			return m, nil
+		}
+	}
+}
+
+// Close closes the connection.
+func (ps *PubSub) Close() error {
+	return ps.cn.close()
+}
+
+func parseMessage(v resp.Value) (*Message, error) {
+	items := v.Array()
+	if len(items) < 3 {
+		return nil, fmt.Errorf("client: unexpected Pub/Sub reply %s", v)
+	}
+	m := &Message{Kind: strings.ToLower(items[0].String())}
+	switch m.Kind {
+	case "message":
+		m.Channel = items[1].String()
+		m.Payload = items[2].Bytes()
+	case "pmessage":
+		if len(items) < 4 {
+			return nil, fmt.Errorf("client: unexpected Pub/Sub reply %s", v)
+		}
+		m.Pattern = items[1].String()
+		m.Channel = items[2].String()
+		m.Payload = items[3].Bytes()
+	case "subscribe", "unsubscribe", "psubscribe", "punsubscribe":
+		m.Channel = items[1].String()
+		n, err := items[2].Int()
+		if err != nil {
+			return nil, err
+		}
+		m.Count = n
+	default:
+		return nil, fmt.Errorf("client: unexpected Pub/Sub reply %s", v)
+	}
+	return m, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/client"
+)
+
+// randInt is replaced by a random number below the key space size, as in
+// redis-benchmark.
+const randInt = "__rand_int__"
+
+// benchmark holds the settings shared by all tests.
+type benchmark struct {
+	opts     client.Options
+	clients  int
+	requests int
+	pipeline int
+	keyspace int
+	value    string
+}
+
+// test is a named command generator. args is called once per request.
+type test struct {
+	name string
+	args func(b *benchmark, r *rand.Rand) []interface{}
+}
+
+// tests are the built-in tests, by the name given to -t.
+var tests = map[string]test{
+	"ping": {"PING", func(b *benchmark, r *rand.Rand) []interface{} {
+		return []interface{}{"PING"}
+	}},
+	"set": {"SET", func(b *benchmark, r *rand.Rand) []interface{} {
+		return []interface{}{"SET", b.key(r, "key:"), b.value}
+	}},
+	"get": {"GET", func(b *benchmark, r *rand.Rand) []interface{} {
+		return []interface{}{"GET", b.key(r, "key:")}
+	}},
+	"incr": {"INCR", func(b *benchmark, r *rand.Rand) []interface{} {
+		return []interface{}{"INCR", b.key(r, "counter:")}
+	}},
+	"append": {"APPEND", func(b *benchmark, r *rand.Rand) []interface{} {
+		return []interface{}{"APPEND", b.key(r, "append:"), b.value}
+	}},
+	"mset": {"MSET (10 keys)", func(b *benchmark, r *rand.Rand) []interface{} {
+		args := make([]interface{}, 0, 21)
+		args = append(args, "MSET")
+		for i := 0; i < 10; i++ {
+			args = append(args, b.key(r, "key:"), b.value)
+		}
+		return args
+	}},
+	"mget": {"MGET (10 keys)", func(b *benchmark, r *rand.Rand) []interface{} {
+		args := make([]interface{}, 0, 11)
+		args = append(args, "MGET")
+		for i := 0; i < 10; i++ {
+			args = append(args, b.key(r, "key:"))
+		}
+		return args
+	}},
+}
+
+var defaultTests = []string{"ping", "set", "get", "incr", "mset"}
+
+func testNames() []string {
+	names := make([]string, 0, len(tests))
+	for name := range tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupTest(name string) (test, bool) {
+	t, ok := tests[strings.ToLower(name)]
+	return t, ok
+}
+
+// customTest benchmarks the command given on the command line.
+func customTest(cmd []string) test {
+	return test{strings.Join(cmd, " "), func(b *benchmark, r *rand.Rand) []interface{} {
+		args := make([]interface{}, len(cmd))
+		for i, a := range cmd {
+			if b.keyspace > 0 && strings.Contains(a, randInt) {
+				a = strings.Replace(a, randInt, b.randInt(r), -1)
+			}
+			args[i] = a
+		}
+		return args
+	}}
+}
+
+// key returns prefix followed by a random number when a key space is set,
+// and by the literal __rand_int__ otherwise, so that every request hits the
+// same key.
+func (b *benchmark) key(r *rand.Rand, prefix string) string {
+	if b.keyspace == 0 {
+		return prefix + randInt
+	}
+	return prefix + b.randInt(r)
+}
+
+func (b *benchmark) randInt(r *rand.Rand) string {
+	return fmt.Sprintf("%012d", r.Intn(b.keyspace))
+}
+
+// result is the outcome of a test.
+type result struct {
+	name     string
+	elapsed  time.Duration
+	errors   int64
+	firstErr error
+	hist     *histogram
+}
+
+// rps returns the number of requests per second.
+func (r *result) rps() float64 {
+	return float64(r.hist.count()) / r.elapsed.Seconds()
+}
+
+// run sends b.requests commands of t over b.clients connections, pipelining
+// b.pipeline of them at a time. Each request of a pipeline is recorded with
+// the latency of the whole batch.
+func (b *benchmark) run(t test) (*result, error) {
+	ctx := context.Background()
+	conns := make([]*client.Client, b.clients)
+	defer func() {
+		for _, c := range conns {
+			if c != nil {
+				c.Close()
+			}
+		}
+	}()
+	// connect first, so that dialing isn't counted in the latencies
+	for i := range conns {
+		conns[i] = client.New(b.opts)
+		if _, err := conns[i].Do(ctx, "PING"); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &result{name: t.name, hist: newHistogram()}
+	var mu sync.Mutex
+	var next int64
+	var ioErr error
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, c := range conns {
+		wg.Add(1)
+		go func(c *client.Client, seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			p := c.Pipeline()
+			for {
+				first := atomic.AddInt64(&next, int64(b.pipeline)) - int64(b.pipeline)
+				n := int64(b.requests) - first
+				if n <= 0 {
+					return
+				}
+				if n > int64(b.pipeline) {
+					n = int64(b.pipeline)
+				}
+				for j := int64(0); j < n; j++ {
+					p.Do(t.args(b, r)...)
+				}
+				t0 := time.Now()
+				replies, err := p.Exec(ctx)
+				lat := time.Since(t0)
+				if err != nil {
+					mu.Lock()
+					if ioErr == nil {
+						ioErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				res.hist.record(lat, n)
+				for _, v := range replies {
+					if e := v.Err(); e != nil {
+						mu.Lock()
+						if res.firstErr == nil {
+							res.firstErr = e
+						}
+						res.errors++
+						mu.Unlock()
+					}
+				}
+			}
+		}(c, start.UnixNano()+int64(i))
+	}
+	wg.Wait()
+	res.elapsed = time.Since(start)
+	if ioErr != nil {
+		return nil, ioErr
+	}
+	return res, nil
+}
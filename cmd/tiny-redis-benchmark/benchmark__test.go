@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/client"
+	"github.com/tinfoil-knight/tiny-redis/server"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__Buckets(t *testing.T) {
+	tests := []int64{0, 1, 1023, 1024, 1025, 2047, 2048, 5000, 123456, 1 << 40}
+	for _, v := range tests {
+		i := bucketIndex(v)
+		hi := bucketValue(i)
+		if hi < v {
+			t.Errorf("bucketValue(bucketIndex(%d)): got %d", v, hi)
+		}
+		// three significant digits
+		if float64(hi-v) > float64(v)*0.002 {
+			t.Errorf("bucketValue(bucketIndex(%d)): got %d, too far", v, hi)
+		}
+		if i > 0 && bucketValue(i-1) >= v {
+			t.Errorf("bucketValue(bucketIndex(%d)-1): got %d", v, bucketValue(i-1))
+		}
+	}
+}
+
+func Test__Percentile(t *testing.T) {
+	h := newHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i)*time.Microsecond, 1)
+	}
+	h.record(50*time.Millisecond, 1000)
+	tests := []struct {
+		p        float64
+		expected int64
+	}{
+		{0, 1},
+		{25, 500},
+		{50, 1000},
+		{50.1, 50000},
+		{100, 50000},
+	}
+	for _, tt := range tests {
+		if got := h.percentile(tt.p); got != tt.expected {
+			t.Errorf("percentile(%v): got %d want %d", tt.p, got, tt.expected)
+		}
+	}
+	if got := h.countAtMost(500); got != 500 {
+		t.Errorf("countAtMost(500): got %d want 500", got)
+	}
+	if h.min != 1 || h.max != 50000 || h.count() != 2000 {
+		t.Errorf("got min %d max %d count %d", h.min, h.max, h.count())
+	}
+}
+
+func Test__Run(t *testing.T) {
+	srv := server.New(server.Config{Bind: "127.0.0.1", Store: store.Open(filepath.Join(t.TempDir(), "dump.trdb"))})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	b := &benchmark{
+		opts:     client.Options{Network: "tcp", Addr: srv.Addr().String()},
+		clients:  4,
+		requests: 1001,
+		pipeline: 3,
+		keyspace: 100,
+		value:    "xxx",
+	}
+
+	r, err := b.run(customTest([]string{"INCR", "counter"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.hist.count() != 1001 || r.errors != 0 {
+		t.Errorf("INCR: got %d requests, %d errors", r.hist.count(), r.errors)
+	}
+	if v, _ := srv.Store().Get([]byte("counter")); string(v) != "1001" {
+		t.Errorf("counter: got %q want 1001", v)
+	}
+
+	if _, err := b.run(tests["set"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.Store().Get([]byte("key:" + randInt)); ok {
+		t.Errorf("SET with a key space used the literal %s", randInt)
+	}
+
+	r, err = b.run(customTest([]string{"NOSUCHCMD", "a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.errors != 1001 || r.firstErr == nil {
+		t.Errorf("NOSUCHCMD: got %d errors, %v", r.errors, r.firstErr)
+	}
+	if out := formatFull(b, r); !strings.Contains(out, "1001 errors") || !strings.Contains(out, "throughput summary") {
+		t.Errorf("formatFull: got %q", out)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// subBuckets is the number of buckets per power of two above it. Latencies
+// are recorded in microseconds, exactly up to 1024us and within 0.2%
+// above, like an HdrHistogram with three significant digits.
+const (
+	subBucketBits = 10
+	subBuckets    = 1 << subBucketBits
+	halfBuckets   = subBuckets / 2
+)
+
+// histogram counts latencies. It is safe for concurrent use.
+type histogram struct {
+	mu     sync.Mutex
+	counts []int64
+	total  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+func newHistogram() *histogram {
+	// the top bucket holds math.MaxInt64
+	return &histogram{counts: make([]int64, bucketIndex(math.MaxInt64)+1), min: math.MaxInt64}
+}
+
+// bucketIndex returns the bucket of v microseconds.
+func bucketIndex(v int64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	return subBuckets + (shift-1)*halfBuckets + int(v>>uint(shift)) - halfBuckets
+}
+
+// bucketValue returns the highest value counted in bucket i.
+func bucketValue(i int) int64 {
+	if i < subBuckets {
+		return int64(i)
+	}
+	shift := uint((i-subBuckets)/halfBuckets + 1)
+	top := int64((i-subBuckets)%halfBuckets + halfBuckets)
+	return (top+1)<<shift - 1
+}
+
+// record adds n requests that took d each.
+func (h *histogram) record(d time.Duration, n int64) {
+	v := d.Microseconds()
+	h.mu.Lock()
+	h.counts[bucketIndex(v)] += n
+	h.total += n
+	h.sum += v * n
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.mu.Unlock()
+}
+
+func (h *histogram) count() int64 {
+	return h.total
+}
+
+// mean returns the average latency in microseconds.
+func (h *histogram) mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return float64(h.sum) / float64(h.total)
+}
+
+// percentile returns the latency in microseconds that p percent of the
+// requests didn't exceed.
+func (h *histogram) percentile(p float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	if p >= 100 {
+		return h.max
+	}
+	target := int64(math.Ceil(p / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			v := bucketValue(i)
+			if v > h.max {
+				v = h.max
+			}
+			return v
+		}
+	}
+	return h.max
+}
+
+// countAtMost returns the number of requests that took at most v
+// microseconds. A bucket straddling v isn't counted.
+func (h *histogram) countAtMost(v int64) int64 {
+	if v >= h.max {
+		return h.total
+	}
+	var n int64
+	for i, c := range h.counts {
+		if bucketValue(i) > v {
+			break
+		}
+		n += c
+	}
+	return n
+}
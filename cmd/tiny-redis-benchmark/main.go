@@ -0,0 +1,95 @@
+// Command tiny-redis-benchmark measures the throughput and latency of a
+// tiny-redis server, in the spirit of redis-benchmark.
+//
+// Usage:
+//
+//	tiny-redis-benchmark [flags]                 run the tests selected by -t
+//	tiny-redis-benchmark [flags] cmd [arg ...]   benchmark a custom command
+//
+// Occurrences of __rand_int__ in keys and custom commands are replaced by
+// a random number below -r, so that requests spread over a key space.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/client"
+)
+
+func main() {
+	host := flag.String("h", "127.0.0.1", "server hostname")
+	port := flag.Int("p", 8001, "server port")
+	socket := flag.String("s", "", "server socket, overrides hostname and port")
+	pass := flag.String("a", "", "password for the server")
+	clients := flag.Int("c", 50, "number of parallel connections")
+	requests := flag.Int("n", 100000, "total number of requests")
+	dataSize := flag.Int("d", 3, "data size of SET/GET values in bytes")
+	pipeline := flag.Int("P", 1, "pipeline <numreq> requests")
+	keyspace := flag.Int("r", 0, "use random keys for SET/GET/INCR, __rand_int__ ranges over [0, keyspacelen)")
+	tests := flag.String("t", strings.Join(defaultTests, ","), "comma separated list of tests, of "+strings.Join(testNames(), ", "))
+	quiet := flag.Bool("q", false, "quiet, just show query/sec values")
+	csv := flag.Bool("csv", false, "output in CSV format")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [cmd [arg ...]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *clients < 1 || *requests < 1 || *pipeline < 1 || *dataSize < 0 || *keyspace < 0 {
+		fmt.Fprintln(os.Stderr, "-c, -n and -P must be positive, -d and -r can't be negative")
+		os.Exit(1)
+	}
+	opts := client.Options{
+		Network:  "tcp",
+		Addr:     net.JoinHostPort(*host, strconv.Itoa(*port)),
+		Password: *pass,
+		PoolSize: 1,
+	}
+	if *socket != "" {
+		opts.Network, opts.Addr = "unix", *socket
+	}
+	b := &benchmark{
+		opts:     opts,
+		clients:  *clients,
+		requests: *requests,
+		pipeline: *pipeline,
+		keyspace: *keyspace,
+		value:    strings.Repeat("x", *dataSize),
+	}
+
+	var run []test
+	if flag.NArg() > 0 {
+		run = []test{customTest(flag.Args())}
+	} else {
+		for _, name := range strings.Split(*tests, ",") {
+			t, ok := lookupTest(strings.TrimSpace(name))
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Unknown test %q, the tests are %s. Other commands can be given as arguments.\n", name, strings.Join(testNames(), ", "))
+				os.Exit(1)
+			}
+			run = append(run, t)
+		}
+	}
+
+	format := formatFull
+	switch {
+	case *csv:
+		format = formatCSV
+		fmt.Println(csvHeader)
+	case *quiet:
+		format = formatQuiet
+	}
+	for _, t := range run {
+		r, err := b.run(t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.name, err)
+			os.Exit(1)
+		}
+		fmt.Print(format(b, r))
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// percentiles are listed in the full report.
+var percentiles = []float64{50, 75, 90, 95, 99, 99.9, 100}
+
+// barWidth is the width of a histogram bar for 100% of the requests.
+const barWidth = 40
+
+const csvHeader = `"test","rps","avg_latency_ms","min_latency_ms","p50_latency_ms","p99_latency_ms","p999_latency_ms","max_latency_ms"`
+
+type formatter func(b *benchmark, r *result) string
+
+func msec(us int64) string {
+	return fmt.Sprintf("%.3f", float64(us)/1000)
+}
+
+func formatFull(b *benchmark, r *result) string {
+	h := r.hist
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "====== %s ======\n", r.name)
+	fmt.Fprintf(&sb, "  %d requests completed in %.2f seconds\n", h.count(), r.elapsed.Seconds())
+	fmt.Fprintf(&sb, "  %d parallel clients\n", b.clients)
+	fmt.Fprintf(&sb, "  %d bytes payload\n", len(b.value))
+	fmt.Fprintf(&sb, "  pipeline: %d\n", b.pipeline)
+	if r.errors > 0 {
+		fmt.Fprintf(&sb, "  %d errors, the first: %v\n", r.errors, r.firstErr)
+	}
+
+	sb.WriteString("\nLatency by percentile distribution:\n")
+	for _, p := range percentiles {
+		v := h.percentile(p)
+		fmt.Fprintf(&sb, "%8.3f%% <= %s milliseconds (cumulative count %d)\n", p, msec(v), h.countAtMost(v))
+	}
+
+	sb.WriteString("\nLatency histogram:\n")
+	var prev int64
+	for _, bound := range bounds(h.max) {
+		n := h.countAtMost(bound)
+		if n == 0 {
+			continue
+		}
+		share := float64(n-prev) / float64(h.count()) * 100
+		bar := strings.Repeat("#", int(share/100*barWidth+0.5))
+		fmt.Fprintf(&sb, "  <= %9s ms %8.3f%% %8.3f%% %s\n", msec(bound), share, float64(n)/float64(h.count())*100, bar)
+		prev = n
+	}
+
+	sb.WriteString("\nSummary:\n")
+	fmt.Fprintf(&sb, "  throughput summary: %.2f requests per second\n", r.rps())
+	sb.WriteString("  latency summary (msec):\n")
+	fmt.Fprintf(&sb, "  %9s %9s %9s %9s %9s %9s\n", "avg", "min", "p50", "p99", "p999", "max")
+	fmt.Fprintf(&sb, "  %9.3f %9s %9s %9s %9s %9s\n\n",
+		h.mean()/1000, msec(h.min), msec(h.percentile(50)), msec(h.percentile(99)), msec(h.percentile(99.9)), msec(h.max))
+	return sb.String()
+}
+
+func formatQuiet(b *benchmark, r *result) string {
+	h := r.hist
+	return fmt.Sprintf("%s: %.2f requests per second, p50=%s p99=%s p999=%s msec\n",
+		r.name, r.rps(), msec(h.percentile(50)), msec(h.percentile(99)), msec(h.percentile(99.9)))
+}
+
+func formatCSV(b *benchmark, r *result) string {
+	h := r.hist
+	return fmt.Sprintf("%q,\"%.2f\",\"%.3f\",\"%s\",\"%s\",\"%s\",\"%s\",\"%s\"\n",
+		r.name, r.rps(), h.mean()/1000, msec(h.min), msec(h.percentile(50)),
+		msec(h.percentile(99)), msec(h.percentile(99.9)), msec(h.max))
+}
+
+// bounds returns the upper bounds of the histogram rows in microseconds:
+// 1, 2 and 5 times the powers of ten, up to the first one covering max.
+func bounds(max int64) []int64 {
+	var bs []int64
+	for scale := int64(10); ; scale *= 10 {
+		for _, m := range []int64{1, 2, 5} {
+			bs = append(bs, m*scale)
+			if m*scale >= max {
+				return bs
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/client"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/server"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func value(t *testing.T, s string) resp.Value {
+	t.Helper()
+	v, _, err := resp.ParseValue([]byte(s))
+	if err != nil {
+		t.Fatalf("ParseValue(%q): %v", s, err)
+	}
+	return v
+}
+
+func Test__Format(t *testing.T) {
+	tests := []struct {
+		input string
+		tty   string
+		raw   string
+	}{
+		{"+OK\r\n", "OK\n", "OK\n"},
+		{"-ERR wrong\r\n", "(error) ERR wrong\n", "ERR wrong\n"},
+		{":42\r\n", "(integer) 42\n", "42\n"},
+		{",1.5\r\n", "(double) 1.5\n", "1.5\n"},
+		{"(123\r\n", "(big number) 123\n", "123\n"},
+		{"#t\r\n", "(true)\n", "(true)\n"},
+		{"$6\r\nfo\"o\n\x01\r\n", "\"fo\\\"o\\n\\x01\"\n", "fo\"o\n\x01\n"},
+		{"=9\r\ntxt:a\nb c\r\n", "a\nb c\n", "a\nb c\n"},
+		{"_\r\n", "(nil)\n", "\n"},
+		{"*0\r\n", "(empty array)\n", "\n"},
+		{"%0\r\n", "(empty hash)\n", "\n"},
+		{"*2\r\n$1\r\na\r\n:1\r\n", "1) \"a\"\n2) (integer) 1\n", "a\n1\n"},
+		{"~1\r\n$1\r\na\r\n", "1~ \"a\"\n", "a\n"},
+		{
+			"*2\r\n*2\r\n+x\r\n+y\r\n+z\r\n",
+			"1) 1) x\n   2) y\n2) z\n",
+			"x\ny\nz\n",
+		},
+		{
+			"%2\r\n+server\r\n+redis\r\n+modules\r\n*1\r\n+m\r\n",
+			"1# server => redis\n2# modules => 1) m\n",
+			"server\nredis\nmodules\nm\n",
+		},
+		{
+			"*10\r\n:1\r\n:2\r\n:3\r\n:4\r\n:5\r\n:6\r\n:7\r\n:8\r\n:9\r\n*1\r\n:10\r\n",
+			" 1) (integer) 1\n 2) (integer) 2\n 3) (integer) 3\n 4) (integer) 4\n 5) (integer) 5\n" +
+				" 6) (integer) 6\n 7) (integer) 7\n 8) (integer) 8\n 9) (integer) 9\n10) 1) (integer) 10\n",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+		},
+	}
+	for _, tt := range tests {
+		v := value(t, tt.input)
+		if got := formatTTY(v); got != tt.tty {
+			t.Errorf("formatTTY(%q): got %q want %q", tt.input, got, tt.tty)
+		}
+		if got := formatRaw(v); got != tt.raw {
+			t.Errorf("formatRaw(%q): got %q want %q", tt.input, got, tt.raw)
+		}
+	}
+}
+
+func Test__Editor(t *testing.T) {
+	tests := []struct {
+		input    string
+		history  []string
+		expected string
+	}{
+		{"GET k\r", nil, "GET k"},
+		// backspace, then left arrow and an insertion
+		{"GET kk\x7f\x1b[D\x1b[Dx\r", nil, "GETx k"},
+		// Ctrl-A and Ctrl-K
+		{"abc\x01\x0bPING\r", nil, "PING"},
+		// up twice and down once
+		{"\x1b[A\x1b[A\x1b[B\r", []string{"one", "two"}, "two"},
+		// back down to the line being edited
+		{"draft\x1b[A\x1b[B\r", []string{"one"}, "draft"},
+		// Ctrl-W deletes the previous word
+		{"SET key value\x17\r", nil, "SET key "},
+		// delete key
+		{"ab\x01\x1b[3~\r", nil, "b"},
+	}
+	for _, tt := range tests {
+		ed := &editor{in: bufio.NewReader(strings.NewReader(tt.input)), out: ioutil.Discard, history: tt.history}
+		got, err := ed.readLine("> ")
+		if err != nil || got != tt.expected {
+			t.Errorf("readLine(%q): got %q, %v want %q", tt.input, got, err, tt.expected)
+		}
+	}
+	ed := &editor{in: bufio.NewReader(strings.NewReader("\x03")), out: ioutil.Discard}
+	if _, err := ed.readLine("> "); err != errInterrupted {
+		t.Errorf("Ctrl-C: got %v want %v", err, errInterrupted)
+	}
+}
+
+func Test__Sensitive(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected bool
+	}{
+		{[]string{"auth", "secret"}, true},
+		{[]string{"HELLO", "3", "AUTH", "bob", "secret"}, true},
+		{[]string{"HELLO", "3"}, false},
+		{[]string{"ACL", "SETUSER", "bob", ">secret"}, true},
+		{[]string{"ACL", "LIST"}, false},
+		{[]string{"CONFIG", "SET", "requirepass", "secret"}, true},
+		{[]string{"CONFIG", "SET", "maxmemory", "1mb"}, false},
+		{[]string{"SET", "auth", "v"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := sensitive(tt.input); got != tt.expected {
+			t.Errorf("sensitive(%q): got %v want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func Test__Pipe(t *testing.T) {
+	srv := server.New(server.Config{Bind: "127.0.0.1", Store: store.Open(filepath.Join(t.TempDir(), "dump.trdb"))})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	opts := client.Options{Network: "tcp", Addr: srv.Addr().String()}
+
+	var in bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		in.WriteString("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n")
+	}
+	in.WriteString("*2\r\n$4\r\nINCR\r\n$1\r\nk\r\n")
+	var out bytes.Buffer
+	if code := pipeMode(opts, &in, &out); code != 1 {
+		t.Errorf("exit status: got %d want 1", code)
+	}
+	if !strings.HasSuffix(out.String(), "Last reply received from server.\nerrors: 1, replies: 1001\n") {
+		t.Errorf("got output %q", out.String())
+	}
+	if v, _ := srv.Store().Get([]byte("k")); string(v) != "v" {
+		t.Errorf("k: got %q", v)
+	}
+
+	opts.DB = 2
+	out.Reset()
+	if code := pipeMode(opts, strings.NewReader("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\n2\r\n"), &out); code != 0 {
+		t.Errorf("-n 2: exit status %d, output %q", code, out.String())
+	}
+	if v, _ := srv.Store().DB(2).Get([]byte("k")); string(v) != "2" {
+		t.Errorf("k in DB 2: got %q", v)
+	}
+}
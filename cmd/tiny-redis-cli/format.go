@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+// formatter renders a reply, including its trailing newline.
+type formatter func(resp.Value) string
+
+// formatTTY renders a reply the way redis-cli does in a terminal: type
+// hints, quoted strings and numbered aggregates.
+func formatTTY(v resp.Value) string {
+	return ttyValue(v, "")
+}
+
+// ttyValue formats v. prefix indents every line after the first, so nested
+// aggregates line up under their parent's index.
+func ttyValue(v resp.Value, prefix string) string {
+	var b strings.Builder
+	for _, p := range v.Attrs() {
+		fmt.Fprintf(&b, "| %s => %s%s", strings.TrimSuffix(ttyValue(p.Key, prefix), "\n"), ttyValue(p.Value, prefix+"  "), prefix)
+	}
+	switch v.Kind() {
+	case resp.KindError, resp.KindBulkError:
+		fmt.Fprintf(&b, "(error) %s\n", v.Bytes())
+	case resp.KindSimpleString:
+		fmt.Fprintf(&b, "%s\n", v.Bytes())
+	case resp.KindInteger:
+		fmt.Fprintf(&b, "(integer) %s\n", v.Bytes())
+	case resp.KindDouble:
+		fmt.Fprintf(&b, "(double) %s\n", v.Bytes())
+	case resp.KindBigNumber:
+		fmt.Fprintf(&b, "(big number) %s\n", v.Bytes())
+	case resp.KindBoolean:
+		if ok, _ := v.Bool(); ok {
+			b.WriteString("(true)\n")
+		} else {
+			b.WriteString("(false)\n")
+		}
+	case resp.KindBulkString:
+		b.WriteString(quote(v.Bytes()) + "\n")
+	case resp.KindVerbatim:
+		// verbatim strings are meant to be shown as they are
+		b.Write(v.Bytes())
+		b.WriteString("\n")
+	case resp.KindNull:
+		b.WriteString("(nil)\n")
+	case resp.KindArray, resp.KindSet, resp.KindPush, resp.KindMap:
+		b.WriteString(ttyAggregate(v, prefix))
+	}
+	return b.String()
+}
+
+var emptyAggregates = map[resp.Kind]string{
+	resp.KindArray: "(empty array)\n",
+	resp.KindSet:   "(empty set)\n",
+	resp.KindPush:  "(empty push)\n",
+	resp.KindMap:   "(empty hash)\n",
+}
+
+func ttyAggregate(v resp.Value, prefix string) string {
+	n := v.Len()
+	if n == 0 {
+		return emptyAggregates[v.Kind()]
+	}
+	sep := ')'
+	switch v.Kind() {
+	case resp.KindSet:
+		sep = '~'
+	case resp.KindMap:
+		sep = '#'
+	}
+	width := len(strconv.Itoa(n))
+	inner := prefix + strings.Repeat(" ", width+2)
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(prefix)
+		}
+		fmt.Fprintf(&b, "%*d%c ", width, i+1, sep)
+		if v.Kind() == resp.KindMap {
+			p := v.Map()[i]
+			key := strings.TrimSuffix(ttyValue(p.Key, inner), "\n")
+			b.WriteString(key + " => ")
+			b.WriteString(ttyValue(p.Value, inner+strings.Repeat(" ", len(key)+4)))
+		} else {
+			b.WriteString(ttyValue(v.Array()[i], inner))
+		}
+	}
+	return b.String()
+}
+
+// formatRaw renders a reply for scripts: strings as they are, numbers
+// without type hints and aggregate items one per line.
+func formatRaw(v resp.Value) string {
+	return rawValue(v) + "\n"
+}
+
+func rawValue(v resp.Value) string {
+	switch v.Kind() {
+	case resp.KindNull:
+		return ""
+	case resp.KindBoolean:
+		if ok, _ := v.Bool(); ok {
+			return "(true)"
+		}
+		return "(false)"
+	case resp.KindArray, resp.KindSet, resp.KindPush, resp.KindMap:
+		items := make([]string, len(v.Array()))
+		for i, item := range v.Array() {
+			items[i] = rawValue(item)
+		}
+		return strings.Join(items, "\n")
+	}
+	return string(v.Bytes())
+}
+
+// quote returns s in double quotes with the escapes of redis-cli
+// (sdscatrepr).
+func quote(s []byte) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
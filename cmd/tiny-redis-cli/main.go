@@ -0,0 +1,115 @@
+// Command tiny-redis-cli is a command line interface to tiny-redis, modelled
+// after redis-cli.
+//
+// Usage:
+//
+//	tiny-redis-cli [flags]                  interactive mode
+//	tiny-redis-cli [flags] cmd [arg ...]    run a single command
+//	tiny-redis-cli [flags] --pipe < data    send raw RESP from stdin
+//
+// --tls connects over TLS, with --cacert, --cert and --key for the CA and
+// the client certificate.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/client"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+func main() {
+	host := flag.String("h", "127.0.0.1", "server hostname")
+	port := flag.Int("p", 8001, "server port")
+	socket := flag.String("s", "", "server socket, overrides hostname and port")
+	user := flag.String("user", "", "username used with -a")
+	pass := flag.String("a", "", "password to use when connecting to the server")
+	db := flag.Int("n", 0, "database number")
+	resp3 := flag.Bool("3", false, "start the session in RESP3 protocol mode")
+	raw := flag.Bool("raw", false, "use raw formatting for replies, the default when stdout isn't a tty")
+	noRaw := flag.Bool("no-raw", false, "force formatted output even when stdout isn't a tty")
+	pipe := flag.Bool("pipe", false, "transfer raw RESP protocol from stdin to the server")
+	useTLS := flag.Bool("tls", false, "establish a secure TLS connection")
+	var to tlsOptions
+	flag.StringVar(&to.sni, "sni", "", "server name indication for TLS")
+	flag.StringVar(&to.cacert, "cacert", "", "CA certificate file to verify the server with")
+	flag.StringVar(&to.cert, "cert", "", "client certificate to authenticate with")
+	flag.StringVar(&to.key, "key", "", "private key file to authenticate with")
+	flag.BoolVar(&to.insecure, "insecure", false, "allow insecure TLS connection by skipping cert validation")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [cmd [arg [arg ...]]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	opts := client.Options{
+		Network:  "tcp",
+		Addr:     net.JoinHostPort(*host, strconv.Itoa(*port)),
+		Username: *user,
+		Password: *pass,
+		DB:       *db,
+		Protocol: 2,
+		PoolSize: 1,
+	}
+	if *socket != "" {
+		opts.Network, opts.Addr = "unix", *socket
+	}
+	if *resp3 {
+		opts.Protocol = 3
+	}
+	if *useTLS {
+		cfg, err := to.config()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not create TLS context: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Dialer = (&tls.Dialer{Config: cfg}).DialContext
+	}
+
+	if *pipe {
+		os.Exit(pipeMode(opts, os.Stdin, os.Stdout))
+	}
+
+	c := client.New(opts)
+	defer c.Close()
+	f := formatTTY
+	if *raw || (!isTerminal(int(os.Stdout.Fd())) && !*noRaw) {
+		f = formatRaw
+	}
+
+	if flag.NArg() > 0 {
+		args := make([]interface{}, flag.NArg())
+		for i, a := range flag.Args() {
+			args[i] = a
+		}
+		if _, ok := run(c, args, f, os.Stdout); !ok {
+			os.Exit(1)
+		}
+		return
+	}
+	repl(c, opts, f)
+}
+
+// run executes a command, prints its reply and returns it. ok is false if
+// the server couldn't be reached.
+func run(c *client.Client, args []interface{}, f formatter, out *os.File) (v resp.Value, ok bool) {
+	v, err := c.Do(context.Background(), args...)
+	if err == io.EOF && strings.EqualFold(args[0].(string), "shutdown") {
+		// the server hangs up once it has stopped
+		return v, true
+	}
+	if _, ok := err.(client.Error); err != nil && !ok {
+		fmt.Fprintf(os.Stderr, "Could not connect to tiny-redis: %v\n", err)
+		return v, false
+	}
+	fmt.Fprint(out, f(v))
+	return v, true
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/client"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+// pipeMode sends the RESP commands read from in to the server, then an ECHO
+// of a random string. Its reply marks the end of the replies to in, which
+// are counted rather than printed. It returns the exit status.
+func pipeMode(opts client.Options, in io.Reader, out io.Writer) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	dial := opts.Dialer
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	nc, err := dial(ctx, opts.Network, opts.Addr)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(out, "Could not connect to tiny-redis at %s: %v\n", opts.Addr, err)
+		return 1
+	}
+	defer nc.Close()
+
+	var tag [10]byte
+	rand.Read(tag[:])
+	magic := []byte(hex.EncodeToString(tag[:]))
+	// the writer goroutine reports progress too
+	out = &syncWriter{w: out}
+
+	w := resp.NewWriter(nc)
+	// setup names the commands sent before in, whose replies aren't counted
+	var setup []string
+	if opts.Password != "" {
+		user := opts.Username
+		if user == "" {
+			user = "default"
+		}
+		w.Encode([]interface{}{[]byte("HELLO"), []byte("2"), []byte("AUTH"), []byte(user), []byte(opts.Password)})
+		setup = append(setup, "AUTH")
+	}
+	if opts.DB != 0 {
+		w.Encode([]interface{}{[]byte("SELECT"), []byte(strconv.Itoa(opts.DB))})
+		setup = append(setup, "SELECT")
+	}
+	w.Flush()
+	writeErr := make(chan error, 1)
+	go func() {
+		if _, err := io.Copy(nc, in); err != nil {
+			writeErr <- err
+			return
+		}
+		fmt.Fprintln(out, "All data transferred. Waiting for the last reply...")
+		w.Encode([]interface{}{[]byte("ECHO"), magic})
+		writeErr <- w.Flush()
+	}()
+
+	parser := resp.Parser{MaxBulkLen: math.MaxInt64}
+	buf := make([]byte, 0, 64*1024)
+	tmp := make([]byte, 64*1024)
+	replies, errs := 0, 0
+	for {
+		n, err := nc.Read(tmp)
+		buf = append(buf, tmp[:n]...)
+		for {
+			v, n, perr := parser.ParseValue(buf)
+			if perr != nil {
+				if perr != resp.ErrIncomplete {
+					fmt.Fprintf(out, "Bad reply from server: %v\n", perr)
+					return 1
+				}
+				break
+			}
+			buf = buf[:copy(buf, buf[n:])]
+			if len(setup) > 0 {
+				if e := v.Err(); e != nil {
+					fmt.Fprintf(out, "%s failed: %v\n", setup[0], e)
+					return 1
+				}
+				setup = setup[1:]
+				continue
+			}
+			if v.Kind() == resp.KindBulkString && string(v.Bytes()) == string(magic) {
+				fmt.Fprintln(out, "Last reply received from server.")
+				fmt.Fprintf(out, "errors: %d, replies: %d\n", errs, replies)
+				if errs > 0 {
+					return 1
+				}
+				return 0
+			}
+			replies++
+			if e := v.Err(); e != nil {
+				errs++
+				fmt.Fprintln(out, e)
+			}
+		}
+		if err != nil {
+			select {
+			case werr := <-writeErr:
+				if werr != nil {
+					err = werr
+				}
+			default:
+			}
+			fmt.Fprintf(out, "Error reading from the server: %v\n", err)
+			return 1
+		}
+	}
+}
+
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/client"
+	"github.com/tinfoil-knight/tiny-redis/config"
+)
+
+// historyLen is the number of lines kept in the history file, as in
+// redis-cli.
+const historyLen = 100
+
+var errInterrupted = errors.New("interrupted")
+
+// repl runs the interactive mode. Without a terminal, commands are read one
+// per line from stdin.
+func repl(c *client.Client, opts client.Options, f formatter) {
+	defer func() { c.Close() }()
+	prompt := promptFor(opts)
+	fd := int(os.Stdin.Fd())
+	tty := isTerminal(fd)
+	histPath := historyPath()
+	ed := &editor{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	if tty {
+		ed.history = loadHistory(histPath)
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		var line string
+		var err error
+		if tty {
+			line, err = readLineRaw(fd, ed, prompt)
+		} else if scanner.Scan() {
+			line = scanner.Text()
+		} else {
+			err = io.EOF
+		}
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		args, err := config.SplitArgs(line)
+		if tty && !sensitive(args) {
+			ed.add(line)
+			saveHistory(histPath, ed.history)
+		}
+		if err != nil {
+			fmt.Println("Invalid argument(s)")
+			continue
+		}
+		switch strings.ToLower(args[0]) {
+		case "quit", "exit":
+			return
+		case "clear":
+			fmt.Print("\x1b[H\x1b[2J")
+			continue
+		}
+		cmd := make([]interface{}, len(args))
+		for i, a := range args {
+			cmd[i] = a
+		}
+		v, ok := run(c, cmd, f, os.Stdout)
+		if ok && len(args) == 2 && strings.EqualFold(args[0], "select") && v.Err() == nil {
+			// the client switches its connection back to opts.DB, so like
+			// redis-cli the database is kept for the next connections
+			opts.DB, _ = strconv.Atoi(args[1])
+			c.Close()
+			c = client.New(opts)
+			prompt = promptFor(opts)
+		}
+	}
+}
+
+// promptFor returns the prompt of redis-cli, with the database when it
+// isn't 0.
+func promptFor(opts client.Options) string {
+	prompt := opts.Addr
+	if opts.Network == "unix" {
+		prompt = "tiny-redis " + opts.Addr
+	}
+	if opts.DB != 0 {
+		prompt += "[" + strconv.Itoa(opts.DB) + "]"
+	}
+	return prompt + "> "
+}
+
+// readLineRaw reads a line with the terminal in raw mode.
+func readLineRaw(fd int, ed *editor, prompt string) (string, error) {
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	line, err := ed.readLine(prompt)
+	// raw mode doesn't translate \n, so move to the next line by hand
+	fmt.Fprint(ed.out, "\r\n")
+	return line, err
+}
+
+// sensitive reports whether a command holds a password and must be kept
+// out of the history, as in redis-cli.
+func sensitive(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch strings.ToLower(args[0]) {
+	case "auth":
+		return true
+	case "hello":
+		for _, a := range args[1:] {
+			if strings.EqualFold(a, "auth") {
+				return true
+			}
+		}
+	case "acl":
+		return len(args) > 1 && strings.EqualFold(args[1], "setuser")
+	case "config":
+		if len(args) > 1 && strings.EqualFold(args[1], "set") {
+			for _, a := range args[2:] {
+				if strings.EqualFold(a, "requirepass") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tiny-redis-cli_history")
+}
+
+func loadHistory(path string) []string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func saveHistory(path string, history []string) {
+	if path == "" {
+		return
+	}
+	ioutil.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0600)
+}
+
+// editor is a minimal line editor: cursor movement, history and the usual
+// emacs style shortcuts. It expects the terminal to be in raw mode.
+type editor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history []string
+}
+
+func (ed *editor) add(line string) {
+	if n := len(ed.history); n > 0 && ed.history[n-1] == line {
+		return
+	}
+	ed.history = append(ed.history, line)
+	if len(ed.history) > historyLen {
+		ed.history = ed.history[len(ed.history)-historyLen:]
+	}
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// readLine reads a line, returning io.EOF on Ctrl-D of an empty line and
+// errInterrupted on Ctrl-C.
+func (ed *editor) readLine(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	// hist is the history entry shown, len(history) for the line being
+	// edited, which is kept in saved
+	hist := len(ed.history)
+	var saved []rune
+	ed.refresh(prompt, buf, pos)
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case keyEnter, '\n':
+			return string(buf), nil
+		case keyCtrlC:
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(buf) == 0 {
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case keyBackspace, keyCtrlH:
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case keyCtrlA:
+			pos = 0
+		case keyCtrlE:
+			pos = len(buf)
+		case keyCtrlB:
+			if pos > 0 {
+				pos--
+			}
+		case keyCtrlF:
+			if pos < len(buf) {
+				pos++
+			}
+		case keyCtrlK:
+			buf = buf[:pos]
+		case keyCtrlU:
+			buf, pos = buf[pos:], 0
+		case keyCtrlW:
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+		case keyCtrlL:
+			fmt.Fprint(ed.out, "\x1b[H\x1b[2J")
+		case keyCtrlP, keyCtrlN:
+			buf, pos, hist, saved = ed.browse(r == keyCtrlP, buf, hist, saved)
+		case keyEscape:
+			var up bool
+			switch ed.escape() {
+			case 'A':
+				up = true
+				fallthrough
+			case 'B':
+				buf, pos, hist, saved = ed.browse(up, buf, hist, saved)
+			case 'C':
+				if pos < len(buf) {
+					pos++
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(buf)
+			case '3':
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		case keyTab:
+		default:
+			if r >= ' ' {
+				buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+				pos++
+			}
+		}
+		ed.refresh(prompt, buf, pos)
+	}
+}
+
+// escape reads the rest of an escape sequence and returns its final byte,
+// e.g. 'A' for the up arrow. The delete key (ESC [ 3 ~) yields '3'.
+func (ed *editor) escape() byte {
+	b, err := ed.in.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return 0
+	}
+	c, err := ed.in.ReadByte()
+	if err != nil {
+		return 0
+	}
+	if c >= '0' && c <= '9' {
+		// ESC [ n ~
+		ed.in.ReadByte()
+	}
+	return c
+}
+
+// browse moves through the history, up towards older entries.
+func (ed *editor) browse(up bool, buf []rune, hist int, saved []rune) ([]rune, int, int, []rune) {
+	if hist == len(ed.history) {
+		saved = buf
+	}
+	if up && hist > 0 {
+		hist--
+	} else if !up && hist < len(ed.history) {
+		hist++
+	}
+	if hist == len(ed.history) {
+		buf = append([]rune{}, saved...)
+	} else {
+		buf = []rune(ed.history[hist])
+	}
+	return buf, len(buf), hist, saved
+}
+
+// refresh redraws the prompt and line, then puts the cursor at pos.
+func (ed *editor) refresh(prompt string, buf []rune, pos int) {
+	s := "\r" + prompt + string(buf) + "\x1b[K"
+	if back := len(buf) - pos; back > 0 {
+		s += fmt.Sprintf("\x1b[%dD", back)
+	}
+	fmt.Fprint(ed.out, s)
+}
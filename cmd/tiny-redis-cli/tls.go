@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// tlsOptions are the flags of a TLS connection, named like redis-cli's.
+type tlsOptions struct {
+	sni      string
+	cacert   string
+	cert     string
+	key      string
+	insecure bool
+}
+
+// config returns the client configuration described by o.
+func (o tlsOptions) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         o.sni,
+		InsecureSkipVerify: o.insecure,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if o.cacert != "" {
+		pem, err := ioutil.ReadFile(o.cacert)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", o.cacert)
+		}
+	}
+	if o.cert != "" || o.key != "" {
+		cert, err := tls.LoadX509KeyPair(o.cert, o.key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,328 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/acl"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+var (
+	ErrNoAuth          = errors.New("NOAUTH Authentication required.")
+	ErrNoPermKey       = errors.New("NOPERM No permissions to access a key")
+	ErrAuthNotNeeded   = errors.New("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+	ErrHelloNoAuth     = errors.New("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+	ErrNoACLFile       = errors.New("ERR This Redis instance is not configured to use an ACL file. You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE (assuming you have a Redis configuration file set) in order to store users in the Redis configuration.")
+	ErrLogCountInvalid = errors.New("ERR value is out of range, must be positive")
+	ErrNoACL           = errors.New("ERR ACL is not available to this client")
+)
+
+// Categories returns the ACL categories of a command, "config|get" for a
+// subcommand. It is the acl.Resolver of the command table.
+func Categories(name string) ([]string, bool) {
+	name = strings.ToLower(name)
+	parent := name
+	if i := strings.IndexByte(name, '|'); i >= 0 {
+		parent = name[:i]
+	}
+	c, ok := lookupCommand(parent)
+	if !ok {
+		return nil, false
+	}
+	if parent != name {
+		if c, ok = c.lookupSubcommand(name[len(parent)+1:]); !ok {
+			return nil, false
+		}
+	}
+	return c.categories, true
+}
+
+// keyPerm returns the access the command needs to its keys.
+func (c *commandInfo) keyPerm() acl.Perm {
+	switch {
+	case c.hasFlag("write"):
+		return acl.Write
+	case c.hasFlag("readonly"):
+		return acl.Read
+	}
+	return acl.Read | acl.Write
+}
+
+// authenticated reports whether the client may run commands other than
+// AUTH and HELLO. A client whose user was deleted has to authenticate again.
+func (cl *Client) authenticated() bool {
+	if cl.ACL == nil {
+		return true
+	}
+	_, ok := cl.ACL.User(cl.User)
+	return ok
+}
+
+// username returns the name of the user the client is authenticated as.
+func (cl *Client) username() string {
+	if cl.ACL == nil {
+		return acl.DefaultUser
+	}
+	return cl.User
+}
+
+// checkAccess checks that the user of the client may run the command with
+// args, logging the denials.
+func (cl *Client) checkAccess(c *commandInfo, args [][]byte) error {
+	if cl.ACL == nil || c.hasFlag("no_auth") {
+		return nil
+	}
+	u, ok := cl.ACL.User(cl.User)
+	if !ok {
+		return ErrNoAuth
+	}
+	if len(c.subcommands) > 0 && len(args) > 1 {
+		if sub, ok := c.lookupSubcommand(string(args[1])); ok {
+			c = sub
+		}
+	}
+	if !u.CanRun(c.name, c.categories) {
+		cl.logACL(acl.ReasonCommand, c.name, u.Name)
+		return fmt.Errorf("NOPERM User %s has no permissions to run the '%s' command", u.Name, c.name)
+	}
+	perm := c.keyPerm()
+	for _, i := range c.getKeys(args) {
+		if !u.CanAccessKey(args[i], perm) {
+			cl.logACL(acl.ReasonKey, string(args[i]), u.Name)
+			return ErrNoPermKey
+		}
+	}
+	return nil
+}
+
+func (cl *Client) logACL(reason, object, user string) {
+	cl.ACL.AddLog(acl.LogEntry{
+		Reason:     reason,
+		Context:    "toplevel",
+		Object:     object,
+		Username:   user,
+		ClientInfo: cl.info(),
+	}, int(cl.config().Int("acllog-max-len")))
+}
+
+// info describes the client like CLIENT INFO, with the fields tiny-redis
+// knows about.
+func (cl *Client) info() string {
+	return fmt.Sprintf("id=%d addr=%s name=%s resp=%d user=%s", cl.ID, cl.Addr, cl.Name, cl.Protocol(), cl.User)
+}
+
+// authenticate checks a username and password pair and, when valid, makes
+// the client run as that user. Without an ACL only "default" exists, and
+// it has no password.
+func (cl *Client) authenticate(user, pass []byte) error {
+	if cl.ACL == nil {
+		if string(user) != acl.DefaultUser {
+			return ErrWrongPass
+		}
+		return nil
+	}
+	if !cl.ACL.Authenticate(string(user), string(pass)) {
+		cl.logACL(acl.ReasonAuth, "AUTH", string(user))
+		return ErrWrongPass
+	}
+	cl.User = string(user)
+	return nil
+}
+
+// auth implements AUTH [username] password.
+func (cl *Client) auth(s [][]byte) (interface{}, error) {
+	switch len(s) {
+	case 2:
+		if cl.ACL == nil || cl.ACL.NoAuthUser() == acl.DefaultUser {
+			return nil, ErrAuthNotNeeded
+		}
+		if err := cl.authenticate([]byte(acl.DefaultUser), s[1]); err != nil {
+			return nil, err
+		}
+	case 3:
+		if err := cl.authenticate(s[1], s[2]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidSyntax
+	}
+	return "OK", nil
+}
+
+// aclCommand implements ACL and its subcommands.
+func (cl *Client) aclCommand(s [][]byte) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	container, _ := lookupCommand("acl")
+	sub, ok := container.lookupSubcommand(string(s[1]))
+	if !ok {
+		return nil, fmt.Errorf("ERR unknown subcommand '%s'. Try ACL HELP.", s[1])
+	}
+	if !sub.checkArity(len(s)) {
+		return nil, ErrWrongNumOfArgs
+	}
+	switch sub.name {
+	case "acl|whoami":
+		return []byte(cl.username()), nil
+	case "acl|cat":
+		return aclCat(s)
+	}
+	a := cl.ACL
+	if a == nil {
+		return nil, ErrNoACL
+	}
+	switch sub.name {
+	case "acl|setuser":
+		rules := make([]string, len(s)-3)
+		for i, r := range s[3:] {
+			rules[i] = string(r)
+		}
+		if err := a.SetUser(string(s[2]), rules...); err != nil {
+			return nil, fmt.Errorf("ERR %v", err)
+		}
+		return "OK", nil
+	case "acl|getuser":
+		u, ok := a.User(string(s[2]))
+		if !ok {
+			return nil, nil
+		}
+		passwords := make([]interface{}, 0)
+		for _, p := range u.Passwords() {
+			passwords = append(passwords, []byte(p))
+		}
+		flags := make([]interface{}, 0)
+		for _, f := range u.Flags() {
+			flags = append(flags, []byte(f))
+		}
+		return resp.Map{
+			{Key: []byte("flags"), Value: flags},
+			{Key: []byte("passwords"), Value: passwords},
+			{Key: []byte("commands"), Value: []byte(u.CommandRules())},
+			{Key: []byte("keys"), Value: []byte(u.KeyRules())},
+			{Key: []byte("channels"), Value: []byte(u.ChannelRules())},
+			{Key: []byte("selectors"), Value: []interface{}{}},
+		}, nil
+	case "acl|deluser":
+		n := 0
+		for _, name := range s[2:] {
+			ok, err := a.DelUser(string(name))
+			if err != nil {
+				return nil, fmt.Errorf("ERR %v", err)
+			}
+			if ok {
+				n++
+			}
+		}
+		return n, nil
+	case "acl|list":
+		r := []interface{}{}
+		for _, u := range a.Users() {
+			r = append(r, []byte(u.String()))
+		}
+		return r, nil
+	case "acl|users":
+		r := []interface{}{}
+		for _, u := range a.Users() {
+			r = append(r, []byte(u.Name))
+		}
+		return r, nil
+	case "acl|log":
+		return cl.aclLog(a, s)
+	case "acl|save", "acl|load":
+		path := cl.config().String("aclfile")
+		if path == "" {
+			return nil, ErrNoACLFile
+		}
+		var err error
+		if sub.name == "acl|save" {
+			err = a.Save(path)
+		} else {
+			err = a.Load(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ERR %v", err)
+		}
+		return "OK", nil
+	}
+	return nil, ErrInvalidCommand
+}
+
+// aclCat implements ACL CAT [category].
+func aclCat(s [][]byte) (interface{}, error) {
+	r := []interface{}{}
+	if len(s) == 2 {
+		for _, c := range acl.Categories {
+			r = append(r, []byte(c))
+		}
+		return r, nil
+	}
+	cat := strings.ToLower(string(s[2]))
+	known := false
+	for _, c := range acl.Categories {
+		known = known || c == cat
+	}
+	if !known {
+		return nil, fmt.Errorf("ERR Unknown category '%s'", s[2])
+	}
+	var names []string
+	add := func(c *commandInfo) {
+		for _, x := range c.categories {
+			if x == "@"+cat {
+				names = append(names, c.name)
+				return
+			}
+		}
+	}
+	for _, c := range commandTable {
+		add(c)
+		for _, sub := range c.subcommands {
+			add(sub)
+		}
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		r = append(r, []byte(n))
+	}
+	return r, nil
+}
+
+// aclLog implements ACL LOG [count | RESET].
+func (cl *Client) aclLog(a *acl.ACL, s [][]byte) (interface{}, error) {
+	count := 10
+	if len(s) == 3 {
+		if strings.EqualFold(string(s[2]), "reset") {
+			a.ResetLog()
+			return "OK", nil
+		}
+		n, err := strconv.Atoi(string(s[2]))
+		if err != nil || n < 0 {
+			return nil, ErrLogCountInvalid
+		}
+		count = n
+	} else if len(s) > 3 {
+		return nil, ErrInvalidSyntax
+	}
+	now := time.Now()
+	r := []interface{}{}
+	for _, e := range a.Log(count) {
+		r = append(r, resp.Map{
+			{Key: []byte("count"), Value: e.Count},
+			{Key: []byte("reason"), Value: []byte(e.Reason)},
+			{Key: []byte("context"), Value: []byte(e.Context)},
+			{Key: []byte("object"), Value: []byte(e.Object)},
+			{Key: []byte("username"), Value: []byte(e.Username)},
+			{Key: []byte("age-seconds"), Value: now.Sub(e.Created).Seconds()},
+			{Key: []byte("client-info"), Value: []byte(e.ClientInfo)},
+			{Key: []byte("entry-id"), Value: int(e.EntryID)},
+			{Key: []byte("timestamp-created"), Value: int(e.Created.UnixNano() / 1e6)},
+			{Key: []byte("timestamp-last-updated"), Value: int(e.Updated.UnixNano() / 1e6)},
+		})
+	}
+	return r, nil
+}
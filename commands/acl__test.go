@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/acl"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__ACL(t *testing.T) {
+	users := acl.New(Categories)
+	admin := &Client{Store: store.New(), ACL: users, User: users.NoAuthUser(), ID: 1}
+	_, err := admin.Execute(bA([]string{"ACL", "SETUSER", "alice", "on", ">pw", "~alice:*", "%R~shared:*", "+@read", "+set", "+acl|whoami"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// what the server does for CONFIG SET requirepass
+	users.SetRequirePass("secret")
+
+	cl := &Client{Store: admin.Store, ACL: users, User: users.NoAuthUser(), ID: 2, Addr: "127.0.0.1:5000"}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		{[]string{"GET", "alice:1"}, nil, ErrNoAuth.Error()},
+		{[]string{"NOSUCH"}, nil, ErrInvalidCommand.Error()},
+		{[]string{"HELLO", "3"}, nil, ErrHelloNoAuth.Error()},
+		{[]string{"AUTH", "alice", "wrong"}, nil, ErrWrongPass.Error()},
+		{[]string{"AUTH", "alice", "pw"}, "OK", ""},
+		{[]string{"ACL", "WHOAMI"}, b("alice"), ""},
+		{[]string{"SET", "alice:1", "v"}, "OK", ""},
+		{[]string{"GET", "shared:1"}, nil, ""},
+		{[]string{"SET", "shared:1", "v"}, nil, ErrNoPermKey.Error()},
+		{[]string{"DEL", "alice:1"}, nil, "NOPERM User alice has no permissions to run the 'del' command"},
+		{[]string{"ACL", "LIST"}, nil, "NOPERM User alice has no permissions to run the 'acl|list' command"},
+		{[]string{"AUTH", "secret"}, "OK", ""},
+		{[]string{"ACL", "WHOAMI"}, b("default"), ""},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Execute(%q): got %v want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Execute(%q): got %q, %v want %q", tt.input, got, err, tt.expected)
+		}
+	}
+
+	got, err := admin.Execute(bA([]string{"ACL", "LOG"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []string
+	for _, e := range got.([]interface{}) {
+		m := map[string]interface{}{}
+		for _, p := range e.(resp.Map) {
+			m[string(p.Key.([]byte))] = p.Value
+		}
+		entries = append(entries, fmt.Sprintf("%s %s %s %d %s", m["reason"], m["object"], m["username"], m["count"], m["client-info"]))
+	}
+	expected := []string{
+		"command acl|list alice 1 id=2 addr=127.0.0.1:5000 name= resp=2 user=alice",
+		"command del alice 1 id=2 addr=127.0.0.1:5000 name= resp=2 user=alice",
+		"key shared:1 alice 1 id=2 addr=127.0.0.1:5000 name= resp=2 user=alice",
+		"auth AUTH alice 1 id=2 addr=127.0.0.1:5000 name= resp=2 user=",
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("ACL LOG: got %q want %q", entries, expected)
+	}
+	if r, _ := admin.Execute(bA([]string{"ACL", "LOG", "RESET"})); r != "OK" {
+		t.Errorf("ACL LOG RESET: got %q", r)
+	}
+
+	// deleted users have to authenticate again
+	cl.Execute(bA([]string{"AUTH", "alice", "pw"}))
+	if r, _ := admin.Execute(bA([]string{"ACL", "DELUSER", "alice", "nobody"})); r != 1 {
+		t.Errorf("ACL DELUSER: got %v want 1", r)
+	}
+	if _, err := cl.Execute(bA([]string{"PING"})); err != ErrNoAuth {
+		t.Errorf("PING after ACL DELUSER: got %v want %v", err, ErrNoAuth)
+	}
+}
+
+func Test__ACLUsers(t *testing.T) {
+	users := acl.New(Categories)
+	cl := &Client{Store: store.New(), ACL: users, User: acl.DefaultUser}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		{[]string{"ACL", "SETUSER", "bob", "on", "nopass", "+get", "~*"}, "OK", ""},
+		{[]string{"ACL", "SETUSER", "bob", "+nosuch"}, nil, "ERR Error in ACL SETUSER modifier '+nosuch': Unknown command or category name in ACL"},
+		{[]string{"ACL", "USERS"}, []interface{}{b("bob"), b("default")}, ""},
+		{[]string{"ACL", "LIST"}, []interface{}{b("user bob on nopass ~* resetchannels -@all +get"), b("user default on nopass ~* &* +@all")}, ""},
+		{[]string{"ACL", "GETUSER", "bob"}, resp.Map{
+			{Key: b("flags"), Value: []interface{}{b("on"), b("nopass")}},
+			{Key: b("passwords"), Value: []interface{}{}},
+			{Key: b("commands"), Value: b("-@all +get")},
+			{Key: b("keys"), Value: b("~*")},
+			{Key: b("channels"), Value: b("")},
+			{Key: b("selectors"), Value: []interface{}{}},
+		}, ""},
+		{[]string{"ACL", "GETUSER", "nobody"}, nil, ""},
+		{[]string{"ACL", "DELUSER", "default"}, nil, "ERR The 'default' user cannot be removed"},
+		{[]string{"ACL", "CAT", "connection"}, []interface{}{b("auth"), b("command"), b("command|count"), b("command|docs"), b("command|getkeys"), b("command|info"), b("echo"), b("hello"), b("ping"), b("select")}, ""},
+		{[]string{"ACL", "CAT", "nosuch"}, nil, "ERR Unknown category 'nosuch'"},
+		{[]string{"ACL", "SAVE"}, nil, ErrNoACLFile.Error()},
+		{[]string{"ACL", "NOSUCH"}, nil, "ERR unknown subcommand 'NOSUCH'. Try ACL HELP."},
+		{[]string{"AUTH", "pw"}, nil, ErrAuthNotNeeded.Error()},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Execute(%q): got %v want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Execute(%q): got %q, %v want %q", tt.input, got, err, tt.expected)
+		}
+	}
+}
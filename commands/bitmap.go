@@ -0,0 +1,523 @@
+package commands
+
+import (
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+var (
+	ErrBitNotIntOrOutOfRange = errors.New("ERR bit is not an integer or out of range")
+	ErrBitArgNotBinary       = errors.New("ERR The bit argument must be 1 or 0.")
+	ErrBitOpNotSingleSource  = errors.New("ERR BITOP NOT must be called with a single source key.")
+	ErrInvalidBitfieldType   = errors.New("ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+	ErrInvalidOverflowType   = errors.New("ERR Invalid OVERFLOW type specified")
+	ErrBitfieldROGetOnly     = errors.New("ERR BITFIELD_RO only supports the GET subcommand")
+)
+
+// bitOffset parses the bit offset of SETBIT, GETBIT and BITFIELD. With
+// width set the offset may be written #N, meaning N*width. Like Redis, the
+// bitmap can't grow past proto-max-bulk-len bytes.
+func (cl *Client) bitOffset(arg []byte, width int) (int64, error) {
+	s := string(arg)
+	mul := int64(1)
+	if width > 0 && strings.HasPrefix(s, "#") {
+		s, mul = s[1:], int64(width)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)/mul {
+		return 0, ErrBitOffsetNotIntOrOutOfRange
+	}
+	n *= mul
+	if n>>3 >= cl.config().Int("proto-max-bulk-len") {
+		return 0, ErrBitOffsetNotIntOrOutOfRange
+	}
+	return n, nil
+}
+
+// getBit returns the bit at offset of v, bits are numbered from the most
+// significant bit of the first byte.
+func getBit(v []byte, offset int64) int {
+	i := offset >> 3
+	if i >= int64(len(v)) {
+		return 0
+	}
+	return int(v[i]>>(7-uint(offset&7))) & 1
+}
+
+func setBit(v []byte, offset int64, bit int) {
+	mask := byte(1) << (7 - uint(offset&7))
+	if bit == 1 {
+		v[offset>>3] |= mask
+	} else {
+		v[offset>>3] &^= mask
+	}
+}
+
+// grown returns a copy of v that is at least n bytes long, padded with
+// zero bytes.
+func grown(v []byte, n int64) []byte {
+	if n < int64(len(v)) {
+		n = int64(len(v))
+	}
+	r := make([]byte, n)
+	copy(r, v)
+	return r
+}
+
+// bitmap returns the string at key to read it, see store.DB.Borrow.
+func bitmap(kv *store.DB, key []byte) ([]byte, bool) {
+	v, ok := kv.Borrow(key)
+	b, _ := v.([]byte)
+	return b, ok
+}
+
+// getbit implements GETBIT key offset.
+func (cl *Client) getbit(s [][]byte) (interface{}, error) {
+	if len(s) != 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	offset, err := cl.bitOffset(s[2], 0)
+	if err != nil {
+		return nil, err
+	}
+	v, _ := bitmap(cl.db(), s[1])
+	return getBit(v, offset), nil
+}
+
+// setbit implements SETBIT key offset value and replies with the previous
+// bit.
+func (cl *Client) setbit(s [][]byte) (interface{}, error) {
+	if len(s) != 4 {
+		return nil, ErrWrongNumOfArgs
+	}
+	offset, err := cl.bitOffset(s[2], 0)
+	if err != nil {
+		return nil, err
+	}
+	bit, err := strconv.Atoi(string(s[3]))
+	if err != nil || (bit != 0 && bit != 1) {
+		return nil, ErrBitNotIntOrOutOfRange
+	}
+	var old int
+	cl.db().Update(s[1], offset>>3+1, func(v []byte) {
+		old = getBit(v, offset)
+		setBit(v, offset, bit)
+	})
+	return old, nil
+}
+
+// bitRange parses the optional start end [BYTE|BIT] arguments of BITCOUNT
+// and BITPOS and returns the range as inclusive bit offsets into a value of
+// n bytes. ok is false when the range is empty.
+func bitRange(args [][]byte, n int) (start, end int64, endGiven, ok bool, err error) {
+	inBits := false
+	if len(args) == 3 {
+		switch strings.ToUpper(string(args[2])) {
+		case "BYTE":
+		case "BIT":
+			inBits = true
+		default:
+			return 0, 0, false, false, ErrInvalidSyntax
+		}
+	}
+	total := int64(n)
+	if inBits {
+		total *= 8
+	}
+	start, end = 0, total-1
+	if len(args) > 0 {
+		if start, err = strconv.ParseInt(string(args[0]), 10, 64); err != nil {
+			return 0, 0, false, false, ErrValNotIntOrOutOfRange
+		}
+	}
+	if len(args) > 1 {
+		if end, err = strconv.ParseInt(string(args[1]), 10, 64); err != nil {
+			return 0, 0, false, false, ErrValNotIntOrOutOfRange
+		}
+		endGiven = true
+	}
+	if start < 0 {
+		start += total
+	}
+	if end < 0 {
+		end += total
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= total {
+		end = total - 1
+	}
+	if start > end {
+		return 0, 0, endGiven, false, nil
+	}
+	if !inBits {
+		start, end = start*8, end*8+7
+	}
+	return start, end, endGiven, true, nil
+}
+
+// bitcount implements BITCOUNT key [start end [BYTE|BIT]].
+func bitcount(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) != 2 && len(s) != 4 && len(s) != 5 {
+		if len(s) < 2 {
+			return nil, ErrWrongNumOfArgs
+		}
+		return nil, ErrInvalidSyntax
+	}
+	v, _ := bitmap(kv, s[1])
+	start, end, _, ok, err := bitRange(s[2:], len(v))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return 0, nil
+	}
+	n := 0
+	for i := start; i <= end; {
+		if i&7 == 0 && i+7 <= end {
+			n += bits.OnesCount8(v[i>>3])
+			i += 8
+			continue
+		}
+		n += getBit(v, i)
+		i++
+	}
+	return n, nil
+}
+
+// bitpos implements BITPOS key bit [start [end [BYTE|BIT]]]. When looking
+// for a clear bit without an end, the value is considered padded with zero
+// bytes, so the bit past its end is found.
+func bitpos(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 3 || len(s) > 6 {
+		if len(s) < 3 {
+			return nil, ErrWrongNumOfArgs
+		}
+		return nil, ErrInvalidSyntax
+	}
+	bit, err := strconv.Atoi(string(s[2]))
+	if err != nil || (bit != 0 && bit != 1) {
+		return nil, ErrBitArgNotBinary
+	}
+	v, ok := bitmap(kv, s[1])
+	if !ok {
+		if bit == 1 {
+			return -1, nil
+		}
+		return 0, nil
+	}
+	start, end, endGiven, ok, err := bitRange(s[3:], len(v))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return -1, nil
+	}
+	// whole bytes without the bit looked for are skipped
+	skip := byte(0)
+	if bit == 0 {
+		skip = 0xff
+	}
+	for i := start; i <= end; {
+		if i&7 == 0 && i+7 <= end && v[i>>3] == skip {
+			i += 8
+			continue
+		}
+		if getBit(v, i) == bit {
+			return int(i), nil
+		}
+		i++
+	}
+	if bit == 0 && !endGiven {
+		return int(end + 1), nil
+	}
+	return -1, nil
+}
+
+// bitop implements BITOP AND|OR|XOR|NOT destkey key [key ...]. Shorter
+// values are padded with zero bytes. An empty result deletes destkey.
+func bitop(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 4 {
+		return nil, ErrWrongNumOfArgs
+	}
+	op := strings.ToUpper(string(s[1]))
+	switch op {
+	case "AND", "OR", "XOR":
+	case "NOT":
+		if len(s) != 4 {
+			return nil, ErrBitOpNotSingleSource
+		}
+	default:
+		return nil, ErrInvalidSyntax
+	}
+	srcs := make([][]byte, len(s)-3)
+	n := 0
+	for i, key := range s[3:] {
+		srcs[i], _ = bitmap(kv, key)
+		if len(srcs[i]) > n {
+			n = len(srcs[i])
+		}
+	}
+	if n == 0 {
+		kv.Del(s[2])
+		return 0, nil
+	}
+	r := grown(srcs[0], int64(n))
+	if op == "NOT" {
+		for i := range r {
+			r[i] = ^r[i]
+		}
+	}
+	for _, src := range srcs[1:] {
+		for i := range r {
+			var b byte
+			if i < len(src) {
+				b = src[i]
+			}
+			switch op {
+			case "AND":
+				r[i] &= b
+			case "OR":
+				r[i] |= b
+			case "XOR":
+				r[i] ^= b
+			}
+		}
+	}
+	kv.Set(s[2], r)
+	return n, nil
+}
+
+// bitfieldType is an integer type of BITFIELD such as i16 or u8.
+type bitfieldType struct {
+	signed bool
+	bits   uint
+}
+
+func parseBitfieldType(arg []byte) (bitfieldType, error) {
+	s := strings.ToLower(string(arg))
+	if len(s) < 2 || (s[0] != 'i' && s[0] != 'u') {
+		return bitfieldType{}, ErrInvalidBitfieldType
+	}
+	t := bitfieldType{signed: s[0] == 'i'}
+	n, err := strconv.Atoi(s[1:])
+	max := 63
+	if t.signed {
+		max = 64
+	}
+	if err != nil || n < 1 || n > max {
+		return bitfieldType{}, ErrInvalidBitfieldType
+	}
+	t.bits = uint(n)
+	return t, nil
+}
+
+// get reads the integer at offset of v, most significant bit first.
+func (t bitfieldType) get(v []byte, offset int64) int64 {
+	var u uint64
+	for i := int64(0); i < int64(t.bits); i++ {
+		u = u<<1 | uint64(getBit(v, offset+i))
+	}
+	if t.signed && t.bits < 64 && u&(1<<(t.bits-1)) != 0 {
+		u |= ^uint64(0) << t.bits
+	}
+	return int64(u)
+}
+
+func (t bitfieldType) set(v []byte, offset int64, n int64) {
+	u := uint64(n)
+	for i := int64(0); i < int64(t.bits); i++ {
+		setBit(v, offset+i, int(u>>(int64(t.bits)-1-i))&1)
+	}
+}
+
+// Overflow behaviours of BITFIELD.
+const (
+	overflowWrap = iota
+	overflowSat
+	overflowFail
+)
+
+// add returns value+incr in the range of t following the overflow
+// behaviour, ok is false when it fails. The checks mirror the ones of
+// Redis, including their use of wrapping arithmetic.
+func (t bitfieldType) add(value, incr int64, overflow int) (r int64, ok bool) {
+	if !t.signed {
+		max := uint64(1)<<t.bits - 1
+		maxIncr, minIncr := int64(max)-value, -value
+		switch {
+		case uint64(value) > max || incr > maxIncr || (value >= 0 && incr > 0 && incr > maxIncr):
+			if overflow == overflowSat {
+				return int64(max), true
+			}
+		case incr < 0 && incr < minIncr:
+			if overflow == overflowSat {
+				return 0, true
+			}
+		default:
+			return value + incr, true
+		}
+		if overflow == overflowFail {
+			return 0, false
+		}
+		return int64(uint64(value+incr) & max), true
+	}
+	max := int64(1)<<(t.bits-1) - 1
+	if t.bits == 64 {
+		max = 1<<63 - 1
+	}
+	min := -max - 1
+	maxIncr, minIncr := max-value, min-value
+	switch {
+	case value > max || (t.bits != 64 && incr > maxIncr) || (value >= 0 && incr > 0 && incr > maxIncr):
+		if overflow == overflowSat {
+			return max, true
+		}
+	case value < min || (t.bits != 64 && incr < minIncr) || (value < 0 && incr < 0 && incr < minIncr):
+		if overflow == overflowSat {
+			return min, true
+		}
+	default:
+		return value + incr, true
+	}
+	if overflow == overflowFail {
+		return 0, false
+	}
+	c := uint64(value + incr)
+	if t.bits < 64 {
+		mask := ^uint64(0) << t.bits
+		if c&(1<<(t.bits-1)) != 0 {
+			c |= mask
+		} else {
+			c &^= mask
+		}
+	}
+	return int64(c), true
+}
+
+// bitfieldOp is a GET, SET or INCRBY operation of BITFIELD.
+type bitfieldOp struct {
+	op       string
+	t        bitfieldType
+	offset   int64
+	arg      int64
+	overflow int
+}
+
+// bitfield implements BITFIELD key [GET type offset] [SET type offset value]
+// [INCRBY type offset increment] [OVERFLOW WRAP|SAT|FAIL] ... and, with ro
+// set, BITFIELD_RO key [GET type offset] ... Every operation is parsed
+// before any of them runs.
+func (cl *Client) bitfield(s [][]byte, ro bool) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	var ops []bitfieldOp
+	overflow := overflowWrap
+	write := false
+	for i := 2; i < len(s); {
+		op := strings.ToUpper(string(s[i]))
+		if ro && op != "GET" {
+			return nil, ErrBitfieldROGetOnly
+		}
+		switch op {
+		case "OVERFLOW":
+			if i+1 >= len(s) {
+				return nil, ErrInvalidSyntax
+			}
+			switch strings.ToUpper(string(s[i+1])) {
+			case "WRAP":
+				overflow = overflowWrap
+			case "SAT":
+				overflow = overflowSat
+			case "FAIL":
+				overflow = overflowFail
+			default:
+				return nil, ErrInvalidOverflowType
+			}
+			i += 2
+			continue
+		case "GET":
+			if i+2 >= len(s) {
+				return nil, ErrInvalidSyntax
+			}
+		case "SET", "INCRBY":
+			if i+3 >= len(s) {
+				return nil, ErrInvalidSyntax
+			}
+			write = true
+		default:
+			return nil, ErrInvalidSyntax
+		}
+		t, err := parseBitfieldType(s[i+1])
+		if err != nil {
+			return nil, err
+		}
+		offset, err := cl.bitOffset(s[i+2], int(t.bits))
+		if err != nil {
+			return nil, err
+		}
+		if (offset+int64(t.bits)-1)>>3 >= cl.config().Int("proto-max-bulk-len") {
+			return nil, ErrBitOffsetNotIntOrOutOfRange
+		}
+		o := bitfieldOp{op: op, t: t, offset: offset, overflow: overflow}
+		i += 3
+		if op != "GET" {
+			if o.arg, err = strconv.ParseInt(string(s[i]), 10, 64); err != nil {
+				return nil, ErrValNotIntOrOutOfRange
+			}
+			i++
+		}
+		ops = append(ops, o)
+	}
+	r := make([]interface{}, len(ops))
+	run := func(v []byte) {
+		for i, o := range ops {
+			r[i] = o.run(v)
+		}
+	}
+	kv := cl.db()
+	if !write {
+		v, _ := bitmap(kv, s[1])
+		run(v)
+		return r, nil
+	}
+	// the value grows to the end of the last field written
+	n := int64(0)
+	for _, o := range ops {
+		if end := (o.offset+int64(o.t.bits)-1)>>3 + 1; o.op != "GET" && end > n {
+			n = end
+		}
+	}
+	kv.Update(s[1], n, run)
+	return r, nil
+}
+
+// run runs the operation on v and returns its reply.
+func (o bitfieldOp) run(v []byte) interface{} {
+	old := o.t.get(v, o.offset)
+	switch o.op {
+	case "SET":
+		n, ok := o.t.add(o.arg, 0, o.overflow)
+		if !ok {
+			return nil
+		}
+		o.t.set(v, o.offset, n)
+	case "INCRBY":
+		n, ok := o.t.add(old, o.arg, o.overflow)
+		if !ok {
+			return nil
+		}
+		o.t.set(v, o.offset, n)
+		return n
+	}
+	return old
+}
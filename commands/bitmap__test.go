@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__Bitmap(t *testing.T) {
+	cl := &Client{Store: store.New()}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		// the examples of the Redis docs
+		{[]string{"SETBIT", "k", "7", "1"}, 0, ""},
+		{[]string{"GET", "k"}, b("\x01"), ""},
+		{[]string{"GETBIT", "k", "7"}, 1, ""},
+		{[]string{"GETBIT", "k", "6"}, 0, ""},
+		{[]string{"GETBIT", "k", "100"}, 0, ""},
+		{[]string{"SETBIT", "k", "7", "0"}, 1, ""},
+		{[]string{"SETBIT", "k", "17", "1"}, 0, ""},
+		{[]string{"GET", "k"}, b("\x00\x00\x40"), ""},
+		{[]string{"GETBIT", "nosuch", "0"}, 0, ""},
+		{[]string{"SETBIT", "k", "0", "2"}, nil, ErrBitNotIntOrOutOfRange.Error()},
+		{[]string{"SETBIT", "k", "-1", "1"}, nil, ErrBitOffsetNotIntOrOutOfRange.Error()},
+		// past proto-max-bulk-len
+		{[]string{"SETBIT", "k", "4294967296", "1"}, nil, ErrBitOffsetNotIntOrOutOfRange.Error()},
+
+		{[]string{"SET", "k", "foobar"}, "OK", ""},
+		{[]string{"BITCOUNT", "k"}, 26, ""},
+		{[]string{"BITCOUNT", "k", "0", "0"}, 4, ""},
+		{[]string{"BITCOUNT", "k", "1", "1", "BYTE"}, 6, ""},
+		{[]string{"BITCOUNT", "k", "5", "30", "BIT"}, 17, ""},
+		{[]string{"BITCOUNT", "k", "-2", "-1"}, 7, ""},
+		{[]string{"BITCOUNT", "k", "4", "2"}, 0, ""},
+		{[]string{"BITCOUNT", "nosuch"}, 0, ""},
+		{[]string{"BITCOUNT", "k", "1"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"BITCOUNT", "k", "0", "1", "WORD"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"BITCOUNT", "k", "a", "1"}, nil, ErrValNotIntOrOutOfRange.Error()},
+
+		{[]string{"SET", "k", "\xff\xf0\x00"}, "OK", ""},
+		{[]string{"BITPOS", "k", "0"}, 12, ""},
+		{[]string{"SET", "k", "\x00\xff\xf0"}, "OK", ""},
+		{[]string{"BITPOS", "k", "1", "0"}, 8, ""},
+		{[]string{"BITPOS", "k", "1", "2"}, 16, ""},
+		{[]string{"BITPOS", "k", "1", "2", "-1", "BYTE"}, 16, ""},
+		{[]string{"BITPOS", "k", "1", "7", "15", "BIT"}, 8, ""},
+		{[]string{"SET", "k", "\x00\x00\x00"}, "OK", ""},
+		{[]string{"BITPOS", "k", "1"}, -1, ""},
+		{[]string{"BITPOS", "k", "1", "7", "-3", "BIT"}, -1, ""},
+		// a clear bit is found past the end only without an end
+		{[]string{"SET", "k", "\xff"}, "OK", ""},
+		{[]string{"BITPOS", "k", "0"}, 8, ""},
+		{[]string{"BITPOS", "k", "0", "0", "-1"}, -1, ""},
+		{[]string{"BITPOS", "nosuch", "0"}, 0, ""},
+		{[]string{"BITPOS", "nosuch", "1"}, -1, ""},
+		{[]string{"BITPOS", "k", "2"}, nil, ErrBitArgNotBinary.Error()},
+
+		{[]string{"SET", "key1", "foobar"}, "OK", ""},
+		{[]string{"SET", "key2", "abcdef"}, "OK", ""},
+		{[]string{"BITOP", "AND", "dest", "key1", "key2"}, 6, ""},
+		{[]string{"GET", "dest"}, b("`bc`ab"), ""},
+		{[]string{"SET", "short", "\x0f"}, "OK", ""},
+		{[]string{"BITOP", "XOR", "dest", "short", "key1"}, 6, ""},
+		{[]string{"GETRANGE", "dest", "0", "1"}, b("io"), ""},
+		{[]string{"BITOP", "NOT", "dest", "short"}, 1, ""},
+		{[]string{"GET", "dest"}, b("\xf0"), ""},
+		{[]string{"BITOP", "OR", "dest", "nosuch"}, 0, ""},
+		{[]string{"EXISTS", "dest"}, 0, ""},
+		{[]string{"BITOP", "NOT", "dest", "key1", "key2"}, nil, ErrBitOpNotSingleSource.Error()},
+		{[]string{"BITOP", "NAND", "dest", "key1"}, nil, ErrInvalidSyntax.Error()},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %q, %q want %q, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+}
+
+func Test__BITFIELD(t *testing.T) {
+	cl := &Client{Store: store.New()}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		{[]string{"BITFIELD", "k", "INCRBY", "i5", "100", "1", "GET", "u4", "0"}, []interface{}{int64(1), int64(0)}, ""},
+		// the overflow example of the Redis docs
+		{[]string{"BITFIELD", "k", "INCRBY", "u2", "100", "1", "OVERFLOW", "SAT", "INCRBY", "u2", "102", "1"}, []interface{}{int64(1), int64(1)}, ""},
+		{[]string{"BITFIELD", "k", "INCRBY", "u2", "100", "1", "OVERFLOW", "SAT", "INCRBY", "u2", "102", "1"}, []interface{}{int64(2), int64(2)}, ""},
+		{[]string{"BITFIELD", "k", "INCRBY", "u2", "100", "1", "OVERFLOW", "SAT", "INCRBY", "u2", "102", "1"}, []interface{}{int64(3), int64(3)}, ""},
+		{[]string{"BITFIELD", "k", "INCRBY", "u2", "100", "1", "OVERFLOW", "SAT", "INCRBY", "u2", "102", "1"}, []interface{}{int64(0), int64(3)}, ""},
+		{[]string{"BITFIELD", "k", "OVERFLOW", "FAIL", "INCRBY", "u2", "102", "1", "GET", "u2", "102"}, []interface{}{nil, int64(3)}, ""},
+		{[]string{"STRLEN", "k"}, 14, ""},
+
+		{[]string{"BITFIELD", "s", "SET", "i8", "0", "-100", "GET", "i8", "0", "GET", "u8", "0"}, []interface{}{int64(0), int64(-100), int64(156)}, ""},
+		// #N offsets are multiplied by the width of the type
+		{[]string{"BITFIELD", "s", "SET", "i8", "#1", "127", "INCRBY", "i8", "#1", "1"}, []interface{}{int64(0), int64(-128)}, ""},
+		{[]string{"BITFIELD", "s", "OVERFLOW", "SAT", "INCRBY", "i8", "#1", "-1000", "SET", "u8", "#1", "-1"}, []interface{}{int64(-128), int64(128)}, ""},
+		{[]string{"GET", "s"}, b("\x9c\xff"), ""},
+		{[]string{"BITFIELD", "s", "OVERFLOW", "FAIL", "SET", "u8", "0", "256", "GET", "u8", "0"}, []interface{}{nil, int64(156)}, ""},
+		{[]string{"BITFIELD", "s", "SET", "u8", "0", "257"}, []interface{}{int64(156)}, ""},
+		{[]string{"BITFIELD", "s", "GET", "u8", "0"}, []interface{}{int64(1)}, ""},
+		{[]string{"BITFIELD", "w", "SET", "i64", "0", "9223372036854775807", "INCRBY", "i64", "0", "1"}, []interface{}{int64(0), int64(-9223372036854775808)}, ""},
+		{[]string{"BITFIELD", "w", "OVERFLOW", "SAT", "SET", "u63", "0", "-1", "GET", "u63", "0"}, []interface{}{int64(4611686018427387904), int64(9223372036854775807)}, ""},
+
+		// only writes create the key
+		{[]string{"BITFIELD", "nosuch", "GET", "u8", "0"}, []interface{}{int64(0)}, ""},
+		{[]string{"BITFIELD_RO", "s", "GET", "u8", "8"}, []interface{}{int64(255)}, ""},
+		{[]string{"EXISTS", "nosuch"}, 0, ""},
+		{[]string{"BITFIELD", "k"}, []interface{}{}, ""},
+
+		{[]string{"BITFIELD", "k", "GET", "u64", "0"}, nil, ErrInvalidBitfieldType.Error()},
+		{[]string{"BITFIELD", "k", "GET", "i65", "0"}, nil, ErrInvalidBitfieldType.Error()},
+		{[]string{"BITFIELD", "k", "GET", "u8", "x"}, nil, ErrBitOffsetNotIntOrOutOfRange.Error()},
+		{[]string{"BITFIELD", "k", "SET", "u8", "0", "x"}, nil, ErrValNotIntOrOutOfRange.Error()},
+		{[]string{"BITFIELD", "k", "OVERFLOW", "NOPE"}, nil, ErrInvalidOverflowType.Error()},
+		{[]string{"BITFIELD", "k", "GET", "u8"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"BITFIELD", "k", "NOPE"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"BITFIELD_RO", "k", "SET", "u8", "0", "1"}, nil, ErrBitfieldROGetOnly.Error()},
+		// nothing runs when an operation is invalid
+		{[]string{"BITFIELD", "fresh", "SET", "u8", "0", "1", "GET", "u99", "0"}, nil, ErrInvalidBitfieldType.Error()},
+		{[]string{"EXISTS", "fresh"}, 0, ""},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %v, %q want %v, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/acl"
+	"github.com/tinfoil-knight/tiny-redis/config"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+// Client is the state a connection executes commands with. Store, Config,
+// Stats and ACL are usually shared by every client of a server.
+type Client struct {
+	Store  *store.Store
+	Config *config.Registry
+	Stats  *Stats
+	// ACL checks the permissions of User. Every command is allowed when nil.
+	ACL *acl.ACL
+
+	// ID uniquely identifies the connection, it is assigned by the server.
+	ID int64
+	// Name is set with HELLO SETNAME.
+	Name string
+	// Proto is the RESP version negotiated with HELLO, 2 when unset.
+	Proto int
+	// User is the name of the user the client is authenticated as, empty
+	// until it authenticates.
+	User string
+	// Addr is the address of the peer, it is shown in the ACL log.
+	Addr string
+	// DB is the index of the database selected with SELECT.
+	DB int
+}
+
+// Stats are the server wide counters reported by INFO. CONFIG RESETSTAT
+// resets all of them but Clients.
+type Stats struct {
+	// Commands is the number of commands processed.
+	Commands int64
+	// Errors is the number of commands that replied with an error.
+	Errors int64
+	// Connections is the number of connections accepted.
+	Connections int64
+	// Clients is the number of clients connected.
+	Clients int64
+	// Start is the time the server started at.
+	Start time.Time
+}
+
+// Reset zeroes every counter.
+func (st *Stats) Reset() {
+	atomic.StoreInt64(&st.Commands, 0)
+	atomic.StoreInt64(&st.Errors, 0)
+	atomic.StoreInt64(&st.Connections, 0)
+}
+
+// fallbackConfig is used by clients that were created without a registry,
+// e.g. through ExecuteCommand.
+var fallbackConfig = config.New()
+
+func (cl *Client) config() *config.Registry {
+	if cl.Config == nil {
+		return fallbackConfig
+	}
+	return cl.Config
+}
+
+// db returns the database selected by the client.
+func (cl *Client) db() *store.DB {
+	return cl.Store.DB(cl.DB)
+}
+
+// Protocol returns the RESP version replies must be encoded with.
+func (cl *Client) Protocol() int {
+	if cl.Proto == 0 {
+		return defaultProtoVersion
+	}
+	return cl.Proto
+}
+
+func (cl *Client) count(err error) {
+	if cl.Stats == nil {
+		return
+	}
+	atomic.AddInt64(&cl.Stats.Commands, 1)
+	if err != nil {
+		atomic.AddInt64(&cl.Stats.Errors, 1)
+	}
+}
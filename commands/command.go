@@ -0,0 +1,366 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+var (
+	ErrInvalidCommandSpecified = errors.New("ERR Invalid command specified")
+	ErrInvalidNumOfArgsForCmd  = errors.New("ERR Invalid number of arguments specified for command")
+	ErrNoKeyArguments          = errors.New("ERR The command has no key arguments")
+)
+
+// command implements COMMAND and its subcommands.
+func command(s [][]byte) (interface{}, error) {
+	if len(s) == 1 {
+		return commandInfoReply(sortedCommands()), nil
+	}
+	container, _ := lookupCommand("command")
+	sub, ok := container.lookupSubcommand(string(s[1]))
+	if !ok {
+		return nil, fmt.Errorf("ERR unknown subcommand '%s'. Try COMMAND HELP.", s[1])
+	}
+	if !sub.checkArity(len(s)) {
+		return nil, ErrWrongNumOfArgs
+	}
+	switch sub.name {
+	case "command|count":
+		return len(commandTable), nil
+	case "command|info":
+		if len(s) == 2 {
+			return commandInfoReply(sortedCommands()), nil
+		}
+		r := make([]interface{}, len(s)-2)
+		for i, name := range s[2:] {
+			if c, ok := lookupCommandPath(string(name)); ok {
+				r[i] = c.infoReply()
+			}
+		}
+		return r, nil
+	case "command|docs":
+		cmds := sortedCommands()
+		if len(s) > 2 {
+			cmds = cmds[:0]
+			for _, name := range s[2:] {
+				if c, ok := lookupCommandPath(string(name)); ok {
+					cmds = append(cmds, c)
+				}
+			}
+		}
+		r := make(resp.Map, 0, len(cmds))
+		for _, c := range cmds {
+			r = append(r, resp.Pair{Key: []byte(c.name), Value: c.docsReply()})
+		}
+		return r, nil
+	case "command|getkeys":
+		args := s[2:]
+		c, ok := lookupCommand(string(args[0]))
+		if !ok {
+			return nil, ErrInvalidCommandSpecified
+		}
+		if !c.checkArity(len(args)) {
+			return nil, ErrInvalidNumOfArgsForCmd
+		}
+		pos := c.getKeys(args)
+		if len(pos) == 0 {
+			return nil, ErrNoKeyArguments
+		}
+		r := make([]interface{}, len(pos))
+		for i, p := range pos {
+			r[i] = args[p]
+		}
+		return r, nil
+	}
+	return nil, ErrInvalidCommand
+}
+
+// lookupCommandPath resolves both plain names and "container|subcommand".
+func lookupCommandPath(path string) (*commandInfo, bool) {
+	parts := strings.SplitN(path, "|", 2)
+	c, ok := lookupCommand(parts[0])
+	if !ok || len(parts) == 1 {
+		return c, ok
+	}
+	return c.lookupSubcommand(parts[1])
+}
+
+func sortedCommands() []*commandInfo {
+	cmds := make([]*commandInfo, 0, len(commandTable))
+	for _, c := range commandTable {
+		cmds = append(cmds, c)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].name < cmds[j].name })
+	return cmds
+}
+
+func commandInfoReply(cmds []*commandInfo) []interface{} {
+	r := make([]interface{}, len(cmds))
+	for i, c := range cmds {
+		r[i] = c.infoReply()
+	}
+	return r
+}
+
+func statusList(ss []string) resp.Set {
+	r := make(resp.Set, len(ss))
+	for i, v := range ss {
+		r[i] = v
+	}
+	return r
+}
+
+// infoReply builds the 10 element entry returned by COMMAND INFO in Redis 7.
+func (c *commandInfo) infoReply() []interface{} {
+	subs := make([]interface{}, len(c.subcommands))
+	for i, sub := range c.subcommands {
+		subs[i] = sub.infoReply()
+	}
+	return []interface{}{
+		[]byte(c.name),
+		c.arity,
+		statusList(c.flags),
+		c.firstKey,
+		c.lastKey,
+		c.step,
+		statusList(c.categories),
+		statusList(c.tips),
+		c.keySpecsReply(),
+		subs,
+	}
+}
+
+// keySpecsReply derives a single range key specification from the legacy
+// first/last/step triple.
+func (c *commandInfo) keySpecsReply() []interface{} {
+	if c.firstKey == 0 {
+		return []interface{}{}
+	}
+	flags := []string{"RO", "ACCESS"}
+	if c.hasFlag("write") {
+		flags = []string{"RW", "UPDATE"}
+	}
+	last := c.lastKey
+	if last >= 0 {
+		last -= c.firstKey
+	}
+	spec := resp.Map{
+		{Key: []byte("flags"), Value: statusList(flags)},
+		{Key: []byte("begin_search"), Value: resp.Map{
+			{Key: []byte("type"), Value: []byte("index")},
+			{Key: []byte("spec"), Value: resp.Map{{Key: []byte("index"), Value: c.firstKey}}},
+		}},
+		{Key: []byte("find_keys"), Value: resp.Map{
+			{Key: []byte("type"), Value: []byte("range")},
+			{Key: []byte("spec"), Value: resp.Map{
+				{Key: []byte("lastkey"), Value: last},
+				{Key: []byte("keystep"), Value: c.step},
+				{Key: []byte("limit"), Value: 0},
+			}},
+		}},
+	}
+	return []interface{}{spec}
+}
+
+func (c *commandInfo) hasFlag(flag string) bool {
+	for _, f := range c.flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// docsReply builds the documentation map returned by COMMAND DOCS.
+func (c *commandInfo) docsReply() resp.Map {
+	r := resp.Map{
+		{Key: []byte("summary"), Value: []byte(c.summary)},
+		{Key: []byte("since"), Value: []byte(c.since)},
+		{Key: []byte("group"), Value: []byte(c.group)},
+		{Key: []byte("complexity"), Value: []byte(c.complexity)},
+	}
+	if c.syntax != "" {
+		args := parseSyntax(c.syntax)
+		ra := make([]interface{}, len(args))
+		for i, a := range args {
+			ra[i] = a.reply()
+		}
+		r = append(r, resp.Pair{Key: []byte("arguments"), Value: ra})
+	}
+	if len(c.subcommands) > 0 {
+		subs := make(resp.Map, 0, len(c.subcommands))
+		for _, sub := range c.subcommands {
+			subs = append(subs, resp.Pair{Key: []byte(sub.name), Value: sub.docsReply()})
+		}
+		r = append(r, resp.Pair{Key: []byte("subcommands"), Value: subs})
+	}
+	return r
+}
+
+// docArg is a single entry of the "arguments" section of COMMAND DOCS.
+type docArg struct {
+	name     string
+	typ      string
+	token    string
+	optional bool
+	multiple bool
+	args     []*docArg
+}
+
+func (a *docArg) reply() resp.Map {
+	r := resp.Map{
+		{Key: []byte("name"), Value: []byte(a.name)},
+		{Key: []byte("type"), Value: []byte(a.typ)},
+	}
+	if a.typ == "key" {
+		r = append(r, resp.Pair{Key: []byte("key_spec_index"), Value: 0})
+	}
+	if a.typ != "pure-token" && a.typ != "block" && a.typ != "oneof" {
+		r = append(r, resp.Pair{Key: []byte("display_text"), Value: []byte(a.name)})
+	}
+	if a.token != "" {
+		r = append(r, resp.Pair{Key: []byte("token"), Value: []byte(a.token)})
+	}
+	var flags []string
+	if a.optional {
+		flags = append(flags, "optional")
+	}
+	if a.multiple {
+		flags = append(flags, "multiple")
+	}
+	if len(flags) > 0 {
+		r = append(r, resp.Pair{Key: []byte("flags"), Value: statusList(flags)})
+	}
+	if len(a.args) > 0 {
+		sub := make([]interface{}, len(a.args))
+		for i, x := range a.args {
+			sub[i] = x.reply()
+		}
+		r = append(r, resp.Pair{Key: []byte("arguments"), Value: sub})
+	}
+	return r
+}
+
+// parseSyntax parses the compact argument notation used in the command
+// table into the structure reported by COMMAND DOCS:
+//
+//	UPPER        a pure token, e.g. REPLACE
+//	name[:type]  an argument; type defaults to "key" for "key", else "string"
+//	TOKEN name   an argument introduced by a token, e.g. DB destination-db
+//	[a|b]        optional; alternatives make a "oneof"
+//	name(a b)    a named block
+//	x...         x may be repeated
+func parseSyntax(s string) []*docArg {
+	p := &syntaxParser{s: s}
+	return p.sequence()
+}
+
+type syntaxParser struct {
+	s string
+	i int
+}
+
+func (p *syntaxParser) sequence() []*docArg {
+	var seq []*docArg
+	for {
+		for p.i < len(p.s) && p.s[p.i] == ' ' {
+			p.i++
+		}
+		if p.i >= len(p.s) || strings.IndexByte("|])", p.s[p.i]) >= 0 {
+			break
+		}
+		seq = append(seq, p.item())
+	}
+	// fold "TOKEN arg" pairs into a single argument with a token
+	out := seq[:0]
+	for i := 0; i < len(seq); i++ {
+		a := seq[i]
+		if a.typ == "pure-token" && !a.optional && !a.multiple && i+1 < len(seq) {
+			next := seq[i+1]
+			if next.token == "" && next.typ != "pure-token" && next.typ != "oneof" {
+				next.token = a.token
+				continue
+			}
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func (p *syntaxParser) item() *docArg {
+	start := p.i
+	for p.i < len(p.s) && strings.IndexByte(" |[]()", p.s[p.i]) < 0 && !strings.HasPrefix(p.s[p.i:], "...") {
+		p.i++
+	}
+	word := p.s[start:p.i]
+	var a *docArg
+	if p.i < len(p.s) && (p.s[p.i] == '[' || p.s[p.i] == '(') {
+		open := p.s[p.i]
+		p.i++
+		var alts [][]*docArg
+		for {
+			alts = append(alts, p.sequence())
+			if p.i >= len(p.s) || p.s[p.i] != '|' {
+				break
+			}
+			p.i++
+		}
+		// closing bracket
+		p.i++
+		a = syntaxGroup(word, alts)
+		a.optional = open == '['
+	} else {
+		a = syntaxWord(word)
+	}
+	if strings.HasPrefix(p.s[p.i:], "...") {
+		a.multiple = true
+		p.i += 3
+	}
+	return a
+}
+
+func syntaxWord(word string) *docArg {
+	if i := strings.IndexByte(word, ':'); i >= 0 {
+		return &docArg{name: word[:i], typ: word[i+1:]}
+	}
+	if word == strings.ToUpper(word) && word != strings.ToLower(word) {
+		return &docArg{name: strings.ToLower(word), typ: "pure-token", token: word}
+	}
+	if word == "key" {
+		return &docArg{name: word, typ: "key"}
+	}
+	return &docArg{name: word, typ: "string"}
+}
+
+func syntaxGroup(name string, alts [][]*docArg) *docArg {
+	if len(alts) == 1 {
+		if len(alts[0]) == 1 && name == "" {
+			return alts[0][0]
+		}
+		return &docArg{name: groupName(name, alts[0]), typ: "block", args: alts[0]}
+	}
+	choices := make([]*docArg, len(alts))
+	for i, alt := range alts {
+		if len(alt) == 1 {
+			choices[i] = alt[0]
+		} else {
+			choices[i] = &docArg{name: groupName("", alt), typ: "block", args: alt}
+		}
+	}
+	return &docArg{name: groupName(name, choices), typ: "oneof", args: choices}
+}
+
+func groupName(name string, args []*docArg) string {
+	if name != "" {
+		return name
+	}
+	names := make([]string, len(args))
+	for i, a := range args {
+		names[i] = a.name
+	}
+	return strings.Join(names, "-")
+}
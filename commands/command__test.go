@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__COMMAND(t *testing.T) {
+	kv := store.New()
+
+	got, _ := ExecuteCommand(kv, bA([]string{"COMMAND", "COUNT"}))
+	if got != len(commandTable) {
+		t.Errorf("COMMAND COUNT: got %v want %v", got, len(commandTable))
+	}
+
+	all, _ := ExecuteCommand(kv, bA([]string{"COMMAND"}))
+	if n := len(all.([]interface{})); n != len(commandTable) {
+		t.Errorf("COMMAND: got %d entries want %d", n, len(commandTable))
+	}
+
+	info, _ := ExecuteCommand(kv, bA([]string{"COMMAND", "INFO", "get", "nosuchcmd"}))
+	entries := info.([]interface{})
+	if len(entries) != 2 || entries[1] != nil {
+		t.Fatalf("COMMAND INFO: unexpected reply %q", info)
+	}
+	get := entries[0].([]interface{})
+	if len(get) != 10 {
+		t.Fatalf("COMMAND INFO get: got %d fields want 10", len(get))
+	}
+	head := []interface{}{[]byte("get"), 2}
+	if !reflect.DeepEqual(get[:2], head) || get[3] != 1 || get[4] != 1 || get[5] != 1 {
+		t.Errorf("COMMAND INFO get: got %q", get)
+	}
+}
+
+func Test__COMMAND_GETKEYS(t *testing.T) {
+	kv := store.New()
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      error
+	}{
+		{[]string{"COMMAND", "GETKEYS", "GET", "foo"}, []interface{}{b("foo")}, nil},
+		{[]string{"COMMAND", "GETKEYS", "MSET", "a", "1", "b", "2"}, []interface{}{b("a"), b("b")}, nil},
+		{[]string{"COMMAND", "GETKEYS", "DEL", "a", "b", "c"}, []interface{}{b("a"), b("b"), b("c")}, nil},
+		{[]string{"COMMAND", "GETKEYS", "COPY", "a", "b", "REPLACE"}, []interface{}{b("a"), b("b")}, nil},
+		{[]string{"COMMAND", "GETKEYS", "PING", "x"}, nil, ErrNoKeyArguments},
+		{[]string{"COMMAND", "GETKEYS", "GET"}, nil, ErrInvalidNumOfArgsForCmd},
+		{[]string{"COMMAND", "GETKEYS"}, nil, ErrWrongNumOfArgs},
+		{[]string{"COMMAND", "GETKEYS", "GET", "a", "b"}, nil, ErrInvalidNumOfArgsForCmd},
+		{[]string{"COMMAND", "GETKEYS", "NOPE", "a"}, nil, ErrInvalidCommandSpecified},
+	}
+	for _, tt := range tests {
+		got, err := ExecuteCommand(kv, bA(tt.input))
+		if err != tt.err || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ExecuteCommand(%q): got %q, %v want %q, %v", tt.input, got, err, tt.expected, tt.err)
+		}
+	}
+}
+
+func Test__parseSyntax(t *testing.T) {
+	args := parseSyntax("source:key destination:key [DB destination-db:integer] [NX|XX] data(key value)...")
+	if len(args) != 5 {
+		t.Fatalf("got %d args want 5", len(args))
+	}
+	db := args[2]
+	if db.name != "destination-db" || db.token != "DB" || db.typ != "integer" || !db.optional {
+		t.Errorf("token argument: got %+v", db)
+	}
+	cond := args[3]
+	if cond.typ != "oneof" || !cond.optional || len(cond.args) != 2 || cond.args[1].token != "XX" {
+		t.Errorf("oneof argument: got %+v", cond)
+	}
+	data := args[4]
+	if data.name != "data" || data.typ != "block" || !data.multiple || len(data.args) != 2 {
+		t.Errorf("block argument: got %+v", data)
+	}
+}
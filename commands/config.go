@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/glob"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+// configCommand implements CONFIG and its subcommands.
+func (cl *Client) configCommand(s [][]byte) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	container, _ := lookupCommand("config")
+	sub, ok := container.lookupSubcommand(string(s[1]))
+	if !ok {
+		return nil, fmt.Errorf("ERR unknown subcommand '%s'. Try CONFIG HELP.", s[1])
+	}
+	if !sub.checkArity(len(s)) {
+		return nil, ErrWrongNumOfArgs
+	}
+	reg := cl.config()
+	switch sub.name {
+	case "config|get":
+		r := resp.Map{}
+		for _, name := range reg.Names() {
+			for _, pattern := range s[2:] {
+				if glob.MatchString(string(pattern), name, true) {
+					r = append(r, resp.Pair{Key: []byte(name), Value: []byte(reg.String(name))})
+					break
+				}
+			}
+		}
+		return r, nil
+	case "config|set":
+		if len(s)%2 != 0 {
+			return nil, ErrWrongNumOfArgs
+		}
+		// validate every name first so that nothing is applied when one of
+		// them is wrong
+		seen := make(map[string]bool)
+		for i := 2; i < len(s); i += 2 {
+			name := strings.ToLower(string(s[i]))
+			mutable, ok := reg.Mutable(name)
+			if !ok {
+				return nil, fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", s[i])
+			}
+			if seen[name] {
+				return nil, configSetError(name, "duplicate parameter")
+			}
+			seen[name] = true
+			if !mutable {
+				return nil, configSetError(name, "can't set immutable config")
+			}
+		}
+		var applied [][2]string
+		for i := 2; i < len(s); i += 2 {
+			name := strings.ToLower(string(s[i]))
+			old := reg.String(name)
+			if err := reg.Set(name, string(s[i+1])); err != nil {
+				// roll back the parameters set so far
+				for j := len(applied) - 1; j >= 0; j-- {
+					reg.Set(applied[j][0], applied[j][1])
+				}
+				return nil, configSetError(name, err.Error())
+			}
+			applied = append(applied, [2]string{name, old})
+		}
+		return "OK", nil
+	case "config|resetstat":
+		if cl.Stats != nil {
+			cl.Stats.Reset()
+		}
+		cl.Store.ResetStats()
+		return "OK", nil
+	case "config|rewrite":
+		if err := reg.Rewrite(); err != nil {
+			return nil, fmt.Errorf("ERR Rewriting config file: %v", err)
+		}
+		return "OK", nil
+	}
+	return nil, ErrInvalidCommand
+}
+
+func configSetError(name, reason string) error {
+	return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %s", name, reason)
+}
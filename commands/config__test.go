@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/config"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__CONFIG(t *testing.T) {
+	cl := &Client{Store: store.New(), Config: config.New(), Stats: new(Stats)}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		{[]string{"CONFIG", "GET", "port"}, resp.Map{{Key: b("port"), Value: b("8001")}}, ""},
+		{[]string{"CONFIG", "GET", "DB*"}, resp.Map{{Key: b("dbfilename"), Value: b("dump.trdb")}}, ""},
+		{[]string{"CONFIG", "GET", "nosuch"}, resp.Map{}, ""},
+		{[]string{"CONFIG", "SET", "dbfilename", "x.trdb"}, "OK", ""},
+		{[]string{"CONFIG", "GET", "dbfilename"}, resp.Map{{Key: b("dbfilename"), Value: b("x.trdb")}}, ""},
+		{[]string{"CONFIG", "SET", "port", "1"}, nil, "ERR CONFIG SET failed (possibly related to argument 'port') - can't set immutable config"},
+		{[]string{"CONFIG", "SET", "nosuch", "1"}, nil, "ERR Unknown option or number of arguments for CONFIG SET - 'nosuch'"},
+		// the first parameter is rolled back when the second one fails
+		{[]string{"CONFIG", "SET", "dbfilename", "y.trdb", "dir", "/nonexistent/dir"}, nil, "ERR CONFIG SET failed (possibly related to argument 'dir') - stat /nonexistent/dir: no such file or directory"},
+		{[]string{"CONFIG", "GET", "dbfilename"}, resp.Map{{Key: b("dbfilename"), Value: b("x.trdb")}}, ""},
+		{[]string{"CONFIG", "SET", "dbfilename"}, nil, ErrWrongNumOfArgs.Error()},
+		{[]string{"CONFIG", "REWRITE"}, nil, "ERR Rewriting config file: the server is running without a config file"},
+		{[]string{"CONFIG", "RESETSTAT"}, "OK", ""},
+		{[]string{"CONFIG", "NOPE"}, nil, "ERR unknown subcommand 'NOPE'. Try CONFIG HELP."},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %q, %q want %q, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+	if cl.Stats.Commands != 2 || cl.Stats.Errors != 1 {
+		t.Errorf("stats after RESETSTAT: got %+v", *cl.Stats)
+	}
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+)
+
+// ServerVersion is the Redis version reported to clients. tiny-redis
+// implements a subset of Redis 7 and clients use the version to pick
+// features such as RESP3.
+const ServerVersion = "7.0.0"
+
+// defaultProtoVersion is used until a client negotiates RESP3 with HELLO.
+const defaultProtoVersion = 2
+
+var (
+	ErrNoProto        = errors.New("NOPROTO unsupported protocol version")
+	ErrProtoNotInt    = errors.New("ERR Protocol version is not an integer or out of range")
+	ErrWrongPass      = errors.New("WRONGPASS invalid username-password pair or user is disabled.")
+	ErrInvalidCliName = errors.New("ERR Client names cannot contain spaces, newlines or special characters.")
+)
+
+// hello implements HELLO [protover [AUTH username password] [SETNAME clientname]].
+func (cl *Client) hello(s [][]byte) (interface{}, error) {
+	proto := cl.Protocol()
+	var name *string
+	if len(s) > 1 {
+		v, err := strconv.Atoi(string(s[1]))
+		if err != nil {
+			return nil, ErrProtoNotInt
+		}
+		if v != 2 && v != 3 {
+			return nil, ErrNoProto
+		}
+		proto = v
+		for i := 2; i < len(s); i++ {
+			more := len(s) - i - 1
+			switch opt := strings.ToUpper(string(s[i])); {
+			case opt == "AUTH" && more >= 2:
+				if err := cl.authenticate(s[i+1], s[i+2]); err != nil {
+					return nil, err
+				}
+				i += 2
+			case opt == "SETNAME" && more >= 1:
+				if !validClientName(s[i+1]) {
+					return nil, ErrInvalidCliName
+				}
+				n := string(s[i+1])
+				name = &n
+				i++
+			default:
+				return nil, fmt.Errorf("ERR Syntax error in HELLO option '%s'", s[i])
+			}
+		}
+	}
+	if !cl.authenticated() {
+		return nil, ErrHelloNoAuth
+	}
+	cl.Proto = proto
+	if name != nil {
+		cl.Name = *name
+	}
+	return resp.Map{
+		{Key: []byte("server"), Value: []byte("redis")},
+		{Key: []byte("version"), Value: []byte(ServerVersion)},
+		{Key: []byte("proto"), Value: proto},
+		{Key: []byte("id"), Value: int(cl.ID)},
+		{Key: []byte("mode"), Value: []byte("standalone")},
+		{Key: []byte("role"), Value: []byte("master")},
+		{Key: []byte("modules"), Value: []interface{}{}},
+	}, nil
+}
+
+// validClientName reports whether name only contains printable characters
+// other than space, like Redis requires.
+func validClientName(name []byte) bool {
+	for _, c := range name {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
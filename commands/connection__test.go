@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__HELLO(t *testing.T) {
+	cl := &Client{Store: store.New(), ID: 7}
+	if cl.Protocol() != 2 {
+		t.Errorf("default protocol: got %d want 2", cl.Protocol())
+	}
+
+	got, err := cl.Execute(bA([]string{"HELLO", "3", "AUTH", "default", "secret", "SETNAME", "worker-1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := got.(resp.Map)
+	fields := map[string]interface{}{}
+	for _, p := range m {
+		fields[string(p.Key.([]byte))] = p.Value
+	}
+	if fields["proto"] != 3 || fields["id"] != 7 || !reflect.DeepEqual(fields["server"], b("redis")) {
+		t.Errorf("HELLO 3: got %q", got)
+	}
+	if cl.Protocol() != 3 || cl.Name != "worker-1" {
+		t.Errorf("HELLO 3: client has proto %d name %q", cl.Protocol(), cl.Name)
+	}
+
+	tests := []struct {
+		input []string
+		err   string
+	}{
+		{[]string{"HELLO", "4"}, ErrNoProto.Error()},
+		{[]string{"HELLO", "x"}, ErrProtoNotInt.Error()},
+		{[]string{"HELLO", "2", "AUTH", "bob", "pw"}, ErrWrongPass.Error()},
+		{[]string{"HELLO", "2", "SETNAME", "has space"}, ErrInvalidCliName.Error()},
+		{[]string{"HELLO", "2", "AUTH", "default"}, "ERR Syntax error in HELLO option 'AUTH'"},
+	}
+	for _, tt := range tests {
+		_, err := cl.Execute(bA(tt.input))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Execute(%q): got %v want %q", tt.input, err, tt.err)
+		}
+	}
+	// failed negotiations leave the connection untouched
+	if cl.Protocol() != 3 || cl.Name != "worker-1" {
+		t.Errorf("after errors: client has proto %d name %q", cl.Protocol(), cl.Name)
+	}
+
+	cl.Execute(bA([]string{"HELLO", "2"}))
+	if cl.Protocol() != 2 {
+		t.Errorf("HELLO 2: got proto %d", cl.Protocol())
+	}
+}
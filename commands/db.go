@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrDBIndexOutOfRange = errors.New("ERR DB index is out of range")
+	ErrSameObject        = errors.New("ERR source and destination objects are the same")
+	ErrInvalidFirstDB    = errors.New("ERR invalid first DB index")
+	ErrInvalidSecondDB   = errors.New("ERR invalid second DB index")
+)
+
+// dbIndex parses a database index, checking that it exists.
+func (cl *Client) dbIndex(arg []byte, invalid error) (int, error) {
+	i, err := strconv.Atoi(string(arg))
+	if err != nil {
+		return 0, invalid
+	}
+	if i < 0 || i >= cl.Store.Databases() {
+		return 0, ErrDBIndexOutOfRange
+	}
+	return i, nil
+}
+
+// selectDB implements SELECT index.
+func (cl *Client) selectDB(s [][]byte) (interface{}, error) {
+	if len(s) != 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	i, err := cl.dbIndex(s[1], ErrValNotIntOrOutOfRange)
+	if err != nil {
+		return nil, err
+	}
+	cl.DB = i
+	return "OK", nil
+}
+
+// move implements MOVE key db. The key is only moved when it doesn't exist
+// in the destination.
+func (cl *Client) move(s [][]byte) (interface{}, error) {
+	if len(s) != 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	i, err := cl.dbIndex(s[2], ErrValNotIntOrOutOfRange)
+	if err != nil {
+		return nil, err
+	}
+	if i == cl.DB {
+		return nil, ErrSameObject
+	}
+	src, dst := cl.db(), cl.Store.DB(i)
+	v, ok := src.Value(s[1])
+	if !ok {
+		return 0, nil
+	}
+	if _, ok := dst.Get(s[1]); ok {
+		return 0, nil
+	}
+	dst.SetValue(s[1], v)
+	src.Del(s[1])
+	return 1, nil
+}
+
+// swapDB implements SWAPDB index1 index2. Clients keep their selected
+// index, so they see the other database from then on.
+func (cl *Client) swapDB(s [][]byte) (interface{}, error) {
+	if len(s) != 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	i, err := cl.dbIndex(s[1], ErrInvalidFirstDB)
+	if err != nil {
+		return nil, err
+	}
+	j, err := cl.dbIndex(s[2], ErrInvalidSecondDB)
+	if err != nil {
+		return nil, err
+	}
+	cl.Store.Swap(i, j)
+	return "OK", nil
+}
+
+// flushMode checks the optional ASYNC or SYNC argument of FLUSHDB and
+// FLUSHALL. Both flush synchronously.
+func flushMode(s [][]byte) error {
+	switch {
+	case len(s) == 1:
+		return nil
+	case len(s) > 2:
+		return ErrInvalidSyntax
+	}
+	switch strings.ToUpper(string(s[1])) {
+	case "ASYNC", "SYNC":
+		return nil
+	}
+	return ErrInvalidSyntax
+}
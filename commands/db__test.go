@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__DB(t *testing.T) {
+	kv := store.New()
+	cl := &Client{Store: kv}
+	other := &Client{Store: kv}
+	tests := []struct {
+		cl       *Client
+		input    []string
+		expected interface{}
+		err      error
+	}{
+		{cl, []string{"SET", "k", "0"}, "OK", nil},
+		{cl, []string{"SELECT", "1"}, "OK", nil},
+		{cl, []string{"GET", "k"}, nil, nil},
+		{cl, []string{"SET", "k", "1"}, "OK", nil},
+		{cl, []string{"SET", "only1", "1"}, "OK", nil},
+		{cl, []string{"DBSIZE"}, 2, nil},
+		{other, []string{"DBSIZE"}, 1, nil},
+		{cl, []string{"SELECT", "16"}, nil, ErrDBIndexOutOfRange},
+		{cl, []string{"SELECT", "x"}, nil, ErrValNotIntOrOutOfRange},
+		{cl, []string{"MOVE", "k", "0"}, 0, nil},
+		{cl, []string{"MOVE", "only1", "0"}, 1, nil},
+		{cl, []string{"MOVE", "only1", "1"}, nil, ErrSameObject},
+		{cl, []string{"MOVE", "nosuch", "0"}, 0, nil},
+		{other, []string{"GET", "only1"}, b("1"), nil},
+		{cl, []string{"SWAPDB", "0", "1"}, "OK", nil},
+		{other, []string{"GET", "k"}, b("1"), nil},
+		{cl, []string{"GET", "only1"}, b("1"), nil},
+		{cl, []string{"SWAPDB", "x", "1"}, nil, ErrInvalidFirstDB},
+		{cl, []string{"SWAPDB", "0", "x"}, nil, ErrInvalidSecondDB},
+		{cl, []string{"SWAPDB", "0", "-1"}, nil, ErrDBIndexOutOfRange},
+		{cl, []string{"FLUSHDB", "LAZY"}, nil, ErrInvalidSyntax},
+		{cl, []string{"FLUSHDB", "ASYNC"}, "OK", nil},
+		{cl, []string{"DBSIZE"}, 0, nil},
+		{other, []string{"DBSIZE"}, 1, nil},
+		{cl, []string{"FLUSHALL"}, "OK", nil},
+		{other, []string{"DBSIZE"}, 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := tt.cl.Execute(bA(tt.input))
+		if err != tt.err || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Execute(%q): got %q, %v want %q, %v", tt.input, got, err, tt.expected, tt.err)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/geohash"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+var (
+	ErrGeoUnit           = errors.New("ERR unsupported unit provided. please use M, KM, FT, MI")
+	ErrGeoMember         = errors.New("ERR could not decode requested zset member")
+	ErrGeoCount          = errors.New("ERR COUNT must be > 0")
+	ErrGeoAnyNeedsCount  = errors.New("ERR the ANY argument requires COUNT argument")
+	ErrGeoRadius         = errors.New("ERR need numeric radius")
+	ErrGeoNegativeRadius = errors.New("ERR radius cannot be negative")
+	ErrGeoWidth          = errors.New("ERR need numeric width")
+	ErrGeoHeight         = errors.New("ERR need numeric height")
+	ErrGeoNegativeBox    = errors.New("ERR height or width cannot be negative")
+	ErrGeoStoreWith      = errors.New("ERR GEOSEARCHSTORE is not compatible with WITHDIST, WITHHASH and WITHCOORD options")
+)
+
+// geoUnits are the meters in each unit.
+var geoUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"ft": 0.3048,
+	"mi": 1609.34,
+}
+
+func geoUnit(b []byte) (float64, error) {
+	if u, ok := geoUnits[strings.ToLower(string(b))]; ok {
+		return u, nil
+	}
+	return 0, ErrGeoUnit
+}
+
+// lonLat parses a longitude and a latitude that can be encoded.
+func lonLat(lon, lat []byte) (float64, float64, error) {
+	x, err := parseFloat(lon)
+	if err != nil {
+		return 0, 0, err
+	}
+	y, err := parseFloat(lat)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !geohash.Valid(x, y) {
+		return 0, 0, fmt.Errorf("ERR invalid longitude,latitude pair %f,%f", x, y)
+	}
+	return x, y, nil
+}
+
+// formatDistance formats a distance with 4 decimals like Redis.
+func formatDistance(d float64) []byte {
+	return []byte(strconv.FormatFloat(d, 'f', 4, 64))
+}
+
+// formatCoord formats a coordinate the way Redis formats a long double for
+// humans: 17 decimals without the trailing zeros.
+func formatCoord(f float64) []byte {
+	s := strconv.FormatFloat(f, 'f', 17, 64)
+	s = strings.TrimRight(s, "0")
+	return []byte(strings.TrimSuffix(s, "."))
+}
+
+// geoadd implements GEOADD key [NX|XX] [CH] longitude latitude member
+// [longitude latitude member ...]. Members are added to a sorted set with
+// their 52 bit geohash as score.
+func geoadd(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 5 {
+		return nil, ErrWrongNumOfArgs
+	}
+	var f zaddFlags
+	i := 2
+options:
+	for ; i < len(s); i++ {
+		switch strings.ToUpper(string(s[i])) {
+		case "NX":
+			f.nx = true
+		case "XX":
+			f.xx = true
+		case "CH":
+			f.ch = true
+		default:
+			break options
+		}
+	}
+	triples := s[i:]
+	if len(triples) == 0 || len(triples)%3 != 0 || (f.nx && f.xx) {
+		return nil, ErrInvalidSyntax
+	}
+	members := make([]store.ZMember, len(triples)/3)
+	for j := range members {
+		lon, lat, err := lonLat(triples[3*j], triples[3*j+1])
+		if err != nil {
+			return nil, err
+		}
+		hash, _ := geohash.Encode(lon, lat)
+		members[j] = store.ZMember{Member: string(triples[3*j+2]), Score: float64(hash)}
+	}
+	n, _, _, err := zaddMembers(kv, s[1], f, members)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// geopos implements GEOPOS key [member ...].
+func geopos(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	z := zset(kv, s[1])
+	r := make([]interface{}, len(s)-2)
+	for i, member := range s[2:] {
+		if z == nil {
+			continue
+		}
+		if score, ok := z.Score(string(member)); ok {
+			lon, lat := geohash.Decode(uint64(score))
+			r[i] = []interface{}{formatCoord(lon), formatCoord(lat)}
+		}
+	}
+	return r, nil
+}
+
+// geodist implements GEODIST key member1 member2 [M|KM|FT|MI].
+func geodist(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 4 {
+		return nil, ErrWrongNumOfArgs
+	}
+	if len(s) > 5 {
+		return nil, ErrInvalidSyntax
+	}
+	unit := 1.0
+	if len(s) == 5 {
+		var err error
+		if unit, err = geoUnit(s[4]); err != nil {
+			return nil, err
+		}
+	}
+	z := zset(kv, s[1])
+	if z == nil {
+		return nil, nil
+	}
+	score1, ok1 := z.Score(string(s[2]))
+	score2, ok2 := z.Score(string(s[3]))
+	if !ok1 || !ok2 {
+		return nil, nil
+	}
+	lon1, lat1 := geohash.Decode(uint64(score1))
+	lon2, lat2 := geohash.Decode(uint64(score2))
+	return formatDistance(geohash.Distance(lon1, lat1, lon2, lat2) / unit), nil
+}
+
+// geohashes implements GEOHASH key [member ...].
+func geohashes(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	z := zset(kv, s[1])
+	r := make([]interface{}, len(s)-2)
+	for i, member := range s[2:] {
+		if z == nil {
+			continue
+		}
+		if score, ok := z.Score(string(member)); ok {
+			r[i] = []byte(geohash.String(uint64(score)))
+		}
+	}
+	return r, nil
+}
+
+// geoPoint is a member found by a search, dist is in meters.
+type geoPoint struct {
+	store.ZMember
+	dist     float64
+	lon, lat float64
+}
+
+// geosearch implements GEOSEARCH key FROMMEMBER member|FROMLONLAT longitude
+// latitude BYRADIUS radius unit|BYBOX width height unit [ASC|DESC] [COUNT
+// count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH] and, when storing,
+// GEOSEARCHSTORE destination source ... [STOREDIST].
+//
+// Like Redis, the members are looked up in the areas of the geohashes
+// around the center and, unless sorted, replied in that order.
+func geosearch(kv *store.DB, s [][]byte, storing bool) (interface{}, error) {
+	base := 2
+	if storing {
+		base = 3
+	}
+	if len(s) < base+5 {
+		return nil, ErrWrongNumOfArgs
+	}
+	z := zset(kv, s[base-1])
+	var shape geohash.Shape
+	var fromMember, fromLonLat, byRadius, byBox bool
+	var withDist, withHash, withCoord, countAny, storeDist bool
+	order := 0
+	count := int64(0)
+	args := s[base:]
+	for i := 0; i < len(args); i++ {
+		left := len(args) - 1 - i
+		var err error
+		switch opt := strings.ToUpper(string(args[i])); {
+		case opt == "WITHDIST":
+			withDist = true
+		case opt == "WITHHASH":
+			withHash = true
+		case opt == "WITHCOORD":
+			withCoord = true
+		case opt == "ANY":
+			countAny = true
+		case opt == "ASC":
+			order = 1
+		case opt == "DESC":
+			order = -1
+		case opt == "COUNT" && left >= 1:
+			if count, err = strconv.ParseInt(string(args[i+1]), 10, 64); err != nil {
+				return nil, ErrValNotIntOrOutOfRange
+			}
+			if count <= 0 {
+				return nil, ErrGeoCount
+			}
+			i++
+		case opt == "FROMMEMBER" && left >= 1 && !fromLonLat:
+			// a missing key is replied to once the options are checked
+			if z != nil {
+				score, ok := z.Score(string(args[i+1]))
+				if !ok {
+					return nil, ErrGeoMember
+				}
+				shape.Lon, shape.Lat = geohash.Decode(uint64(score))
+			}
+			fromMember = true
+			i++
+		case opt == "FROMLONLAT" && left >= 2 && !fromMember:
+			if shape.Lon, shape.Lat, err = lonLat(args[i+1], args[i+2]); err != nil {
+				return nil, err
+			}
+			fromLonLat = true
+			i += 2
+		case opt == "BYRADIUS" && left >= 2 && !byBox:
+			if shape.Radius, err = parseFloat(args[i+1]); err != nil {
+				return nil, ErrGeoRadius
+			}
+			if shape.Radius < 0 {
+				return nil, ErrGeoNegativeRadius
+			}
+			if shape.Conversion, err = geoUnit(args[i+2]); err != nil {
+				return nil, err
+			}
+			byRadius = true
+			i += 2
+		case opt == "BYBOX" && left >= 3 && !byRadius:
+			if shape.Width, err = parseFloat(args[i+1]); err != nil {
+				return nil, ErrGeoWidth
+			}
+			if shape.Height, err = parseFloat(args[i+2]); err != nil {
+				return nil, ErrGeoHeight
+			}
+			if shape.Width < 0 || shape.Height < 0 {
+				return nil, ErrGeoNegativeBox
+			}
+			if shape.Conversion, err = geoUnit(args[i+3]); err != nil {
+				return nil, err
+			}
+			shape.Box, byBox = true, true
+			i += 3
+		case opt == "STOREDIST" && storing:
+			storeDist = true
+		default:
+			return nil, ErrInvalidSyntax
+		}
+	}
+	switch {
+	case storing && (withDist || withHash || withCoord):
+		return nil, ErrGeoStoreWith
+	case !fromMember && !fromLonLat:
+		return nil, fmt.Errorf("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for %s", s[0])
+	case !byRadius && !byBox:
+		return nil, fmt.Errorf("ERR exactly one of BYRADIUS and BYBOX can be specified for %s", s[0])
+	case countAny && count == 0:
+		return nil, ErrGeoAnyNeedsCount
+	}
+	if z == nil {
+		if storing {
+			kv.Del(s[1])
+			return 0, nil
+		}
+		return []interface{}{}, nil
+	}
+	// the closest members are returned when there is a COUNT
+	if count != 0 && order == 0 && !countAny {
+		order = 1
+	}
+
+	limit := 0
+	if countAny {
+		limit = int(count)
+	}
+	var found []geoPoint
+	for _, area := range shape.Areas() {
+		if limit > 0 && len(found) >= limit {
+			break
+		}
+		min, max := area.Scores()
+		z.Between(float64(min), float64(max), func(m store.ZMember) bool {
+			lon, lat := geohash.Decode(uint64(m.Score))
+			if dist, ok := shape.Contains(lon, lat); ok {
+				found = append(found, geoPoint{m, dist, lon, lat})
+			}
+			return limit == 0 || len(found) < limit
+		})
+	}
+	if order != 0 {
+		sort.SliceStable(found, func(i, j int) bool {
+			if order > 0 {
+				return found[i].dist < found[j].dist
+			}
+			return found[i].dist > found[j].dist
+		})
+	}
+	if count != 0 && int64(len(found)) > count {
+		found = found[:count]
+	}
+
+	if storing {
+		if len(found) == 0 {
+			kv.Del(s[1])
+			return 0, nil
+		}
+		dst := store.NewZSet()
+		for _, p := range found {
+			if storeDist {
+				dst.Add(p.Member, p.dist/shape.Conversion)
+			} else {
+				dst.Add(p.Member, p.Score)
+			}
+		}
+		kv.SetValue(s[1], dst)
+		return len(found), nil
+	}
+	r := make([]interface{}, len(found))
+	for i, p := range found {
+		if !withDist && !withHash && !withCoord {
+			r[i] = []byte(p.Member)
+			continue
+		}
+		item := []interface{}{[]byte(p.Member)}
+		if withDist {
+			item = append(item, formatDistance(p.dist/shape.Conversion))
+		}
+		if withHash {
+			item = append(item, int64(p.Score))
+		}
+		if withCoord {
+			item = append(item, []interface{}{formatCoord(p.lon), formatCoord(p.lat)})
+		}
+		r[i] = item
+	}
+	return r, nil
+}
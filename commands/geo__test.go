@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__Geo(t *testing.T) {
+	cl := &Client{Store: store.New()}
+	pos := func(lon, lat string) []interface{} {
+		return []interface{}{b(lon), b(lat)}
+	}
+	palermo, catania := pos("13.36138933897018433", "38.11555639549629859"), pos("15.08726745843887329", "37.50266842333162032")
+	edge1, edge2 := pos("12.7584877610206604", "38.78813451624225195"), pos("17.24151045083999634", "38.78813451624225195")
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		// the examples of the Redis docs
+		{[]string{"GEOADD", "Sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania"}, 2, ""},
+		{[]string{"GEODIST", "Sicily", "Palermo", "Catania"}, b("166274.1516"), ""},
+		{[]string{"GEODIST", "Sicily", "Palermo", "Catania", "km"}, b("166.2742"), ""},
+		{[]string{"GEODIST", "Sicily", "Palermo", "Catania", "MI"}, b("103.3182"), ""},
+		{[]string{"GEODIST", "Sicily", "Foo", "Bar"}, nil, ""},
+		{[]string{"GEODIST", "Sicily", "Palermo", "Catania", "yd"}, nil, ErrGeoUnit.Error()},
+		{[]string{"GEOHASH", "Sicily", "Palermo", "Catania", "NonExisting"}, []interface{}{b("sqc8b49rny0"), b("sqdtr74hyu0"), nil}, ""},
+		{[]string{"GEOPOS", "Sicily", "Palermo", "Catania", "NonExisting"}, []interface{}{palermo, catania, nil}, ""},
+		{[]string{"GEOPOS", "nosuch", "Palermo"}, []interface{}{nil}, ""},
+		{[]string{"GEOADD", "Sicily", "12.758489", "38.788135", "edge1", "17.241510", "38.788135", "edge2"}, 2, ""},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "ASC"}, []interface{}{b("Catania"), b("Palermo")}, ""},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "WITHCOORD", "WITHDIST"}, []interface{}{
+			[]interface{}{b("Catania"), b("56.4413"), catania},
+			[]interface{}{b("Palermo"), b("190.4424"), palermo},
+			[]interface{}{b("edge2"), b("279.7403"), edge2},
+			[]interface{}{b("edge1"), b("279.7405"), edge1},
+		}, ""},
+		// unsorted, the members are in the order of the geohash areas
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "WITHDIST", "WITHHASH"}, []interface{}{
+			[]interface{}{b("Palermo"), b("190.4424"), int64(3479099956230698)},
+			[]interface{}{b("Catania"), b("56.4413"), int64(3479447370796909)},
+		}, ""},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "DESC", "COUNT", "2"}, []interface{}{b("edge1"), b("edge2")}, ""},
+		// COUNT alone returns the closest members
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "COUNT", "1"}, []interface{}{b("Catania")}, ""},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "COUNT", "1", "ANY"}, []interface{}{b("Palermo")}, ""},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km"}, []interface{}{}, ""},
+		{[]string{"GEOSEARCH", "nosuch", "FROMMEMBER", "x", "BYRADIUS", "1", "km"}, []interface{}{}, ""},
+
+		{[]string{"GEOSEARCHSTORE", "key1", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "COUNT", "3"}, 3, ""},
+		{[]string{"ZRANGE", "key1", "0", "-1", "WITHSCORES"}, []interface{}{
+			b("Palermo"), float64(3479099956230698), b("Catania"), float64(3479447370796909), b("edge2"), float64(3481342659049484),
+		}, ""},
+		{[]string{"GEOSEARCH", "key1", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "WITHDIST"}, []interface{}{
+			[]interface{}{b("Catania"), b("56.4413")},
+			[]interface{}{b("Palermo"), b("190.4424")},
+			[]interface{}{b("edge2"), b("279.7403")},
+		}, ""},
+		{[]string{"GEOSEARCHSTORE", "key2", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "COUNT", "3", "STOREDIST"}, 3, ""},
+		{[]string{"ZRANGE", "key2", "0", "-1"}, []interface{}{b("Catania"), b("Palermo"), b("edge2")}, ""},
+		{[]string{"GEOSEARCHSTORE", "key2", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km"}, 0, ""},
+		{[]string{"EXISTS", "key2"}, 0, ""},
+		{[]string{"GEOADD", "Sicily", "13.583333", "37.316667", "Agrigento"}, 1, ""},
+		{[]string{"GEOSEARCH", "Sicily", "FROMMEMBER", "Agrigento", "BYRADIUS", "100", "km"}, []interface{}{b("Agrigento"), b("Palermo")}, ""},
+		{[]string{"GEOSEARCH", "Sicily", "FROMMEMBER", "Agrigento", "BYRADIUS", "1", "m"}, []interface{}{b("Agrigento")}, ""},
+
+		{[]string{"GEOADD", "Sicily", "NX", "CH", "0", "0", "Palermo", "1", "1", "new"}, 1, ""},
+		{[]string{"GEOADD", "Sicily", "XX", "CH", "0", "0", "Palermo", "1", "1", "other"}, 1, ""},
+		{[]string{"GEOPOS", "Sicily", "Palermo", "other"}, []interface{}{pos("0.00000268220901489", "0.00000126736058093"), nil}, ""},
+		{[]string{"GEOADD", "Sicily", "NX", "XX", "0", "0", "x"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"GEOADD", "Sicily", "0", "0", "x", "1"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"GEOADD", "Sicily", "200", "100", "x"}, nil, "ERR invalid longitude,latitude pair 200.000000,100.000000"},
+		{[]string{"GEOADD", "Sicily", "a", "0", "x"}, nil, ErrValNotFloat.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "BYRADIUS", "1", "km", "ASC", "DESC"}, nil, "ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH"},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "ASC", "DESC"}, nil, "ERR exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH"},
+		{[]string{"GEOSEARCH", "Sicily", "FROMMEMBER", "x", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km"}, nil, ErrGeoMember.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "FROMMEMBER", "x", "BYRADIUS", "1", "km"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km", "BYBOX", "1", "1", "km"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "-1", "km"}, nil, ErrGeoNegativeRadius.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "x", "km"}, nil, ErrGeoRadius.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYBOX", "1", "-1", "km"}, nil, ErrGeoNegativeBox.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km", "ANY"}, nil, ErrGeoAnyNeedsCount.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km", "COUNT", "0"}, nil, ErrGeoCount.Error()},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km", "STOREDIST"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"GEOSEARCHSTORE", "d", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km", "WITHDIST"}, nil, ErrGeoStoreWith.Error()},
+
+		{[]string{"SET", "str", "x"}, "OK", ""},
+		{[]string{"GEOADD", "str", "0", "0", "x"}, nil, ErrWrongType.Error()},
+		{[]string{"GEOSEARCH", "str", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km"}, nil, ErrWrongType.Error()},
+		// the destination is replaced whatever its type
+		{[]string{"GEOSEARCHSTORE", "str", "Sicily", "FROMMEMBER", "Agrigento", "BYRADIUS", "1", "km"}, 1, ""},
+		{[]string{"TYPE", "str"}, "zset", ""},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %v, %q want %v, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/tinfoil-knight/tiny-redis/hll"
+)
+
+var (
+	ErrNotHLL     = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrHLLCorrupt = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+func hllError(err error) error {
+	if err == hll.ErrCorrupt {
+		return ErrHLLCorrupt
+	}
+	return ErrNotHLL
+}
+
+func (cl *Client) hllSparseMax() int {
+	return int(cl.config().Int("hll-sparse-max-bytes"))
+}
+
+// pfadd implements PFADD key [element ...]. It replies 1 when the key was
+// created or a register changed.
+func (cl *Client) pfadd(s [][]byte) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	kv := cl.db()
+	sk, changed := hll.New(), true
+	if v, ok := kv.Get(s[1]); ok {
+		var err error
+		if sk, err = hll.Parse(v); err != nil {
+			return nil, hllError(err)
+		}
+		changed = false
+	}
+	for _, elem := range s[2:] {
+		if sk.Add(elem) {
+			changed = true
+		}
+	}
+	if !changed {
+		return 0, nil
+	}
+	kv.Set(s[1], sk.Bytes(cl.hllSparseMax()))
+	return 1, nil
+}
+
+// pfcount implements PFCOUNT key [key ...]. The cardinality of a single key
+// is cached in its value, the one of the union of several keys isn't.
+func (cl *Client) pfcount(s [][]byte) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	kv := cl.db()
+	if len(s) == 2 {
+		v, ok := kv.Get(s[1])
+		if !ok {
+			return int64(0), nil
+		}
+		n, ok, err := hll.Cached(v)
+		if err != nil {
+			return nil, hllError(err)
+		}
+		if ok {
+			return int64(n), nil
+		}
+		sk, err := hll.Parse(v)
+		if err != nil {
+			return nil, hllError(err)
+		}
+		n = sk.Count()
+		kv.Set(s[1], hll.SetCached(v, n))
+		return int64(n), nil
+	}
+	union := hll.New()
+	for _, key := range s[1:] {
+		v, ok := kv.Get(key)
+		if !ok {
+			continue
+		}
+		sk, err := hll.Parse(v)
+		if err != nil {
+			return nil, hllError(err)
+		}
+		union.Merge(sk)
+	}
+	return int64(union.Count()), nil
+}
+
+// pfmerge implements PFMERGE destkey [sourcekey ...]. destkey is part of the
+// union when it exists, and the result is dense when any input is.
+func (cl *Client) pfmerge(s [][]byte) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	kv := cl.db()
+	union := hll.New()
+	for _, key := range s[1:] {
+		v, ok := kv.Get(key)
+		if !ok {
+			continue
+		}
+		sk, err := hll.Parse(v)
+		if err != nil {
+			return nil, hllError(err)
+		}
+		union.Merge(sk)
+	}
+	kv.Set(s[1], union.Bytes(cl.hllSparseMax()))
+	return "OK", nil
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/config"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__HyperLogLog(t *testing.T) {
+	cl := &Client{Store: store.New(), Config: config.New()}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		{[]string{"PFADD", "hll", "a", "b", "c", "d", "e", "f", "g"}, 1, ""},
+		{[]string{"PFADD", "hll", "a"}, 0, ""},
+		{[]string{"PFCOUNT", "hll"}, int64(7), ""},
+		// the cardinality is cached until a register changes
+		{[]string{"GETRANGE", "hll", "8", "15"}, b("\x07\x00\x00\x00\x00\x00\x00\x00"), ""},
+		{[]string{"PFADD", "hll", "h"}, 1, ""},
+		{[]string{"GETRANGE", "hll", "15", "15"}, b("\x80"), ""},
+		{[]string{"PFCOUNT", "hll"}, int64(8), ""},
+		{[]string{"PFADD", "empty"}, 1, ""},
+		{[]string{"GET", "empty"}, b("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff"), ""},
+		{[]string{"PFCOUNT", "empty", "nosuch"}, int64(0), ""},
+		{[]string{"PFCOUNT", "nosuch"}, int64(0), ""},
+
+		// the example of the Redis docs
+		{[]string{"PFADD", "hll1", "foo", "bar", "zap", "a"}, 1, ""},
+		{[]string{"PFADD", "hll2", "a", "b", "c", "foo"}, 1, ""},
+		{[]string{"PFMERGE", "hll3", "hll1", "hll2"}, "OK", ""},
+		{[]string{"PFCOUNT", "hll3"}, int64(6), ""},
+		{[]string{"PFCOUNT", "hll1", "hll2", "nosuch"}, int64(6), ""},
+		// destkey is merged too
+		{[]string{"PFMERGE", "hll1", "hll"}, "OK", ""},
+		{[]string{"PFCOUNT", "hll1"}, int64(11), ""},
+		{[]string{"PFMERGE", "new"}, "OK", ""},
+		{[]string{"PFCOUNT", "new"}, int64(0), ""},
+
+		{[]string{"SET", "str", "bar"}, "OK", ""},
+		{[]string{"PFADD", "str", "1"}, nil, ErrNotHLL.Error()},
+		{[]string{"PFCOUNT", "str"}, nil, ErrNotHLL.Error()},
+		{[]string{"PFCOUNT", "hll", "str"}, nil, ErrNotHLL.Error()},
+		{[]string{"PFMERGE", "hll", "str"}, nil, ErrNotHLL.Error()},
+		// one more register than there are
+		{[]string{"SET", "bad", "HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80\x7f\xff\x00"}, "OK", ""},
+		{[]string{"PFCOUNT", "bad"}, nil, ErrHLLCorrupt.Error()},
+		{[]string{"PFADD", "bad", "a"}, nil, ErrHLLCorrupt.Error()},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %q, %q want %q, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+}
+
+func Test__HyperLogLogPromotion(t *testing.T) {
+	cl := &Client{Store: store.New(), Config: config.New()}
+	if err := cl.Config.Set("hll-sparse-max-bytes", "100"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		cl.Execute(bA([]string{"PFADD", "hll", strconv.Itoa(i)}))
+		cl.Execute(bA([]string{"PFADD", "sparse", strconv.Itoa(i % 10)}))
+	}
+	tests := []struct {
+		key      string
+		encoding string
+	}{
+		{"hll", "\x00"},
+		{"sparse", "\x01"},
+	}
+	for _, tt := range tests {
+		if got, _ := cl.Execute(bA([]string{"GETRANGE", tt.key, "4", "4"})); !reflect.DeepEqual(got, b(tt.encoding)) {
+			t.Errorf("%s: got encoding %q want %q", tt.key, got, tt.encoding)
+		}
+	}
+	if got, _ := cl.Execute(bA([]string{"STRLEN", "hll"})); got != 12304 {
+		t.Errorf("dense length: got %v", got)
+	}
+	if got, _ := cl.Execute(bA([]string{"PFCOUNT", "hll"})); got != int64(100) {
+		t.Errorf("PFCOUNT after the promotion: got %v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// infoSections are the sections of INFO in the order they are written.
+var infoSections = []string{"server", "clients", "memory", "stats", "keyspace"}
+
+// serverInfo implements INFO [section [section ...]]. With no section, or with
+// "default", "all" or "everything", every section is returned.
+func (cl *Client) serverInfo(s [][]byte) (interface{}, error) {
+	want := map[string]bool{}
+	for _, arg := range s[1:] {
+		switch name := strings.ToLower(string(arg)); name {
+		case "default", "all", "everything":
+			for _, section := range infoSections {
+				want[section] = true
+			}
+		default:
+			want[name] = true
+		}
+	}
+	if len(s) == 1 {
+		for _, section := range infoSections {
+			want[section] = true
+		}
+	}
+	var b strings.Builder
+	for _, section := range infoSections {
+		if !want[section] {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		fmt.Fprintf(&b, "# %s\r\n", strings.Title(section))
+		for _, field := range cl.infoSection(section) {
+			fmt.Fprintf(&b, "%s:%v\r\n", field[0], field[1])
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+func (cl *Client) infoSection(section string) [][2]interface{} {
+	var st Stats
+	if cl.Stats != nil {
+		st = Stats{
+			Commands:    atomic.LoadInt64(&cl.Stats.Commands),
+			Errors:      atomic.LoadInt64(&cl.Stats.Errors),
+			Connections: atomic.LoadInt64(&cl.Stats.Connections),
+			Clients:     atomic.LoadInt64(&cl.Stats.Clients),
+			Start:       cl.Stats.Start,
+		}
+	}
+	reg := cl.config()
+	switch section {
+	case "server":
+		var uptime int64
+		if !st.Start.IsZero() {
+			uptime = int64(time.Since(st.Start) / time.Second)
+		}
+		return [][2]interface{}{
+			{"redis_version", ServerVersion},
+			{"redis_mode", "standalone"},
+			{"process_id", os.Getpid()},
+			{"tcp_port", reg.String("port")},
+			{"uptime_in_seconds", uptime},
+			{"uptime_in_days", uptime / (24 * 3600)},
+		}
+	case "clients":
+		return [][2]interface{}{
+			{"connected_clients", st.Clients},
+		}
+	case "memory":
+		used, max := cl.Store.UsedMemory(), reg.Int("maxmemory")
+		return [][2]interface{}{
+			{"used_memory", used},
+			{"used_memory_human", bytesToHuman(used)},
+			{"maxmemory", max},
+			{"maxmemory_human", bytesToHuman(max)},
+			{"maxmemory_policy", reg.String("maxmemory-policy")},
+		}
+	case "stats":
+		return [][2]interface{}{
+			{"total_connections_received", st.Connections},
+			{"total_commands_processed", st.Commands},
+			{"total_error_replies", st.Errors},
+			{"evicted_keys", cl.Store.EvictedKeys()},
+		}
+	case "keyspace":
+		var r [][2]interface{}
+		for i := 0; i < cl.Store.Databases(); i++ {
+			if n := cl.Store.DB(i).Len(); n > 0 {
+				r = append(r, [2]interface{}{fmt.Sprintf("db%d", i), fmt.Sprintf("keys=%d,expires=0,avg_ttl=0", n)})
+			}
+		}
+		return r
+	}
+	return nil
+}
+
+// bytesToHuman formats n like the *_human fields of Redis, e.g. 1.50K.
+func bytesToHuman(n int64) string {
+	units := []string{"B", "K", "M", "G", "T", "P"}
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.2f%s", f, units[i])
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/config"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__INFO(t *testing.T) {
+	cl := &Client{Store: store.New(), Config: config.New(), Stats: &Stats{Clients: 1}}
+	cl.Execute(bA([]string{"SET", "a", "1"}))
+	cl.Execute(bA([]string{"SELECT", "3"}))
+	cl.Execute(bA([]string{"SET", "b", "1"}))
+	tests := []struct {
+		input    []string
+		sections []string
+		fields   []string
+	}{
+		{[]string{"INFO"}, []string{"Server", "Clients", "Memory", "Stats", "Keyspace"}, []string{"redis_version:" + ServerVersion, "connected_clients:1"}},
+		{[]string{"INFO", "all"}, []string{"Server", "Clients", "Memory", "Stats", "Keyspace"}, nil},
+		{[]string{"INFO", "KEYSPACE", "memory"}, []string{"Memory", "Keyspace"}, []string{
+			"db0:keys=1,expires=0,avg_ttl=0", "db3:keys=1,expires=0,avg_ttl=0", "maxmemory:0", "maxmemory_policy:noeviction",
+		}},
+		{[]string{"INFO", "stats"}, []string{"Stats"}, []string{"total_commands_processed:", "evicted_keys:0"}},
+		{[]string{"INFO", "nosuch"}, nil, nil},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		if err != nil {
+			t.Fatalf("Execute(%q): %v", tt.input, err)
+		}
+		var sections []string
+		for _, line := range strings.Split(string(got.([]byte)), "\r\n") {
+			if strings.HasPrefix(line, "# ") {
+				sections = append(sections, line[2:])
+			}
+		}
+		if !reflect.DeepEqual(sections, tt.sections) {
+			t.Errorf("Execute(%q): got sections %q want %q", tt.input, sections, tt.sections)
+		}
+		for _, field := range tt.fields {
+			if !strings.Contains(string(got.([]byte)), "\r\n"+field) {
+				t.Errorf("Execute(%q): no %q in %q", tt.input, field, got)
+			}
+		}
+	}
+}
+
+func Test__OOM(t *testing.T) {
+	cl := &Client{Store: store.New()}
+	cl.Execute(bA([]string{"SET", "a", "1"}))
+	// the buckets alone are over the limit
+	cl.Store.SetEviction(store.Eviction{MaxMemory: 1})
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		{[]string{"SET", "b", "1"}, nil, ErrOOM.Error()},
+		{[]string{"APPEND", "a", "1"}, nil, ErrOOM.Error()},
+		{[]string{"GET", "a"}, b("1"), ""},
+		{[]string{"DEL", "a"}, 1, ""},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %q, %q want %q, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+	cl.Store.SetEviction(store.Eviction{})
+	cl.Execute(bA([]string{"SET", "a", "1"}))
+	cl.Execute(bA([]string{"SET", "b", "1"}))
+	// one of the keys has to be evicted
+	cl.Store.SetEviction(store.Eviction{MaxMemory: cl.Store.UsedMemory() - 1, Policy: store.AllKeysRandom})
+	if got, err := cl.Execute(bA([]string{"SET", "c", "1"})); err != nil || got != "OK" {
+		t.Errorf("SET with allkeys-random: got %q, %v", got, err)
+	}
+	if n := cl.Store.EvictedKeys(); n != 1 {
+		t.Errorf("got %d evicted keys", n)
+	}
+}
+
+func Test__bytesToHuman(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1536, "1.50K"},
+		{3 << 30, "3.00G"},
+	}
+	for _, tt := range tests {
+		if got := bytesToHuman(tt.input); got != tt.expected {
+			t.Errorf("bytesToHuman(%d): got %q want %q", tt.input, got, tt.expected)
+		}
+	}
+}
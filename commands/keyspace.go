@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/glob"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+var (
+	ErrNoSuchKey     = errors.New("ERR no such key")
+	ErrInvalidCursor = errors.New("ERR invalid cursor")
+	ErrWrongType     = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+)
+
+// typeName returns the type of a value as reported by TYPE.
+func typeName(v interface{}) string {
+	if _, ok := v.(*store.ZSet); ok {
+		return "zset"
+	}
+	return "string"
+}
+
+// groupTypes maps the groups of commands to the type of value their keys
+// hold. Generic commands work on every type.
+var groupTypes = map[string]string{
+	"string":      "string",
+	"bitmap":      "string",
+	"hyperloglog": "string",
+	"sorted-set":  "zset",
+	"geo":         "zset",
+}
+
+// overwrites lists the commands that replace the value of a key whatever
+// its type, with the position of that key, 0 for all of them.
+var overwrites = map[string]int{
+	"set":            0,
+	"setnx":          0,
+	"mset":           0,
+	"msetnx":         0,
+	"bitop":          2,
+	"geosearchstore": 1,
+}
+
+// checkType returns ErrWrongType when a key of the command holds a value
+// of another type than the one of its group.
+func checkType(kv *store.DB, c *commandInfo, args [][]byte) error {
+	want, ok := groupTypes[c.group]
+	if !ok {
+		return nil
+	}
+	pos, ow := overwrites[c.name]
+	for _, i := range c.getKeys(args) {
+		if ow && (pos == 0 || pos == i) {
+			continue
+		}
+		if v, ok := kv.Borrow(args[i]); ok && typeName(v) != want {
+			return ErrWrongType
+		}
+	}
+	return nil
+}
+
+// keys implements KEYS pattern.
+func keys(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) != 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	pattern := s[1]
+	r := []interface{}{}
+	kv.Range(func(key string, v interface{}) bool {
+		if string(pattern) == "*" || glob.MatchString(string(pattern), key, false) {
+			r = append(r, []byte(key))
+		}
+		return true
+	})
+	return r, nil
+}
+
+// scan implements SCAN cursor [MATCH pattern] [COUNT count] [TYPE type].
+// MATCH and TYPE filter the keys after they are read, so a call may return
+// no keys with a cursor that isn't 0.
+func scan(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	cursor, err := strconv.ParseUint(string(s[1]), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var pattern, typ string
+	count := 10
+	for i := 2; i < len(s); i += 2 {
+		if i+1 >= len(s) {
+			return nil, ErrInvalidSyntax
+		}
+		switch strings.ToUpper(string(s[i])) {
+		case "MATCH":
+			pattern = string(s[i+1])
+		case "COUNT":
+			n, err := strconv.Atoi(string(s[i+1]))
+			if err != nil {
+				return nil, ErrValNotIntOrOutOfRange
+			}
+			if n < 1 {
+				return nil, ErrInvalidSyntax
+			}
+			count = n
+		case "TYPE":
+			typ = string(s[i+1])
+		default:
+			return nil, ErrInvalidSyntax
+		}
+	}
+	found := []interface{}{}
+	cursor = kv.Scan(cursor, count, func(key string, v interface{}) {
+		if pattern != "" && pattern != "*" && !glob.MatchString(pattern, key, false) {
+			return
+		}
+		if typ != "" && !strings.EqualFold(typ, typeName(v)) {
+			return
+		}
+		found = append(found, []byte(key))
+	})
+	return []interface{}{[]byte(strconv.FormatUint(cursor, 10)), found}, nil
+}
+
+// rename implements RENAME and, with nx set, RENAMENX.
+func rename(kv *store.DB, s [][]byte, nx bool) (interface{}, error) {
+	if len(s) != 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	if string(s[1]) == string(s[2]) {
+		if _, ok := kv.Get(s[1]); !ok {
+			return nil, ErrNoSuchKey
+		}
+		if nx {
+			return 0, nil
+		}
+		return "OK", nil
+	}
+	found, renamed := kv.Rename(s[1], s[2], nx)
+	switch {
+	case !found:
+		return nil, ErrNoSuchKey
+	case !nx:
+		return "OK", nil
+	case renamed:
+		return 1, nil
+	}
+	return 0, nil
+}
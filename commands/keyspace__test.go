@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+// sorted returns the bulk strings of a reply in sorted order.
+func sorted(reply interface{}) []string {
+	var r []string
+	for _, v := range reply.([]interface{}) {
+		r = append(r, string(v.([]byte)))
+	}
+	sort.Strings(r)
+	return r
+}
+
+func Test__Keyspace(t *testing.T) {
+	kv := store.New()
+	for _, k := range []string{"user:1", "user:2", "user:10", "order:1"} {
+		kv.Set(b(k), b(k))
+	}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      error
+	}{
+		{[]string{"TYPE", "user:1"}, "string", nil},
+		{[]string{"TYPE", "nosuch"}, "none", nil},
+		{[]string{"TOUCH", "user:1", "user:2", "nosuch"}, 2, nil},
+		{[]string{"RENAME", "nosuch", "x"}, nil, ErrNoSuchKey},
+		{[]string{"RENAME", "user:1", "user:1"}, "OK", nil},
+		{[]string{"RENAME", "order:1", "order:2"}, "OK", nil},
+		{[]string{"GET", "order:2"}, b("order:1"), nil},
+		{[]string{"EXISTS", "order:1"}, 0, nil},
+		{[]string{"RENAMENX", "order:2", "user:1"}, 0, nil},
+		{[]string{"RENAMENX", "order:2", "order:3"}, 1, nil},
+		{[]string{"RENAMENX", "order:3", "order:3"}, 0, nil},
+		{[]string{"RENAME", "order:3", "user:10"}, "OK", nil},
+		{[]string{"GET", "user:10"}, b("order:1"), nil},
+		{[]string{"UNLINK", "user:10", "nosuch"}, 1, nil},
+		{[]string{"SCAN", "x"}, nil, ErrInvalidCursor},
+		{[]string{"SCAN", "0", "COUNT", "0"}, nil, ErrInvalidSyntax},
+		{[]string{"SCAN", "0", "MATCH"}, nil, ErrInvalidSyntax},
+	}
+	for _, tt := range tests {
+		got, err := ExecuteCommand(kv, bA(tt.input))
+		if err != tt.err || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ExecuteCommand(%q): got %q, %v want %q, %v", tt.input, got, err, tt.expected, tt.err)
+		}
+	}
+
+	got, _ := ExecuteCommand(kv, bA([]string{"KEYS", "user:?"}))
+	if keys := sorted(got); !reflect.DeepEqual(keys, []string{"user:1", "user:2"}) {
+		t.Errorf("KEYS user:?: got %q", keys)
+	}
+	if got, _ := ExecuteCommand(kv, bA([]string{"RANDOMKEY"})); got == nil {
+		t.Errorf("RANDOMKEY: got nil")
+	}
+	ExecuteCommand(kv, bA([]string{"FLUSHDB"}))
+	if got, err := ExecuteCommand(kv, bA([]string{"RANDOMKEY"})); got != nil || err != nil {
+		t.Errorf("RANDOMKEY of an empty database: got %q, %v", got, err)
+	}
+}
+
+func Test__SCAN(t *testing.T) {
+	kv := store.New()
+	for i := 0; i < 500; i++ {
+		kv.Set(b("key:"+strconv.Itoa(i)), b("v"))
+	}
+	tests := []struct {
+		options  []string
+		expected int
+	}{
+		{nil, 500},
+		{[]string{"COUNT", "100"}, 500},
+		{[]string{"COUNT", "1000000000000000000"}, 500},
+		{[]string{"COUNT", "9223372036854775807"}, 500},
+		{[]string{"MATCH", "key:1*"}, 111},
+		{[]string{"MATCH", "key:1*", "TYPE", "STRING"}, 111},
+		{[]string{"TYPE", "hash"}, 0},
+	}
+	for _, tt := range tests {
+		seen := map[string]bool{}
+		cursor := "0"
+		for calls := 0; ; calls++ {
+			if calls > 1000 {
+				t.Fatalf("SCAN %q: the cursor never returned to 0", tt.options)
+			}
+			reply, err := ExecuteCommand(kv, bA(append([]string{"SCAN", cursor}, tt.options...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := reply.([]interface{})
+			for _, k := range sorted(r[1]) {
+				seen[k] = true
+			}
+			// keys are added while iterating
+			kv.Set(b(fmt.Sprintf("new:%d", calls)), b("v"))
+			if cursor = string(r[0].([]byte)); cursor == "0" {
+				break
+			}
+		}
+		n := 0
+		for k := range seen {
+			if k[:4] == "key:" {
+				n++
+			}
+		}
+		if n != tt.expected {
+			t.Errorf("SCAN %q: got %d keys want %d", tt.options, n, tt.expected)
+		}
+		ExecuteCommand(kv, bA([]string{"FLUSHDB"}))
+		for i := 0; i < 500; i++ {
+			kv.Set(b("key:"+strconv.Itoa(i)), b("v"))
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/tinfoil-knight/tiny-redis/store"
 )
@@ -17,16 +19,57 @@ var (
 	ErrValNotIntOrOutOfRange       = errors.New("ERR value is not an integer or out of range")
 	ErrOffsetOutOfRange            = errors.New("ERR offset is out of range")
 	ErrBitOffsetNotIntOrOutOfRange = errors.New("ERR bit offset is not an integer or out of range")
+	ErrValNotFloat                 = errors.New("ERR value is not a valid float")
+	ErrOOM                         = errors.New("OOM command not allowed when used memory > 'maxmemory'.")
 )
 
 const NUL = "\u0000"
 
 var EMPTY = []byte("")
 
+// ShutdownRequest is the result of SHUTDOWN. The server stops once it
+// receives it, saving the keyspace first when Save is set.
+type ShutdownRequest struct {
+	Save bool
+}
+
+// execMu serializes command execution so that every command, including the
+// ones added through Register, is atomic like in Redis.
+var execMu sync.Mutex
+
 func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err error) {
+	return (&Client{Store: kv}).Execute(cmdSeq)
+}
+
+// Execute runs a command on behalf of the client.
+func (cl *Client) Execute(cmdSeq []([]byte)) (res interface{}, err error) {
+	execMu.Lock()
+	defer execMu.Unlock()
+	defer func() { cl.count(err) }()
+	kv := cl.db()
 	s := cmdSeq
 	sLen := len(s)
 	cmd := strings.ToUpper(string(s[0]))
+	c, ok := lookupCommand(cmd)
+	if ok {
+		if err := cl.checkAccess(c, s); err != nil {
+			return nil, err
+		}
+		// keys are evicted before every command, but only the ones that
+		// may use more memory are refused when that isn't enough
+		if !cl.Store.Evict() && c.hasFlag("denyoom") {
+			return nil, ErrOOM
+		}
+		if err := checkType(kv, c, s); err != nil {
+			return nil, err
+		}
+	}
+	if ok && c.handler != nil {
+		if !c.checkArity(sLen) {
+			return nil, ErrWrongNumOfArgs
+		}
+		return c.handler(&Context{Args: s, kv: kv})
+	}
 	switch cmd {
 	case "PING":
 		if sLen > 2 {
@@ -96,7 +139,7 @@ func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err er
 		}
 		kv.Set(key, v)
 		return "OK", nil
-	case "DEL":
+	case "DEL", "UNLINK":
 		if sLen < 2 {
 			return nil, ErrWrongNumOfArgs
 		}
@@ -119,7 +162,7 @@ func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err er
 			return v, nil
 		}
 		return nil, nil
-	case "EXISTS":
+	case "EXISTS", "TOUCH":
 		if sLen < 2 {
 			return nil, ErrWrongNumOfArgs
 		}
@@ -131,78 +174,52 @@ func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err er
 			}
 		}
 		return n, nil
-	case "INCR":
-		if sLen != 2 {
-			return nil, ErrWrongNumOfArgs
-		}
-		key := s[1]
-		if str, ok := kv.Get(key); ok {
-			v, err := strconv.Atoi(string(str))
-			if err != nil {
-				return nil, ErrValNotIntOrOutOfRange
-			}
-			v++
-			kv.Set(key, []byte(strconv.Itoa(v)))
-			return v, nil
-		}
-		kv.Set(key, []byte("1"))
-		return 1, nil
-	case "DECR":
+	case "INCR", "DECR":
 		if sLen != 2 {
 			return nil, ErrWrongNumOfArgs
 		}
-		key := s[1]
-		if byts, ok := kv.Get(key); ok {
-			v, err := strconv.Atoi(string(byts))
-			if err != nil {
-				return nil, ErrValNotIntOrOutOfRange
-			}
-			v--
-			kv.Set(key, []byte(strconv.Itoa(v)))
-			return v, nil
+		if cmd == "DECR" {
+			return incrBy(kv, s[1], -1)
 		}
-		kv.Set(key, []byte("-1"))
-		return -1, nil
-	case "INCRBY":
+		return incrBy(kv, s[1], 1)
+	case "INCRBY", "DECRBY":
 		if sLen != 3 {
 			return nil, ErrWrongNumOfArgs
 		}
-		key := s[1]
-		incr, err := strconv.Atoi(string(s[2]))
-		if err != nil {
+		incr, ok := parseInt64(s[2])
+		if !ok {
 			return nil, ErrValNotIntOrOutOfRange
 		}
-		if byts, ok := kv.Get(key); ok {
-			v, err := strconv.Atoi(string(byts))
-			if err != nil {
-				return nil, ErrValNotIntOrOutOfRange
+		if cmd == "DECRBY" {
+			if incr == math.MinInt64 {
+				return nil, ErrDecrOverflow
 			}
-			v += incr
-			kv.Set(key, []byte(strconv.Itoa(v)))
-			return v, nil
+			incr = -incr
 		}
-		kv.Set(key, []byte(strconv.Itoa(incr)))
-		return incr, nil
-	case "DECRBY":
+		return incrBy(kv, s[1], incr)
+	case "INCRBYFLOAT":
+		return incrByFloat(kv, s)
+	case "SETNX":
 		if sLen != 3 {
 			return nil, ErrWrongNumOfArgs
 		}
-		key := s[1]
-		decr, err := strconv.Atoi(string(s[2]))
-		if err != nil {
-			return nil, ErrValNotIntOrOutOfRange
+		if _, ok := kv.Get(s[1]); ok {
+			return 0, nil
 		}
-		if byts, ok := kv.Get(key); ok {
-			v, err := strconv.Atoi(string(byts))
-			if err != nil {
-				return nil, ErrValNotIntOrOutOfRange
-			}
-			v -= decr
-			kv.Set(key, []byte(strconv.Itoa(v)))
-			return v, nil
+		kv.Set(s[1], s[2])
+		return 1, nil
+	case "GETSET":
+		if sLen != 3 {
+			return nil, ErrWrongNumOfArgs
+		}
+		v, ok := kv.Get(s[1])
+		kv.Set(s[1], s[2])
+		if !ok {
+			return nil, nil
 		}
-		kv.Set(key, []byte(strconv.Itoa(-decr)))
-		return -decr, nil
+		return v, nil
+	case "LCS":
+		return cl.lcs(s)
 	case "APPEND":
 		if sLen != 3 {
 			return nil, ErrWrongNumOfArgs
@@ -219,26 +236,79 @@ func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err er
 		kv.Set(key, []byte(value))
 		return len(value), nil
 	case "GETBIT":
-		if sLen != 3 {
-			return nil, ErrWrongNumOfArgs
-		}
-		offset, err := strconv.Atoi(string(s[2]))
-		if err != nil || offset < 0 {
-			return nil, ErrBitOffsetNotIntOrOutOfRange
-		}
-		key := s[1]
-		v, ok := kv.Get(key)
-		if !ok {
-			return 0, nil
-		}
-		if offset >= len(v) || s[1][offset] == 0 {
-			return 0, nil
-		}
-		return 1, nil
+		return cl.getbit(s)
 	case "SETBIT":
+		return cl.setbit(s)
+	case "BITCOUNT":
+		return bitcount(kv, s)
+	case "BITPOS":
+		return bitpos(kv, s)
+	case "BITOP":
+		return bitop(kv, s)
+	case "BITFIELD":
+		return cl.bitfield(s, false)
+	case "BITFIELD_RO":
+		return cl.bitfield(s, true)
+	case "PFADD":
+		return cl.pfadd(s)
+	case "PFCOUNT":
+		return cl.pfcount(s)
+	case "PFMERGE":
+		return cl.pfmerge(s)
+	case "ZADD":
+		return zadd(kv, s)
+	case "ZCARD":
+		return zcard(kv, s)
+	case "ZSCORE":
+		return zscore(kv, s)
+	case "ZREM":
+		return zrem(kv, s)
+	case "ZRANGE":
+		return cl.zrange(s)
+	case "ZSCAN":
+		return zscan(kv, s)
+	case "GEOADD":
+		return geoadd(kv, s)
+	case "GEODIST":
+		return geodist(kv, s)
+	case "GEOHASH":
+		return geohashes(kv, s)
+	case "GEOPOS":
+		return geopos(kv, s)
+	case "GEOSEARCH":
+		return geosearch(kv, s, false)
+	case "GEOSEARCHSTORE":
+		return geosearch(kv, s, true)
+	case "COMMAND":
+		return command(s)
+	case "CONFIG":
+		return cl.configCommand(s)
+	case "HELLO":
+		return cl.hello(s)
+	case "AUTH":
+		return cl.auth(s)
+	case "ACL":
+		return cl.aclCommand(s)
 	case "SAVE":
-		kv.Save()
+		if err := cl.Store.Save(); err != nil {
+			return nil, fmt.Errorf("ERR %v", err)
+		}
 		return "OK", nil
+	case "SHUTDOWN":
+		if sLen > 2 {
+			return nil, ErrInvalidSyntax
+		}
+		req := ShutdownRequest{Save: true}
+		if sLen == 2 {
+			switch strings.ToUpper(string(s[1])) {
+			case "NOSAVE":
+				req.Save = false
+			case "SAVE":
+			default:
+				return nil, ErrInvalidSyntax
+			}
+		}
+		return req, nil
 	case "STRLEN":
 		if sLen != 2 {
 			return nil, ErrWrongNumOfArgs
@@ -246,34 +316,8 @@ func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err er
 		key := s[1]
 		v, _ := kv.Get(key)
 		return len(v), nil
-	case "GETRANGE":
-		if sLen != 4 {
-			return nil, ErrWrongNumOfArgs
-		}
-		key := s[1]
-		if v, ok := kv.Get(key); ok {
-			l := len(v)
-			start, err1 := strconv.Atoi(string(s[2]))
-			end, err2 := strconv.Atoi(string(s[3]))
-			if err1 != nil || err2 != nil {
-				return nil, ErrValNotIntOrOutOfRange
-			}
-			if start >= l {
-				return EMPTY, nil
-			}
-			if end >= l {
-				end = l - 1
-			}
-			start = (start%l + l) % l
-			end = (end%l + l) % l
-			if start > end {
-				return EMPTY, nil
-			}
-			// GETRANGE is inclusive for both offsets
-			end++
-			return []byte(v[start:end]), nil
-		}
-		return EMPTY, nil
+	case "GETRANGE", "SUBSTR":
+		return getrange(kv, s)
 	case "SETRANGE":
 		if sLen != 4 {
 			return nil, ErrWrongNumOfArgs
@@ -333,7 +377,6 @@ func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err er
 		if sLen < 3 || (sLen&1 == 0) {
 			return nil, ErrWrongNumOfArgs
 		}
-		// TODO: make the operation atomic
 		pairs := s[1:]
 		n := 0
 		for i := 0; i < len(pairs)-1; i += 2 {
@@ -354,10 +397,13 @@ func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err er
 			return nil, ErrWrongNumOfArgs
 		}
 		src := s[1]
-		v, ok := kv.Get(src)
+		v, ok := kv.Value(src)
 		if !ok {
 			return 0, nil
 		}
+		if z, ok := v.(*store.ZSet); ok {
+			v = z.Clone()
+		}
 		dest := s[2]
 		_, ok = kv.Get(dest)
 		if ok {
@@ -369,10 +415,57 @@ func ExecuteCommand(kv *store.Store, cmdSeq []([]byte)) (res interface{}, err er
 				return 0, nil
 			}
 		}
-		kv.Set(dest, v)
+		kv.SetValue(dest, v)
 		return 1, nil
-	case "RENAME":
+	case "SELECT":
+		return cl.selectDB(s)
+	case "MOVE":
+		return cl.move(s)
+	case "SWAPDB":
+		return cl.swapDB(s)
 	case "FLUSHDB":
+		if err := flushMode(s); err != nil {
+			return nil, err
+		}
+		kv.Flush()
+		return "OK", nil
+	case "FLUSHALL":
+		if err := flushMode(s); err != nil {
+			return nil, err
+		}
+		cl.Store.FlushAll()
+		return "OK", nil
+	case "INFO":
+		return cl.serverInfo(s)
+	case "DBSIZE":
+		if sLen != 1 {
+			return nil, ErrWrongNumOfArgs
+		}
+		return kv.Len(), nil
+	case "KEYS":
+		return keys(kv, s)
+	case "SCAN":
+		return scan(kv, s)
+	case "RANDOMKEY":
+		if sLen != 1 {
+			return nil, ErrWrongNumOfArgs
+		}
+		if key, ok := kv.RandomKey(); ok {
+			return []byte(key), nil
+		}
+		return nil, nil
+	case "TYPE":
+		if sLen != 2 {
+			return nil, ErrWrongNumOfArgs
+		}
+		if v, ok := kv.Value(s[1]); ok {
+			return typeName(v), nil
+		}
+		return "none", nil
+	case "RENAME":
+		return rename(kv, s, false)
+	case "RENAMENX":
+		return rename(kv, s, true)
 	}
 	return nil, ErrInvalidCommand
 }
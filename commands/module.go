@@ -0,0 +1,248 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+// Handler executes a custom command. Handlers run while holding the
+// execution lock, so a handler is atomic with respect to every other command.
+type Handler func(ctx *Context) (interface{}, error)
+
+// Command describes a custom command registered through Register.
+//
+// Arity, FirstKey, LastKey and Step follow the Redis conventions, see COMMAND
+// INFO. Flags are Redis command flags such as "write", "readonly", "denyoom"
+// or "fast". When Categories is empty they are derived from Flags.
+type Command struct {
+	Name       string
+	Arity      int
+	Flags      []string
+	FirstKey   int
+	LastKey    int
+	Step       int
+	Categories []string
+	Summary    string
+	Handler    Handler
+}
+
+var (
+	ErrCommandExists     = errors.New("command already exists")
+	ErrInvalidCommandDef = errors.New("invalid command definition")
+)
+
+var validFlags = map[string]bool{
+	"write": true, "readonly": true, "denyoom": true, "admin": true,
+	"pubsub": true, "noscript": true, "blocking": true, "loading": true,
+	"stale": true, "fast": true, "no_auth": true, "may_replicate": true,
+	"no_mandatory_keys": true, "allow_busy": true,
+}
+
+// Register adds a custom command to the command table. It takes the
+// execution lock, so commands may be registered while clients run commands.
+func Register(cmd Command) error {
+	execMu.Lock()
+	defer execMu.Unlock()
+	name := strings.ToLower(cmd.Name)
+	if name == "" || strings.ContainsAny(name, " |") {
+		return fmt.Errorf("%w: bad name %q", ErrInvalidCommandDef, cmd.Name)
+	}
+	if cmd.Handler == nil {
+		return fmt.Errorf("%w: %s has no handler", ErrInvalidCommandDef, name)
+	}
+	if cmd.Arity == 0 {
+		return fmt.Errorf("%w: %s has zero arity", ErrInvalidCommandDef, name)
+	}
+	if _, ok := commandTable[name]; ok {
+		return fmt.Errorf("%w: %s", ErrCommandExists, name)
+	}
+	for _, f := range cmd.Flags {
+		if !validFlags[f] {
+			return fmt.Errorf("%w: %s has unknown flag %q", ErrInvalidCommandDef, name, f)
+		}
+	}
+	step := cmd.Step
+	if cmd.FirstKey > 0 && step == 0 {
+		step = 1
+	}
+	info := &commandInfo{
+		name:       name,
+		arity:      cmd.Arity,
+		flags:      cmd.Flags,
+		firstKey:   cmd.FirstKey,
+		lastKey:    cmd.LastKey,
+		step:       step,
+		categories: cmd.Categories,
+		group:      "module",
+		summary:    cmd.Summary,
+		handler:    cmd.Handler,
+	}
+	if len(info.categories) == 0 {
+		info.categories = categoriesFromFlags(cmd.Flags)
+	}
+	commandTable[name] = info
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func MustRegister(cmd Command) {
+	if err := Register(cmd); err != nil {
+		panic(err)
+	}
+}
+
+func categoriesFromFlags(flags []string) []string {
+	var cats []string
+	for _, f := range flags {
+		switch f {
+		case "write":
+			cats = append(cats, "@write")
+		case "readonly":
+			cats = append(cats, "@read")
+		case "admin":
+			cats = append(cats, "@admin", "@dangerous")
+		case "pubsub":
+			cats = append(cats, "@pubsub")
+		case "blocking":
+			cats = append(cats, "@blocking")
+		}
+	}
+	speed := "@slow"
+	for _, f := range flags {
+		if f == "fast" {
+			speed = "@fast"
+		}
+	}
+	return append(cats, speed)
+}
+
+// Context is passed to a Handler. It carries the command arguments and gives
+// typed access to the database selected by the client.
+type Context struct {
+	// Args holds the command name followed by its arguments.
+	Args [][]byte
+	kv   *store.DB
+}
+
+// NArgs returns the number of arguments including the command name.
+func (ctx *Context) NArgs() int {
+	return len(ctx.Args)
+}
+
+// Arg returns the i-th argument, where 0 is the command name.
+func (ctx *Context) Arg(i int) []byte {
+	return ctx.Args[i]
+}
+
+// ArgInt parses the i-th argument as a 64 bit signed integer.
+func (ctx *Context) ArgInt(i int) (int64, error) {
+	n, err := strconv.ParseInt(string(ctx.Args[i]), 10, 64)
+	if err != nil {
+		return 0, ErrValNotIntOrOutOfRange
+	}
+	return n, nil
+}
+
+// ArgFloat parses the i-th argument as a float.
+func (ctx *Context) ArgFloat(i int) (float64, error) {
+	f, err := strconv.ParseFloat(string(ctx.Args[i]), 64)
+	if err != nil {
+		return 0, ErrValNotFloat
+	}
+	return f, nil
+}
+
+// Get returns the string value stored at key.
+func (ctx *Context) Get(key []byte) ([]byte, bool) {
+	return ctx.kv.Get(key)
+}
+
+// GetInt returns the value stored at key as an integer. A missing key reads
+// as 0 with ok set to false.
+func (ctx *Context) GetInt(key []byte) (n int64, ok bool, err error) {
+	v, ok := ctx.kv.Get(key)
+	if !ok {
+		return 0, false, nil
+	}
+	n, err = strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return 0, true, ErrValNotIntOrOutOfRange
+	}
+	return n, true, nil
+}
+
+// Set stores value at key.
+func (ctx *Context) Set(key []byte, value []byte) {
+	ctx.kv.Set(key, value)
+}
+
+// SetInt stores n at key using its decimal representation.
+func (ctx *Context) SetInt(key []byte, n int64) {
+	ctx.kv.Set(key, []byte(strconv.FormatInt(n, 10)))
+}
+
+// Del removes key and reports whether it existed.
+func (ctx *Context) Del(key []byte) bool {
+	if _, ok := ctx.kv.Get(key); !ok {
+		return false
+	}
+	ctx.kv.Del(key)
+	return true
+}
+
+// Exists reports whether key is present.
+func (ctx *Context) Exists(key []byte) bool {
+	_, ok := ctx.kv.Get(key)
+	return ok
+}
+
+// ReplyOK replies with the +OK status.
+func (ctx *Context) ReplyOK() (interface{}, error) {
+	return "OK", nil
+}
+
+// ReplyStatus replies with a simple string.
+func (ctx *Context) ReplyStatus(s string) (interface{}, error) {
+	return s, nil
+}
+
+// ReplyInt replies with an integer.
+func (ctx *Context) ReplyInt(n int64) (interface{}, error) {
+	return int(n), nil
+}
+
+// ReplyBulk replies with a bulk string.
+func (ctx *Context) ReplyBulk(b []byte) (interface{}, error) {
+	return b, nil
+}
+
+// ReplyNull replies with a null.
+func (ctx *Context) ReplyNull() (interface{}, error) {
+	return nil, nil
+}
+
+// ReplyArray replies with an array of values built by the other helpers:
+// string for status, int for integers, []byte for bulk strings and nil.
+func (ctx *Context) ReplyArray(items ...interface{}) (interface{}, error) {
+	if items == nil {
+		items = []interface{}{}
+	}
+	return items, nil
+}
+
+// ReplyError replies with an error. The "ERR" code is prepended unless msg
+// already starts with an upper-case error code.
+func (ctx *Context) ReplyError(msg string) (interface{}, error) {
+	code := msg
+	if i := strings.IndexByte(msg, ' '); i >= 0 {
+		code = msg[:i]
+	}
+	if code == "" || code != strings.ToUpper(code) {
+		msg = "ERR " + msg
+	}
+	return nil, errors.New(msg)
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__Register(t *testing.T) {
+	kv := store.New()
+	err := Register(Command{
+		Name:     "test.incrcap",
+		Arity:    3,
+		Flags:    []string{"write", "denyoom", "fast"},
+		FirstKey: 1,
+		LastKey:  1,
+		Handler: func(ctx *Context) (interface{}, error) {
+			limit, err := ctx.ArgInt(2)
+			if err != nil {
+				return nil, err
+			}
+			n, _, err := ctx.GetInt(ctx.Arg(1))
+			if err != nil {
+				return nil, err
+			}
+			if n >= limit {
+				return ctx.ReplyError("LIMIT reached")
+			}
+			ctx.SetInt(ctx.Arg(1), n+1)
+			return ctx.ReplyInt(n + 1)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		{[]string{"TEST.INCRCAP", "cap", "2"}, 1, ""},
+		{[]string{"test.incrcap", "cap", "2"}, 2, ""},
+		{[]string{"test.incrcap", "cap", "2"}, nil, "LIMIT reached"},
+		{[]string{"test.incrcap", "cap", "x"}, nil, ErrValNotIntOrOutOfRange.Error()},
+		{[]string{"test.incrcap", "cap"}, nil, ErrWrongNumOfArgs.Error()},
+	}
+	for _, tt := range tests {
+		got, err := ExecuteCommand(kv, bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("ExecuteCommand(%q): got %v, %q want %v, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+
+	keys, _ := ExecuteCommand(kv, bA([]string{"COMMAND", "GETKEYS", "test.incrcap", "k", "1"}))
+	if !reflect.DeepEqual(keys, []interface{}{b("k")}) {
+		t.Errorf("COMMAND GETKEYS: got %q", keys)
+	}
+}
+
+func Test__RegisterWhileExecuting(t *testing.T) {
+	kv := store.New()
+	noop := func(ctx *Context) (interface{}, error) { return ctx.ReplyOK() }
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			ExecuteCommand(kv, bA([]string{"COMMAND", "COUNT"}))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		MustRegister(Command{Name: fmt.Sprintf("test.concurrent%d", i), Arity: 1, Handler: noop})
+	}
+	wg.Wait()
+}
+
+func Test__RegisterInvalid(t *testing.T) {
+	noop := func(ctx *Context) (interface{}, error) { return ctx.ReplyOK() }
+	tests := []struct {
+		cmd Command
+		err error
+	}{
+		{Command{Name: "get", Arity: 2, Handler: noop}, ErrCommandExists},
+		{Command{Name: "", Arity: 1, Handler: noop}, ErrInvalidCommandDef},
+		{Command{Name: "x|y", Arity: 1, Handler: noop}, ErrInvalidCommandDef},
+		{Command{Name: "nohandler", Arity: 1}, ErrInvalidCommandDef},
+		{Command{Name: "noarity", Handler: noop}, ErrInvalidCommandDef},
+		{Command{Name: "badflag", Arity: 1, Flags: []string{"quick"}, Handler: noop}, ErrInvalidCommandDef},
+	}
+	for _, tt := range tests {
+		if err := Register(tt.cmd); !errors.Is(err, tt.err) {
+			t.Errorf("Register(%q): got %v want %v", tt.cmd.Name, err, tt.err)
+		}
+	}
+}
+
+// A fixed window rate limiter: RATELIMIT key max
+func ExampleRegister() {
+	MustRegister(Command{
+		Name:     "ratelimit",
+		Arity:    3,
+		Flags:    []string{"write", "denyoom", "fast"},
+		FirstKey: 1,
+		LastKey:  1,
+		Summary:  "Counts a hit and replies with 1 while under the limit.",
+		Handler: func(ctx *Context) (interface{}, error) {
+			max, err := ctx.ArgInt(2)
+			if err != nil {
+				return nil, err
+			}
+			hits, _, err := ctx.GetInt(ctx.Arg(1))
+			if err != nil {
+				return nil, err
+			}
+			if hits >= max {
+				return ctx.ReplyInt(0)
+			}
+			ctx.SetInt(ctx.Arg(1), hits+1)
+			return ctx.ReplyInt(1)
+		},
+	})
+
+	kv := store.New()
+	for i := 0; i < 3; i++ {
+		r, _ := ExecuteCommand(kv, bA([]string{"RATELIMIT", "user:42", "2"}))
+		fmt.Println(r)
+	}
+	// Output:
+	// 1
+	// 1
+	// 0
+}
@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/glob"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+var (
+	ErrZAddXXAndNX   = errors.New("ERR XX and NX options at the same time are not compatible")
+	ErrZAddGTLTAndNX = errors.New("ERR GT, LT, and/or NX options at the same time are not compatible")
+	ErrZAddIncrPair  = errors.New("ERR INCR option supports a single increment-element pair")
+	ErrScoreNaN      = errors.New("ERR resulting score is not a number (NaN)")
+)
+
+// zset returns the sorted set at key, nil when it doesn't exist. The type
+// of key is checked by Execute.
+func zset(kv *store.DB, key []byte) *store.ZSet {
+	v, _ := kv.Value(key)
+	z, _ := v.(*store.ZSet)
+	return z
+}
+
+// parseFloat parses a score or a coordinate, which may be "inf" but not
+// NaN.
+func parseFloat(b []byte) (float64, error) {
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, ErrValNotFloat
+	}
+	return f, nil
+}
+
+// zaddFlags are the options of ZADD, GEOADD only has nx, xx and ch.
+type zaddFlags struct {
+	nx, xx, gt, lt, ch, incr bool
+}
+
+// zaddMembers adds members to the sorted set at key, or increments the
+// score of the only one with f.incr. It returns the number of members
+// added, or changed with f.ch, and the score of the last member, which ok
+// reports whether the flags let it be updated.
+func zaddMembers(kv *store.DB, key []byte, f zaddFlags, members []store.ZMember) (n int, score float64, ok bool, err error) {
+	z := zset(kv, key)
+	if z == nil {
+		if f.xx {
+			return 0, 0, false, nil
+		}
+		z = store.NewZSet()
+	}
+	added, updated := 0, 0
+	for _, m := range members {
+		score, ok = m.Score, false
+		cur, exists := z.Score(m.Member)
+		switch {
+		case exists && f.nx, !exists && f.xx:
+			continue
+		case !exists:
+			z.Add(m.Member, score)
+			added++
+			ok = true
+			continue
+		}
+		if f.incr {
+			if score += cur; math.IsNaN(score) {
+				return 0, 0, false, ErrScoreNaN
+			}
+		}
+		if (f.lt && score >= cur) || (f.gt && score <= cur) {
+			continue
+		}
+		ok = true
+		if score != cur {
+			z.Add(m.Member, score)
+			updated++
+		}
+	}
+	if z.Len() > 0 {
+		kv.SetValue(key, z)
+	}
+	if f.ch {
+		return added + updated, score, ok, nil
+	}
+	return added, score, ok, nil
+}
+
+// zadd implements ZADD key [NX|XX] [GT|LT] [CH] [INCR] score member
+// [score member ...].
+func zadd(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 4 {
+		return nil, ErrWrongNumOfArgs
+	}
+	var f zaddFlags
+	i := 2
+options:
+	for ; i < len(s); i++ {
+		switch strings.ToUpper(string(s[i])) {
+		case "NX":
+			f.nx = true
+		case "XX":
+			f.xx = true
+		case "GT":
+			f.gt = true
+		case "LT":
+			f.lt = true
+		case "CH":
+			f.ch = true
+		case "INCR":
+			f.incr = true
+		default:
+			break options
+		}
+	}
+	pairs := s[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return nil, ErrInvalidSyntax
+	}
+	switch {
+	case f.nx && f.xx:
+		return nil, ErrZAddXXAndNX
+	case (f.gt || f.lt) && f.nx, f.gt && f.lt:
+		return nil, ErrZAddGTLTAndNX
+	case f.incr && len(pairs) > 2:
+		return nil, ErrZAddIncrPair
+	}
+	members := make([]store.ZMember, len(pairs)/2)
+	for j := range members {
+		score, err := parseFloat(pairs[2*j])
+		if err != nil {
+			return nil, err
+		}
+		members[j] = store.ZMember{Member: string(pairs[2*j+1]), Score: score}
+	}
+	n, score, ok, err := zaddMembers(kv, s[1], f, members)
+	if err != nil {
+		return nil, err
+	}
+	if !f.incr {
+		return n, nil
+	}
+	if !ok {
+		return nil, nil
+	}
+	return score, nil
+}
+
+// zrem implements ZREM key member [member ...]. The key is deleted once
+// its last member is removed.
+func zrem(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	z := zset(kv, s[1])
+	if z == nil {
+		return 0, nil
+	}
+	n := 0
+	for _, member := range s[2:] {
+		if z.Remove(string(member)) {
+			n++
+		}
+	}
+	if z.Len() == 0 {
+		kv.Del(s[1])
+	} else if n > 0 {
+		kv.SetValue(s[1], z)
+	}
+	return n, nil
+}
+
+// zrange implements ZRANGE key start stop [REV] [WITHSCORES], the ranges
+// by score or lexicographic aren't supported. With RESP3 the members and
+// their scores are paired.
+func (cl *Client) zrange(s [][]byte) (interface{}, error) {
+	if len(s) < 4 {
+		return nil, ErrWrongNumOfArgs
+	}
+	start, err := strconv.Atoi(string(s[2]))
+	if err != nil {
+		return nil, ErrValNotIntOrOutOfRange
+	}
+	stop, err := strconv.Atoi(string(s[3]))
+	if err != nil {
+		return nil, ErrValNotIntOrOutOfRange
+	}
+	var rev, withScores bool
+	for _, opt := range s[4:] {
+		switch strings.ToUpper(string(opt)) {
+		case "REV":
+			rev = true
+		case "WITHSCORES":
+			withScores = true
+		default:
+			return nil, ErrInvalidSyntax
+		}
+	}
+	r := []interface{}{}
+	z := zset(cl.db(), s[1])
+	if z == nil {
+		return r, nil
+	}
+	n := z.Len()
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	z.Range(start, stop, rev, func(m store.ZMember) bool {
+		switch {
+		case !withScores:
+			r = append(r, []byte(m.Member))
+		case cl.Protocol() == 3:
+			r = append(r, []interface{}{[]byte(m.Member), m.Score})
+		default:
+			r = append(r, []byte(m.Member), m.Score)
+		}
+		return true
+	})
+	return r, nil
+}
+
+// zscan implements ZSCAN key cursor [MATCH pattern] [COUNT count]. The
+// scores are replied as strings like in Redis.
+func zscan(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) < 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	cursor, err := strconv.ParseUint(string(s[2]), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var pattern string
+	count := 10
+	for i := 3; i < len(s); i += 2 {
+		if i+1 >= len(s) {
+			return nil, ErrInvalidSyntax
+		}
+		switch strings.ToUpper(string(s[i])) {
+		case "MATCH":
+			pattern = string(s[i+1])
+		case "COUNT":
+			n, err := strconv.Atoi(string(s[i+1]))
+			if err != nil {
+				return nil, ErrValNotIntOrOutOfRange
+			}
+			if n < 1 {
+				return nil, ErrInvalidSyntax
+			}
+			count = n
+		default:
+			return nil, ErrInvalidSyntax
+		}
+	}
+	found := []interface{}{}
+	if z := zset(kv, s[1]); z != nil {
+		cursor = z.Scan(cursor, count, func(m store.ZMember) {
+			if pattern != "" && pattern != "*" && !glob.MatchString(pattern, m.Member, false) {
+				return
+			}
+			found = append(found, []byte(m.Member), []byte(formatFloat(m.Score)))
+		})
+	} else {
+		cursor = 0
+	}
+	return []interface{}{[]byte(strconv.FormatUint(cursor, 10)), found}, nil
+}
+
+// zscore implements ZSCORE key member.
+func zscore(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) != 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	if z := zset(kv, s[1]); z != nil {
+		if score, ok := z.Score(string(s[2])); ok {
+			return score, nil
+		}
+	}
+	return nil, nil
+}
+
+// zcard implements ZCARD key.
+func zcard(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) != 2 {
+		return nil, ErrWrongNumOfArgs
+	}
+	if z := zset(kv, s[1]); z != nil {
+		return z.Len(), nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__SortedSet(t *testing.T) {
+	cl := &Client{Store: store.New()}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		// the examples of the Redis docs
+		{[]string{"ZADD", "myzset", "1", "one"}, 1, ""},
+		{[]string{"ZADD", "myzset", "1", "uno"}, 1, ""},
+		{[]string{"ZADD", "myzset", "2", "two", "3", "two"}, 1, ""},
+		{[]string{"ZRANGE", "myzset", "0", "-1", "WITHSCORES"}, []interface{}{b("one"), 1.0, b("uno"), 1.0, b("two"), 3.0}, ""},
+		{[]string{"ZRANGE", "myzset", "-2", "-1"}, []interface{}{b("uno"), b("two")}, ""},
+		{[]string{"ZRANGE", "myzset", "0", "1", "REV"}, []interface{}{b("two"), b("uno")}, ""},
+		{[]string{"ZRANGE", "myzset", "2", "1"}, []interface{}{}, ""},
+		{[]string{"ZRANGE", "myzset", "5", "10"}, []interface{}{}, ""},
+		{[]string{"ZRANGE", "myzset", "0", "-1", "BYSCORE"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"ZCARD", "myzset"}, 3, ""},
+		{[]string{"ZCARD", "nosuch"}, 0, ""},
+		{[]string{"ZSCORE", "myzset", "two"}, 3.0, ""},
+		{[]string{"ZSCORE", "myzset", "nosuch"}, nil, ""},
+
+		{[]string{"ZADD", "myzset", "NX", "5", "one", "5", "three"}, 1, ""},
+		{[]string{"ZADD", "myzset", "XX", "CH", "5", "one", "5", "four"}, 1, ""},
+		{[]string{"ZADD", "myzset", "GT", "CH", "4", "one", "6", "uno"}, 1, ""},
+		{[]string{"ZADD", "myzset", "LT", "0", "one"}, 0, ""},
+		{[]string{"ZRANGE", "myzset", "0", "-1", "WITHSCORES"}, []interface{}{b("one"), 0.0, b("two"), 3.0, b("three"), 5.0, b("uno"), 6.0}, ""},
+		{[]string{"ZADD", "myzset", "INCR", "1.5", "two"}, 4.5, ""},
+		{[]string{"ZADD", "myzset", "NX", "INCR", "1", "two"}, nil, ""},
+		{[]string{"ZADD", "myzset", "INCR", "+inf", "two"}, math.Inf(1), ""},
+		{[]string{"ZADD", "myzset", "INCR", "-inf", "two"}, nil, ErrScoreNaN.Error()},
+		{[]string{"ZADD", "myzset", "XX", "1", "a"}, 0, ""},
+		{[]string{"ZADD", "nosuch", "XX", "1", "a"}, 0, ""},
+		{[]string{"EXISTS", "nosuch"}, 0, ""},
+		{[]string{"ZADD", "myzset", "NX", "XX", "1", "a"}, nil, ErrZAddXXAndNX.Error()},
+		{[]string{"ZADD", "myzset", "GT", "LT", "1", "a"}, nil, ErrZAddGTLTAndNX.Error()},
+		{[]string{"ZADD", "myzset", "INCR", "1", "a", "2", "b"}, nil, ErrZAddIncrPair.Error()},
+		{[]string{"ZADD", "myzset", "1", "a", "2"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"ZADD", "myzset", "nan", "a"}, nil, ErrValNotFloat.Error()},
+
+		{[]string{"ZREM", "myzset", "one", "nosuch", "uno"}, 2, ""},
+		{[]string{"ZREM", "nosuch", "one"}, 0, ""},
+		{[]string{"COPY", "myzset", "copy"}, 1, ""},
+		{[]string{"ZREM", "myzset", "two", "three"}, 2, ""},
+		{[]string{"EXISTS", "myzset"}, 0, ""},
+		{[]string{"ZCARD", "copy"}, 2, ""},
+
+		{[]string{"ZADD", "zs", "1", "a", "2.5", "b"}, 2, ""},
+		{[]string{"ZSCAN", "zs", "0", "MATCH", "b"}, []interface{}{b("0"), []interface{}{b("b"), b("2.5")}}, ""},
+		{[]string{"ZSCAN", "nosuch", "0"}, []interface{}{b("0"), []interface{}{}}, ""},
+		{[]string{"ZSCAN", "zs", "x"}, nil, ErrInvalidCursor.Error()},
+		{[]string{"ZSCAN", "zs", "0", "COUNT", "0"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"ZSCAN", "zs", "0", "MATCH"}, nil, ErrInvalidSyntax.Error()},
+
+		{[]string{"TYPE", "copy"}, "zset", ""},
+		{[]string{"GET", "copy"}, nil, ErrWrongType.Error()},
+		{[]string{"APPEND", "copy", "x"}, nil, ErrWrongType.Error()},
+		{[]string{"PFCOUNT", "copy"}, nil, ErrWrongType.Error()},
+		{[]string{"SET", "str", "x"}, "OK", ""},
+		{[]string{"ZADD", "str", "1", "a"}, nil, ErrWrongType.Error()},
+		{[]string{"ZRANGE", "str", "0", "-1"}, nil, ErrWrongType.Error()},
+		{[]string{"ZSCAN", "str", "0"}, nil, ErrWrongType.Error()},
+		{[]string{"SETNX", "copy", "x"}, 0, ""},
+		{[]string{"SET", "copy", "x"}, "OK", ""},
+		{[]string{"TYPE", "copy"}, "string", ""},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %v, %q want %v, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+
+	// RESP3 pairs the members with their scores
+	cl.Proto = 3
+	cl.Execute(bA([]string{"ZADD", "z", "1", "a"}))
+	got, _ := cl.Execute(bA([]string{"ZRANGE", "z", "0", "-1", "WITHSCORES"}))
+	if want := []interface{}{[]interface{}{b("a"), 1.0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ZRANGE with RESP3: got %v want %v", got, want)
+	}
+}
+
+func Test__ZSCAN(t *testing.T) {
+	kv := store.New()
+	for i := 0; i < 500; i++ {
+		ExecuteCommand(kv, bA([]string{"ZADD", "z", strconv.Itoa(i), "m:" + strconv.Itoa(i)}))
+	}
+	seen := map[string]string{}
+	cursor := "0"
+	for calls := 0; ; calls++ {
+		if calls > 1000 {
+			t.Fatal("ZSCAN: the cursor never returned to 0")
+		}
+		reply, err := ExecuteCommand(kv, bA([]string{"ZSCAN", "z", cursor, "COUNT", "20"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := reply.([]interface{})
+		found := r[1].([]interface{})
+		for i := 0; i < len(found); i += 2 {
+			seen[string(found[i].([]byte))] = string(found[i+1].([]byte))
+		}
+		// members are added while iterating
+		ExecuteCommand(kv, bA([]string{"ZADD", "z", "-1", fmt.Sprintf("new:%d", calls)}))
+		if cursor = string(r[0].([]byte)); cursor == "0" {
+			break
+		}
+	}
+	for i := 0; i < 500; i++ {
+		if score := seen["m:"+strconv.Itoa(i)]; score != strconv.Itoa(i) {
+			t.Errorf("ZSCAN: got score %q for m:%d", score, i)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+var (
+	ErrIncrOverflow      = errors.New("ERR increment or decrement would overflow")
+	ErrDecrOverflow      = errors.New("ERR decrement would overflow")
+	ErrIncrNaNOrInfinity = errors.New("ERR increment would produce NaN or Infinity")
+	ErrLCSLenAndIdx      = errors.New("ERR If you want both the length and indexes, please just use IDX.")
+	ErrLCSMemory         = errors.New("ERR Insufficient memory, transient memory for LCS exceeds proto-max-bulk-len")
+)
+
+// parseInt64 parses a 64 bit integer the way Redis does: only the canonical
+// form is accepted, so "+1", "01" and " 1" are not integers.
+func parseInt64(b []byte) (int64, bool) {
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != string(b) {
+		return 0, false
+	}
+	return n, true
+}
+
+// incrBy adds incr to the integer at key, which is 0 when missing, and
+// replies with the result. It fails rather than wrapping around.
+func incrBy(kv *store.DB, key []byte, incr int64) (interface{}, error) {
+	var v int64
+	if byts, ok := kv.Get(key); ok {
+		if v, ok = parseInt64(byts); !ok {
+			return nil, ErrValNotIntOrOutOfRange
+		}
+	}
+	if (incr < 0 && v < math.MinInt64-incr) || (incr > 0 && v > math.MaxInt64-incr) {
+		return nil, ErrIncrOverflow
+	}
+	v += incr
+	kv.Set(key, []byte(strconv.FormatInt(v, 10)))
+	return v, nil
+}
+
+// formatFloat formats f like the human friendly long double format of
+// Redis: no exponent and no trailing zeros. float64 has less precision than
+// a long double, so the shortest representation is used instead of 17
+// digits, e.g. 10.6 rather than 10.59999999999999964.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// incrByFloat implements INCRBYFLOAT key increment.
+func incrByFloat(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) != 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	incr, err := strconv.ParseFloat(string(s[2]), 64)
+	if err != nil || math.IsNaN(incr) {
+		return nil, ErrValNotFloat
+	}
+	var v float64
+	if byts, ok := kv.Get(s[1]); ok {
+		v, err = strconv.ParseFloat(string(byts), 64)
+		if err != nil || math.IsNaN(v) {
+			return nil, ErrValNotFloat
+		}
+	}
+	v += incr
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, ErrIncrNaNOrInfinity
+	}
+	r := []byte(formatFloat(v))
+	kv.Set(s[1], r)
+	return r, nil
+}
+
+// getrange implements GETRANGE key start end and SUBSTR. Both offsets are
+// inclusive and negative ones count from the end.
+func getrange(kv *store.DB, s [][]byte) (interface{}, error) {
+	if len(s) != 4 {
+		return nil, ErrWrongNumOfArgs
+	}
+	start, ok1 := parseInt64(s[2])
+	end, ok2 := parseInt64(s[3])
+	if !ok1 || !ok2 {
+		return nil, ErrValNotIntOrOutOfRange
+	}
+	v, _ := kv.Get(s[1])
+	n := int64(len(v))
+	if start < 0 && end < 0 && start > end {
+		return EMPTY, nil
+	}
+	if start < 0 {
+		start += n
+	}
+	if end < 0 {
+		end += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= n {
+		end = n - 1
+	}
+	if n == 0 || start > end {
+		return EMPTY, nil
+	}
+	return v[start : end+1], nil
+}
+
+// lcs implements LCS key1 key2 [LEN] [IDX] [MINMATCHLEN len]
+// [WITHMATCHLEN]. Missing keys are empty strings. Like Redis, the matches
+// are reported from the end of the strings.
+func (cl *Client) lcs(s [][]byte) (interface{}, error) {
+	if len(s) < 3 {
+		return nil, ErrWrongNumOfArgs
+	}
+	var getLen, getIdx, withMatchLen bool
+	var minMatchLen int64
+	for i := 3; i < len(s); i++ {
+		switch strings.ToUpper(string(s[i])) {
+		case "LEN":
+			getLen = true
+		case "IDX":
+			getIdx = true
+		case "WITHMATCHLEN":
+			withMatchLen = true
+		case "MINMATCHLEN":
+			if i+1 >= len(s) {
+				return nil, ErrInvalidSyntax
+			}
+			n, ok := parseInt64(s[i+1])
+			if !ok {
+				return nil, ErrValNotIntOrOutOfRange
+			}
+			if n > 0 {
+				minMatchLen = n
+			}
+			i++
+		default:
+			return nil, ErrInvalidSyntax
+		}
+	}
+	if getLen && getIdx {
+		return nil, ErrLCSLenAndIdx
+	}
+	kv := cl.db()
+	a, _ := kv.Get(s[1])
+	b, _ := kv.Get(s[2])
+	alen, blen := len(a), len(b)
+	// the table of the lengths of the LCS of every pair of prefixes
+	cells := int64(alen+1) * int64(blen+1)
+	if cells*4 > cl.config().Int("proto-max-bulk-len") {
+		return nil, ErrLCSMemory
+	}
+	dp := make([]uint32, cells)
+	at := func(i, j int) *uint32 { return &dp[j*(alen+1)+i] }
+	for i := 1; i <= alen; i++ {
+		for j := 1; j <= blen; j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				*at(i, j) = *at(i-1, j-1) + 1
+			case *at(i-1, j) > *at(i, j-1):
+				*at(i, j) = *at(i-1, j)
+			default:
+				*at(i, j) = *at(i, j-1)
+			}
+		}
+	}
+	total := int(*at(alen, blen))
+	if getLen {
+		return total, nil
+	}
+
+	// walk back from the end of both strings, collecting the common
+	// characters and the ranges they form
+	result := make([]byte, total)
+	matches := []interface{}{}
+	idx := total
+	// aStart == alen means that no range is being tracked
+	aStart, aEnd, bStart, bEnd := alen, 0, 0, 0
+	for i, j := alen, blen; i > 0 && j > 0; {
+		emit := false
+		if a[i-1] == b[j-1] {
+			result[idx-1] = a[i-1]
+			if aStart == alen {
+				aStart, aEnd, bStart, bEnd = i-1, i-1, j-1, j-1
+			} else if aStart == i && bStart == j {
+				// the range is contiguous, extend it backward
+				aStart--
+				bStart--
+			} else {
+				emit = true
+			}
+			if aStart == 0 || bStart == 0 {
+				emit = true
+			}
+			idx--
+			i--
+			j--
+		} else {
+			if *at(i-1, j) > *at(i, j-1) {
+				i--
+			} else {
+				j--
+			}
+			if aStart != alen {
+				emit = true
+			}
+		}
+		if emit {
+			matchLen := aEnd - aStart + 1
+			if minMatchLen == 0 || int64(matchLen) >= minMatchLen {
+				m := []interface{}{[]interface{}{aStart, aEnd}, []interface{}{bStart, bEnd}}
+				if withMatchLen {
+					m = append(m, matchLen)
+				}
+				matches = append(matches, m)
+			}
+			aStart = alen
+		}
+	}
+	if getIdx {
+		return resp.Map{
+			{Key: []byte("matches"), Value: matches},
+			{Key: []byte("len"), Value: total},
+		}, nil
+	}
+	return result, nil
+}
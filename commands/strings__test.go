@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+func Test__Strings(t *testing.T) {
+	cl := &Client{Store: store.New()}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		{[]string{"INCR", "n"}, int64(1), ""},
+		{[]string{"DECRBY", "n", "3"}, int64(-2), ""},
+		{[]string{"SET", "n", "9223372036854775806"}, "OK", ""},
+		{[]string{"INCR", "n"}, int64(9223372036854775807), ""},
+		{[]string{"INCR", "n"}, nil, ErrIncrOverflow.Error()},
+		{[]string{"INCRBY", "n", "-1"}, int64(9223372036854775806), ""},
+		{[]string{"INCRBY", "m", "-9223372036854775808"}, int64(-9223372036854775808), ""},
+		{[]string{"DECR", "m"}, nil, ErrIncrOverflow.Error()},
+		{[]string{"GET", "m"}, b("-9223372036854775808"), ""},
+		{[]string{"DECRBY", "m", "-9223372036854775808"}, nil, ErrDecrOverflow.Error()},
+		{[]string{"INCRBY", "n", "9223372036854775808"}, nil, ErrValNotIntOrOutOfRange.Error()},
+		{[]string{"INCRBY", "n", "1.5"}, nil, ErrValNotIntOrOutOfRange.Error()},
+		// only the canonical form is an integer
+		{[]string{"SET", "x", "+1"}, "OK", ""},
+		{[]string{"INCR", "x"}, nil, ErrValNotIntOrOutOfRange.Error()},
+		{[]string{"SET", "x", "01"}, "OK", ""},
+		{[]string{"INCR", "x"}, nil, ErrValNotIntOrOutOfRange.Error()},
+
+		// the examples of the Redis docs
+		{[]string{"SET", "f", "10.50"}, "OK", ""},
+		{[]string{"INCRBYFLOAT", "f", "0.1"}, b("10.6"), ""},
+		{[]string{"INCRBYFLOAT", "f", "-5"}, b("5.6"), ""},
+		{[]string{"SET", "f", "5.0e3"}, "OK", ""},
+		{[]string{"INCRBYFLOAT", "f", "2.0e2"}, b("5200"), ""},
+		{[]string{"GET", "f"}, b("5200"), ""},
+		{[]string{"INCRBYFLOAT", "g", "1e20"}, b("100000000000000000000"), ""},
+		{[]string{"INCRBYFLOAT", "f", "abc"}, nil, ErrValNotFloat.Error()},
+		{[]string{"INCRBYFLOAT", "f", "inf"}, nil, ErrIncrNaNOrInfinity.Error()},
+		{[]string{"INCRBYFLOAT", "x", "1"}, b("2"), ""},
+
+		{[]string{"SETNX", "nx", "a"}, 1, ""},
+		{[]string{"SETNX", "nx", "b"}, 0, ""},
+		{[]string{"GETSET", "nx", "c"}, b("a"), ""},
+		{[]string{"GETSET", "gs", "c"}, nil, ""},
+		{[]string{"GET", "gs"}, b("c"), ""},
+
+		{[]string{"SET", "s", "This is a string"}, "OK", ""},
+		{[]string{"SUBSTR", "s", "0", "3"}, b("This"), ""},
+		{[]string{"SUBSTR", "s", "-3", "-1"}, b("ing"), ""},
+		{[]string{"SUBSTR", "s", "-100", "3"}, b("This"), ""},
+		{[]string{"SUBSTR", "s", "-1", "-3"}, EMPTY, ""},
+		{[]string{"SUBSTR", "s", "10", "100"}, b("string"), ""},
+		{[]string{"SUBSTR", "s", "a", "1"}, nil, ErrValNotIntOrOutOfRange.Error()},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %v, %q want %v, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+}
+
+func Test__LCS(t *testing.T) {
+	cl := &Client{Store: store.New()}
+	cl.Execute(bA([]string{"MSET", "key1", "ohmytext", "key2", "mynewtext"}))
+	match := func(a0, a1, b0, b1 int, n ...interface{}) []interface{} {
+		return append([]interface{}{[]interface{}{a0, a1}, []interface{}{b0, b1}}, n...)
+	}
+	idx := func(n int, matches ...interface{}) resp.Map {
+		if matches == nil {
+			matches = []interface{}{}
+		}
+		return resp.Map{{Key: b("matches"), Value: matches}, {Key: b("len"), Value: n}}
+	}
+	tests := []struct {
+		input    []string
+		expected interface{}
+		err      string
+	}{
+		// the examples of the Redis docs
+		{[]string{"LCS", "key1", "key2"}, b("mytext"), ""},
+		{[]string{"LCS", "key1", "key2", "LEN"}, 6, ""},
+		{[]string{"LCS", "key1", "key2", "IDX"}, idx(6, match(4, 7, 5, 8), match(2, 3, 0, 1)), ""},
+		{[]string{"LCS", "key1", "key2", "IDX", "MINMATCHLEN", "4"}, idx(6, match(4, 7, 5, 8)), ""},
+		{[]string{"LCS", "key1", "key2", "idx", "minmatchlen", "4", "withmatchlen"}, idx(6, match(4, 7, 5, 8, 4)), ""},
+		{[]string{"LCS", "key1", "nosuch"}, b(""), ""},
+		{[]string{"LCS", "key1", "nosuch", "IDX"}, idx(0), ""},
+		{[]string{"LCS", "key1", "key2", "LEN", "IDX"}, nil, ErrLCSLenAndIdx.Error()},
+		{[]string{"LCS", "key1", "key2", "MINMATCHLEN"}, nil, ErrInvalidSyntax.Error()},
+		{[]string{"LCS", "key1", "key2", "MINMATCHLEN", "x"}, nil, ErrValNotIntOrOutOfRange.Error()},
+		{[]string{"LCS", "key1", "key2", "NOPE"}, nil, ErrInvalidSyntax.Error()},
+	}
+	for _, tt := range tests {
+		got, err := cl.Execute(bA(tt.input))
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if !reflect.DeepEqual(got, tt.expected) || gotErr != tt.err {
+			t.Errorf("Execute(%q): got %v, %q want %v, %q", tt.input, got, gotErr, tt.expected, tt.err)
+		}
+	}
+}
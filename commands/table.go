@@ -0,0 +1,695 @@
+package commands
+
+import (
+	"strings"
+)
+
+// commandInfo describes a command the way Redis 7 reports it through COMMAND
+// and COMMAND DOCS.
+//
+// arity follows the Redis convention: a positive value is the exact number of
+// arguments (including the command name), a negative value is the minimum.
+// firstKey, lastKey and step locate the key arguments; lastKey may be negative
+// to count from the end of the argument list.
+type commandInfo struct {
+	name       string
+	arity      int
+	flags      []string
+	firstKey   int
+	lastKey    int
+	step       int
+	categories []string
+	tips       []string
+
+	group      string
+	since      string
+	complexity string
+	summary    string
+	// syntax is the argument list as written in the Redis docs,
+	// see parseSyntax for the accepted grammar.
+	syntax string
+
+	subcommands []*commandInfo
+
+	// handler is set for commands added through Register; built-in commands
+	// are dispatched by ExecuteCommand.
+	handler Handler
+}
+
+var commandTable = map[string]*commandInfo{}
+
+func init() {
+	for _, c := range builtinCommands {
+		commandTable[c.name] = c
+	}
+}
+
+// lookupCommand returns the metadata for a command name (case-insensitive).
+func lookupCommand(name string) (*commandInfo, bool) {
+	c, ok := commandTable[strings.ToLower(name)]
+	return c, ok
+}
+
+// lookupSubcommand returns the metadata for a container subcommand such as
+// "command|info".
+func (c *commandInfo) lookupSubcommand(name string) (*commandInfo, bool) {
+	full := c.name + "|" + strings.ToLower(name)
+	for _, sub := range c.subcommands {
+		if sub.name == full {
+			return sub, true
+		}
+	}
+	return nil, false
+}
+
+// checkArity reports whether n arguments (including the command name) are
+// acceptable for the command.
+func (c *commandInfo) checkArity(n int) bool {
+	if c.arity >= 0 {
+		return n == c.arity
+	}
+	return n >= -c.arity
+}
+
+// getKeys returns the positions of the key arguments in args, where args[0]
+// is the command name.
+func (c *commandInfo) getKeys(args [][]byte) []int {
+	if c.firstKey == 0 {
+		return nil
+	}
+	last := c.lastKey
+	if last < 0 {
+		last = len(args) + last
+	}
+	var pos []int
+	for i := c.firstKey; i <= last && i < len(args); i += c.step {
+		pos = append(pos, i)
+	}
+	return pos
+}
+
+var (
+	flagsRead      = []string{"readonly"}
+	flagsReadFast  = []string{"readonly", "fast"}
+	flagsWrite     = []string{"write"}
+	flagsWriteFast = []string{"write", "fast"}
+	flagsWriteOOM  = []string{"write", "denyoom"}
+	flagsWriteOOMF = []string{"write", "denyoom", "fast"}
+)
+
+var builtinCommands = []*commandInfo{
+	{
+		name: "ping", arity: -1, flags: []string{"fast"},
+		categories: []string{"@fast", "@connection"},
+		group:      "connection", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the server's liveliness response.",
+		syntax:  "[message]",
+	},
+	{
+		name: "echo", arity: 2, flags: []string{"fast"},
+		categories: []string{"@fast", "@connection"},
+		group:      "connection", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the given string.",
+		syntax:  "message",
+	},
+	{
+		name: "hello", arity: -1, flags: []string{"noscript", "loading", "stale", "fast", "no_auth", "sentinel", "allow_busy"},
+		categories: []string{"@fast", "@connection"},
+		group:      "connection", since: "6.0.0", complexity: "O(1)",
+		summary: "Handshakes with the Redis server.",
+		syntax:  "[arguments(protover:integer [AUTH auth(username password)] [SETNAME clientname])]",
+	},
+	{
+		name: "auth", arity: -2, flags: []string{"noscript", "loading", "stale", "fast", "no_auth", "allow_busy"},
+		categories: []string{"@fast", "@connection"},
+		group:      "connection", since: "1.0.0", complexity: "O(N) where N is the number of passwords defined for the user",
+		summary: "Authenticates the connection.",
+		syntax:  "[username] password",
+	},
+	{
+		name: "get", arity: 2, flags: flagsReadFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the string value of a key.",
+		syntax:  "key",
+	},
+	{
+		name: "set", arity: -3, flags: flagsWriteOOM, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@slow"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+		syntax:  "key value [NX|XX] [GET]",
+	},
+	{
+		name: "del", arity: -2, flags: flagsWrite, firstKey: 1, lastKey: -1, step: 1,
+		categories: []string{"@keyspace", "@write", "@slow"},
+		group:      "generic", since: "1.0.0", complexity: "O(N) where N is the number of keys that will be removed.",
+		summary: "Deletes one or more keys.",
+		syntax:  "key...",
+	},
+	{
+		name: "getdel", arity: 2, flags: flagsWriteFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "6.2.0", complexity: "O(1)",
+		summary: "Returns the string value of a key after deleting the key.",
+		syntax:  "key",
+	},
+	{
+		name: "exists", arity: -2, flags: flagsReadFast, firstKey: 1, lastKey: -1, step: 1,
+		categories: []string{"@keyspace", "@read", "@fast"},
+		group:      "generic", since: "1.0.0", complexity: "O(N) where N is the number of keys to check.",
+		summary: "Determines whether one or more keys exist.",
+		syntax:  "key...",
+	},
+	{
+		name: "incr", arity: 2, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		syntax:  "key",
+	},
+	{
+		name: "decr", arity: 2, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		syntax:  "key",
+	},
+	{
+		name: "incrby", arity: 3, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		syntax:  "key increment:integer",
+	},
+	{
+		name: "decrby", arity: 3, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.",
+		syntax:  "key decrement:integer",
+	},
+	{
+		name: "incrbyfloat", arity: 3, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "2.6.0", complexity: "O(1)",
+		summary: "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		syntax:  "key increment:double",
+	},
+	{
+		name: "setnx", arity: 3, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Set the string value of a key only when the key doesn't exist.",
+		syntax:  "key value",
+	},
+	{
+		name: "getset", arity: 3, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the previous string value of a key after setting it to a new value.",
+		syntax:  "key value",
+	},
+	{
+		name: "lcs", arity: -3, flags: flagsRead, firstKey: 1, lastKey: 2, step: 1,
+		categories: []string{"@read", "@string", "@slow"},
+		group:      "string", since: "7.0.0", complexity: "O(N*M) where N and M are the lengths of s1 and s2, respectively",
+		summary: "Finds the longest common substring.",
+		syntax:  "key1:key key2:key [LEN] [IDX] [MINMATCHLEN min-match-len:integer] [WITHMATCHLEN]",
+	},
+	{
+		name: "append", arity: 3, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "2.0.0", complexity: "O(1).",
+		summary: "Appends a string to the value of a key. Creates the key if it doesn't exist.",
+		syntax:  "key value",
+	},
+	{
+		name: "getbit", arity: 3, flags: flagsReadFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@bitmap", "@fast"},
+		group:      "bitmap", since: "2.2.0", complexity: "O(1)",
+		summary: "Returns a bit value by offset.",
+		syntax:  "key offset:integer",
+	},
+	{
+		name: "setbit", arity: 4, flags: flagsWriteOOM, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@bitmap", "@slow"},
+		group:      "bitmap", since: "2.2.0", complexity: "O(1)",
+		summary: "Sets or clears the bit at offset of the string value. Creates the key if it doesn't exist.",
+		syntax:  "key offset:integer value:integer",
+	},
+	{
+		name: "bitcount", arity: -2, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@bitmap", "@slow"},
+		group:      "bitmap", since: "2.6.0", complexity: "O(N)",
+		summary: "Counts the number of set bits (population counting) in a string.",
+		syntax:  "key [range(start:integer end:integer [BYTE|BIT])]",
+	},
+	{
+		name: "bitpos", arity: -3, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@bitmap", "@slow"},
+		group:      "bitmap", since: "2.8.7", complexity: "O(N)",
+		summary: "Finds the first set (1) or clear (0) bit in a string.",
+		syntax:  "key bit:integer [range(start:integer [end-unit-block(end:integer [BYTE|BIT])])]",
+	},
+	{
+		name: "bitop", arity: -4, flags: flagsWriteOOM, firstKey: 2, lastKey: -1, step: 1,
+		categories: []string{"@write", "@bitmap", "@slow"},
+		group:      "bitmap", since: "2.6.0", complexity: "O(N)",
+		summary: "Performs bitwise operations on multiple strings, and stores the result.",
+		syntax:  "operation(AND|OR|XOR|NOT) destkey:key key...",
+	},
+	{
+		name: "bitfield", arity: -2, flags: flagsWriteOOM, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@bitmap", "@slow"},
+		group:      "bitmap", since: "3.2.0", complexity: "O(1) for each subcommand specified",
+		summary: "Performs arbitrary bitfield integer operations on strings.",
+		syntax:  "key [operation(GET get-block(encoding offset:integer)|[OVERFLOW overflow-block(WRAP|SAT|FAIL)] write-operation(SET set-block(encoding offset:integer value:integer)|INCRBY incrby-block(encoding offset:integer increment:integer)))...]",
+	},
+	{
+		name: "bitfield_ro", arity: -2, flags: flagsReadFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@bitmap", "@fast"},
+		group:      "bitmap", since: "6.0.0", complexity: "O(1) for each subcommand specified",
+		summary: "Performs arbitrary read-only bitfield integer operations on strings.",
+		syntax:  "key [GET encoding offset:integer...]",
+	},
+	{
+		name: "pfadd", arity: -2, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@hyperloglog", "@fast"},
+		group:      "hyperloglog", since: "2.8.9", complexity: "O(1) to add every element.",
+		summary: "Adds elements to a HyperLogLog key. Creates the key if it doesn't exist.",
+		syntax:  "key [element...]",
+	},
+	{
+		name: "pfcount", arity: -2, flags: []string{"readonly", "may_replicate"}, firstKey: 1, lastKey: -1, step: 1,
+		categories: []string{"@read", "@hyperloglog", "@slow"},
+		group:      "hyperloglog", since: "2.8.9", complexity: "O(1) with a very small average constant time when called with a single key. O(N) with N being the number of keys, and much bigger constant times, when called with multiple keys.",
+		summary: "Returns the approximated cardinality of the set(s) observed by the HyperLogLog key(s).",
+		syntax:  "key...",
+	},
+	{
+		name: "pfmerge", arity: -2, flags: flagsWriteOOM, firstKey: 1, lastKey: -1, step: 1,
+		categories: []string{"@write", "@hyperloglog", "@slow"},
+		group:      "hyperloglog", since: "2.8.9", complexity: "O(N) to merge N HyperLogLogs, but with high constant times.",
+		summary: "Merges one or more HyperLogLog values into a single key.",
+		syntax:  "destkey:key [sourcekey:key...]",
+	},
+	{
+		name: "zadd", arity: -4, flags: flagsWriteOOMF, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@sortedset", "@fast"},
+		group:      "sorted-set", since: "1.2.0", complexity: "O(log(N)) for each item added, where N is the number of elements in the sorted set.",
+		summary: "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist.",
+		syntax:  "key [condition(NX|XX)] [comparison(GT|LT)] [CH] [INCR] data(score:double member)...",
+	},
+	{
+		name: "zcard", arity: 2, flags: flagsReadFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@sortedset", "@fast"},
+		group:      "sorted-set", since: "1.2.0", complexity: "O(1)",
+		summary: "Returns the number of members in a sorted set.",
+		syntax:  "key",
+	},
+	{
+		name: "zscore", arity: 3, flags: flagsReadFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@sortedset", "@fast"},
+		group:      "sorted-set", since: "1.2.0", complexity: "O(1)",
+		summary: "Returns the score of a member in a sorted set.",
+		syntax:  "key member",
+	},
+	{
+		name: "zrem", arity: -3, flags: flagsWriteFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@sortedset", "@fast"},
+		group:      "sorted-set", since: "1.2.0", complexity: "O(M*log(N)) with N being the number of elements in the sorted set and M the number of elements to be removed.",
+		summary: "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed.",
+		syntax:  "key member...",
+	},
+	{
+		name: "zrange", arity: -4, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@sortedset", "@slow"},
+		group:      "sorted-set", since: "1.2.0", complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements returned.",
+		summary: "Returns members in a sorted set within a range of indexes.",
+		syntax:  "key start:integer stop:integer [REV] [WITHSCORES]",
+	},
+	{
+		name: "zscan", arity: -3, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@sortedset", "@slow"},
+		group:      "sorted-set", since: "2.8.0", complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+		summary: "Iterates over members and scores of a sorted set.",
+		syntax:  "key cursor:integer [MATCH pattern] [COUNT count:integer]",
+	},
+	{
+		name: "geoadd", arity: -5, flags: flagsWriteOOM, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@geo", "@slow"},
+		group:      "geo", since: "3.2.0", complexity: "O(log(N)) for each item added, where N is the number of elements in the sorted set.",
+		summary: "Adds one or more members to a geospatial index. The key is created if it doesn't exist.",
+		syntax:  "key [condition(NX|XX)] [CH] data(longitude:double latitude:double member)...",
+	},
+	{
+		name: "geodist", arity: -4, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@geo", "@slow"},
+		group:      "geo", since: "3.2.0", complexity: "O(1)",
+		summary: "Returns the distance between two members of a geospatial index.",
+		syntax:  "key member1 member2 [unit(M|KM|FT|MI)]",
+	},
+	{
+		name: "geohash", arity: -2, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@geo", "@slow"},
+		group:      "geo", since: "3.2.0", complexity: "O(1) for each member requested.",
+		summary: "Returns members from a geospatial index as geohash strings.",
+		syntax:  "key [member...]",
+	},
+	{
+		name: "geopos", arity: -2, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@geo", "@slow"},
+		group:      "geo", since: "3.2.0", complexity: "O(1) for each member requested.",
+		summary: "Returns the longitude and latitude of members from a geospatial index.",
+		syntax:  "key [member...]",
+	},
+	{
+		name: "geosearch", arity: -7, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@geo", "@slow"},
+		group:      "geo", since: "6.2.0", complexity: "O(N+log(M)) where N is the number of elements in the grid-aligned bounding box area around the shape provided as the filter and M is the number of items inside the shape",
+		summary: "Queries a geospatial index for members inside an area of a box or a circle.",
+		syntax:  "key from(FROMMEMBER member|FROMLONLAT fromlonlat(longitude:double latitude:double)) by(circle(BYRADIUS radius:double unit(M|KM|FT|MI))|box(BYBOX width:double height:double unit(M|KM|FT|MI))) [order(ASC|DESC)] [count-block(COUNT count:integer [ANY])] [WITHCOORD] [WITHDIST] [WITHHASH]",
+	},
+	{
+		name: "geosearchstore", arity: -8, flags: flagsWriteOOM, firstKey: 1, lastKey: 2, step: 1,
+		categories: []string{"@write", "@geo", "@slow"},
+		group:      "geo", since: "6.2.0", complexity: "O(N+log(M)) where N is the number of elements in the grid-aligned bounding box area around the shape provided as the filter and M is the number of items inside the shape",
+		summary: "Queries a geospatial index for members inside an area of a box or a circle, optionally stores the result.",
+		syntax:  "destination:key source:key from(FROMMEMBER member|FROMLONLAT fromlonlat(longitude:double latitude:double)) by(circle(BYRADIUS radius:double unit(M|KM|FT|MI))|box(BYBOX width:double height:double unit(M|KM|FT|MI))) [order(ASC|DESC)] [count-block(COUNT count:integer [ANY])] [STOREDIST]",
+	},
+	{
+		name: "save", arity: 1, flags: []string{"admin", "noscript", "no_async_loading", "no_multi"},
+		categories: []string{"@admin", "@slow", "@dangerous"},
+		group:      "server", since: "1.0.0", complexity: "O(N) where N is the total number of keys in all databases",
+		summary: "Synchronously saves the database(s) to disk.",
+	},
+	{
+		name: "shutdown", arity: -1, flags: []string{"admin", "noscript", "loading", "stale", "no_multi", "sentinel", "allow_busy"},
+		categories: []string{"@admin", "@slow", "@dangerous"},
+		group:      "server", since: "1.0.0", complexity: "O(N) when saving, where N is the total number of keys in all databases when saving data, otherwise O(1)",
+		summary: "Synchronously saves the database(s) to disk and shuts down the Redis server.",
+		syntax:  "[NOSAVE|SAVE]",
+	},
+	{
+		name: "strlen", arity: 2, flags: flagsReadFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@string", "@fast"},
+		group:      "string", since: "2.2.0", complexity: "O(1)",
+		summary: "Returns the length of a string value.",
+		syntax:  "key",
+	},
+	{
+		name: "getrange", arity: 4, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@string", "@slow"},
+		group:      "string", since: "2.4.0", complexity: "O(N) where N is the length of the returned string.",
+		summary: "Returns a substring of the string stored at a key.",
+		syntax:  "key start:integer end:integer",
+	},
+	{
+		name: "substr", arity: 4, flags: flagsRead, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@read", "@string", "@slow"},
+		group:      "string", since: "1.0.0", complexity: "O(N) where N is the length of the returned string.",
+		summary: "Returns a substring from a string value.",
+		syntax:  "key start:integer end:integer",
+	},
+	{
+		name: "setrange", arity: 4, flags: flagsWriteOOM, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@write", "@string", "@slow"},
+		group:      "string", since: "2.2.0", complexity: "O(1), not counting the time taken to copy the new string in place.",
+		summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.",
+		syntax:  "key offset:integer value",
+	},
+	{
+		name: "mget", arity: -2, flags: flagsReadFast, firstKey: 1, lastKey: -1, step: 1,
+		categories: []string{"@read", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(N) where N is the number of keys to retrieve.",
+		summary: "Atomically returns the string values of one or more keys.",
+		syntax:  "key...",
+	},
+	{
+		name: "mset", arity: -3, flags: flagsWriteOOM, firstKey: 1, lastKey: -1, step: 2,
+		categories: []string{"@write", "@string", "@slow"},
+		group:      "string", since: "1.0.1", complexity: "O(N) where N is the number of keys to set.",
+		summary: "Atomically creates or modifies the string values of one or more keys.",
+		syntax:  "data(key value)...",
+	},
+	{
+		name: "msetnx", arity: -3, flags: flagsWriteOOM, firstKey: 1, lastKey: -1, step: 2,
+		categories: []string{"@write", "@string", "@slow"},
+		group:      "string", since: "1.0.1", complexity: "O(N) where N is the number of keys to set.",
+		summary: "Atomically modifies the string values of one or more keys only when all keys don't exist.",
+		syntax:  "data(key value)...",
+	},
+	{
+		name: "copy", arity: -3, flags: flagsWriteOOM, firstKey: 1, lastKey: 2, step: 1,
+		categories: []string{"@keyspace", "@write", "@slow"},
+		group:      "generic", since: "6.2.0", complexity: "O(N) worst case for collections, where N is the number of nested items. O(1) for string values.",
+		summary: "Copies the value of a key to a new key.",
+		syntax:  "source:key destination:key [REPLACE]",
+	},
+	{
+		name: "keys", arity: 2, flags: flagsRead,
+		categories: []string{"@keyspace", "@read", "@slow", "@dangerous"},
+		group:      "generic", since: "1.0.0", complexity: "O(N) with N being the number of keys in the database, under the assumption that the key names in the database and the given pattern have limited length.",
+		summary: "Returns all key names that match a pattern.",
+		syntax:  "pattern",
+	},
+	{
+		name: "scan", arity: -2, flags: flagsRead,
+		categories: []string{"@keyspace", "@read", "@slow"},
+		group:      "generic", since: "2.8.0", complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+		summary: "Iterates over the key names in the database.",
+		syntax:  "cursor:integer [MATCH pattern] [COUNT count:integer] [TYPE type]",
+	},
+	{
+		name: "randomkey", arity: 1, flags: flagsRead,
+		categories: []string{"@keyspace", "@read", "@slow"},
+		group:      "generic", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns a random key name from the database.",
+	},
+	{
+		name: "type", arity: 2, flags: flagsReadFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@keyspace", "@read", "@fast"},
+		group:      "generic", since: "1.0.0", complexity: "O(1)",
+		summary: "Determines the type of value stored at a key.",
+		syntax:  "key",
+	},
+	{
+		name: "touch", arity: -2, flags: flagsReadFast, firstKey: 1, lastKey: -1, step: 1,
+		categories: []string{"@keyspace", "@read", "@fast"},
+		group:      "generic", since: "3.2.1", complexity: "O(N) where N is the number of keys that will be touched.",
+		summary: "Returns the number of existing keys out of those specified after updating the time they were last accessed.",
+		syntax:  "key...",
+	},
+	{
+		name: "unlink", arity: -2, flags: flagsWriteFast, firstKey: 1, lastKey: -1, step: 1,
+		categories: []string{"@keyspace", "@write", "@fast"},
+		group:      "generic", since: "4.0.0", complexity: "O(1) for each key removed regardless of its size. Then the command does O(N) work in a different thread in order to reclaim memory, where N is the number of allocations the deleted objects where composed of.",
+		summary: "Asynchronously deletes one or more keys.",
+		syntax:  "key...",
+	},
+	{
+		name: "rename", arity: 3, flags: flagsWrite, firstKey: 1, lastKey: 2, step: 1,
+		categories: []string{"@keyspace", "@write", "@slow"},
+		group:      "generic", since: "1.0.0", complexity: "O(1)",
+		summary: "Renames a key and overwrites the destination.",
+		syntax:  "key newkey:key",
+	},
+	{
+		name: "renamenx", arity: 3, flags: flagsWriteFast, firstKey: 1, lastKey: 2, step: 1,
+		categories: []string{"@keyspace", "@write", "@fast"},
+		group:      "generic", since: "1.0.0", complexity: "O(1)",
+		summary: "Renames a key only when the target key name doesn't exist.",
+		syntax:  "key newkey:key",
+	},
+	{
+		name: "select", arity: 2, flags: []string{"loading", "stale", "fast"},
+		categories: []string{"@fast", "@connection"},
+		group:      "connection", since: "1.0.0", complexity: "O(1)",
+		summary: "Changes the selected database.",
+		syntax:  "index:integer",
+	},
+	{
+		name: "move", arity: 3, flags: flagsWriteFast, firstKey: 1, lastKey: 1, step: 1,
+		categories: []string{"@keyspace", "@write", "@fast"},
+		group:      "generic", since: "1.0.0", complexity: "O(1)",
+		summary: "Moves a key to another database.",
+		syntax:  "key db:integer",
+	},
+	{
+		name: "swapdb", arity: 3, flags: flagsWriteFast,
+		categories: []string{"@keyspace", "@write", "@fast", "@dangerous"},
+		group:      "server", since: "4.0.0", complexity: "O(N) where N is the count of clients watching or blocking on keys from both databases.",
+		summary: "Swaps two Redis databases.",
+		syntax:  "index1:integer index2:integer",
+	},
+	{
+		name: "flushdb", arity: -1, flags: flagsWrite,
+		categories: []string{"@keyspace", "@write", "@slow", "@dangerous"},
+		group:      "server", since: "1.0.0", complexity: "O(N) where N is the number of keys in the selected database",
+		summary: "Remove all keys from the current database.",
+		syntax:  "[ASYNC|SYNC]",
+	},
+	{
+		name: "flushall", arity: -1, flags: flagsWrite,
+		categories: []string{"@keyspace", "@write", "@slow", "@dangerous"},
+		group:      "server", since: "1.0.0", complexity: "O(N) where N is the total number of keys in all databases",
+		summary: "Removes all keys from all databases.",
+		syntax:  "[ASYNC|SYNC]",
+	},
+	{
+		name: "info", arity: -1, flags: []string{"loading", "stale"},
+		categories: []string{"@slow", "@dangerous"},
+		group:      "server", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns information and statistics about the server.",
+		syntax:  "[section...]",
+	},
+	{
+		name: "dbsize", arity: 1, flags: flagsReadFast,
+		categories: []string{"@keyspace", "@read", "@fast"},
+		group:      "server", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the number of keys in the database.",
+	},
+	{
+		name: "acl", arity: -2, flags: []string{},
+		categories: []string{"@slow"},
+		group:      "server", since: "6.0.0", complexity: "Depends on subcommand.",
+		summary: "A container for Access List Control commands.",
+		subcommands: []*commandInfo{
+			{
+				name: "acl|cat", arity: -2, flags: []string{"noscript", "loading", "stale"},
+				categories: []string{"@slow"},
+				group:      "server", since: "6.0.0", complexity: "O(1) since the categories and commands are a fixed set.",
+				summary: "Lists the ACL categories, or the commands inside a category.",
+				syntax:  "[category]",
+			},
+			{
+				name: "acl|deluser", arity: -3, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "6.0.0", complexity: "O(1) amortized time considering the typical user.",
+				summary: "Deletes ACL users, and terminates their connections.",
+				syntax:  "username...",
+			},
+			{
+				name: "acl|getuser", arity: 3, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "6.0.0", complexity: "O(N). Where N is the number of password, command and pattern rules that the user has.",
+				summary: "Lists the ACL rules of a user.",
+				syntax:  "username",
+			},
+			{
+				name: "acl|list", arity: 2, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "6.0.0", complexity: "O(N). Where N is the number of configured users.",
+				summary: "Dumps the effective rules in ACL file format.",
+			},
+			{
+				name: "acl|load", arity: 2, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "6.0.0", complexity: "O(N). Where N is the number of configured users.",
+				summary: "Reloads the rules from the configured ACL file.",
+			},
+			{
+				name: "acl|log", arity: -2, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "6.0.0", complexity: "O(N) with N being the number of entries shown.",
+				summary: "Lists recent security events generated due to ACL rules.",
+				syntax:  "[count:integer|RESET]",
+			},
+			{
+				name: "acl|save", arity: 2, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "6.0.0", complexity: "O(N). Where N is the number of configured users.",
+				summary: "Saves the effective ACL rules in the configured ACL file.",
+			},
+			{
+				name: "acl|setuser", arity: -3, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "6.0.0", complexity: "O(N). Where N is the number of rules provided.",
+				summary: "Creates and modifies an ACL user and its rules.",
+				syntax:  "username [rule...]",
+			},
+			{
+				name: "acl|users", arity: 2, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "6.0.0", complexity: "O(N). Where N is the number of configured users.",
+				summary: "Lists all ACL users.",
+			},
+			{
+				name: "acl|whoami", arity: 2, flags: []string{"noscript", "loading", "stale"},
+				categories: []string{"@slow"},
+				group:      "server", since: "6.0.0", complexity: "O(1)",
+				summary: "Returns the authenticated username of the current connection.",
+			},
+		},
+	},
+	{
+		name: "config", arity: -2, flags: []string{},
+		categories: []string{"@slow"},
+		group:      "server", since: "2.0.0", complexity: "Depends on subcommand.",
+		summary: "A container for server configuration commands.",
+		subcommands: []*commandInfo{
+			{
+				name: "config|get", arity: -3, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "2.0.0", complexity: "O(N) when N is the number of configuration parameters provided",
+				summary: "Returns the effective values of configuration parameters.",
+				syntax:  "parameter:pattern...",
+			},
+			{
+				name: "config|set", arity: -4, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "2.0.0", complexity: "O(N) when N is the number of configuration parameters provided",
+				summary: "Sets configuration parameters in-flight.",
+				syntax:  "data(parameter value)...",
+			},
+			{
+				name: "config|resetstat", arity: 2, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "2.0.0", complexity: "O(1)",
+				summary: "Resets the server's statistics.",
+			},
+			{
+				name: "config|rewrite", arity: 2, flags: []string{"admin", "noscript", "loading", "stale"},
+				categories: []string{"@admin", "@slow", "@dangerous"},
+				group:      "server", since: "2.8.0", complexity: "O(1)",
+				summary: "Persists the effective configuration to file.",
+			},
+		},
+	},
+	{
+		name: "command", arity: -1, flags: []string{"loading", "stale"},
+		categories: []string{"@slow", "@connection"},
+		group:      "server", since: "2.8.13", complexity: "O(N) where N is the total number of Redis commands",
+		summary: "Returns detailed information about all commands.",
+		subcommands: []*commandInfo{
+			{
+				name: "command|count", arity: 2, flags: []string{"loading", "stale"},
+				categories: []string{"@slow", "@connection"},
+				group:      "server", since: "2.8.13", complexity: "O(1)",
+				summary: "Returns a count of commands.",
+			},
+			{
+				name: "command|info", arity: -2, flags: []string{"loading", "stale"},
+				categories: []string{"@slow", "@connection"},
+				group:      "server", since: "2.8.13", complexity: "O(N) where N is the number of commands to look up",
+				summary: "Returns information about one, multiple or all commands.",
+				syntax:  "[command-name...]",
+			},
+			{
+				name: "command|docs", arity: -2, flags: []string{"loading", "stale"},
+				categories: []string{"@slow", "@connection"},
+				group:      "server", since: "7.0.0", complexity: "O(N) where N is the number of commands to look up",
+				summary: "Returns documentary information about one, multiple or all commands.",
+				syntax:  "[command-name...]",
+			},
+			{
+				name: "command|getkeys", arity: -3, flags: []string{"loading", "stale"},
+				categories: []string{"@slow", "@connection"},
+				group:      "server", since: "2.8.13", complexity: "O(N) where N is the number of arguments to the command",
+				summary: "Extracts the key names from an arbitrary command.",
+				syntax:  "command arg...",
+			},
+		},
+	},
+}
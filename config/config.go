@@ -0,0 +1,316 @@
+// Package config implements the configuration registry of tiny-redis and
+// the redis.conf style file format used to fill it.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownParam   = errors.New("unknown option")
+	ErrImmutableParam = errors.New("can't set immutable config")
+)
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindBool
+	kindEnum
+	// kindMemory is a byte count that accepts units, e.g. 512mb
+	kindMemory
+	// kindOctal is an integer written in base 8, e.g. file permissions
+	kindOctal
+)
+
+type param struct {
+	name      string
+	kind      kind
+	def       string
+	immutable bool
+	// min and max bound kindInt, kindMemory and kindOctal values
+	min, max int64
+	// enum lists the values accepted by kindEnum
+	enum []string
+	// validate is an additional check run before a value is accepted
+	validate func(string) error
+
+	value    string
+	onChange []func(string)
+}
+
+// Registry holds every configuration parameter. Subsystems read the values
+// through the typed getters and may subscribe to changes made by CONFIG SET
+// with OnChange. It is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	params map[string]*param
+	// path is the file the configuration was loaded from, used by Rewrite
+	path string
+}
+
+// New returns a registry with every parameter set to its default value.
+func New() *Registry {
+	r := &Registry{params: make(map[string]*param)}
+	for _, p := range defaults() {
+		p := p
+		p.value = p.def
+		r.params[p.name] = &p
+	}
+	return r
+}
+
+func defaults() []param {
+	return []param{
+		{name: "bind", kind: kindString, def: "[::]", immutable: true},
+		{name: "port", kind: kindInt, def: "8001", immutable: true, min: 0, max: 65535},
+		{name: "dir", kind: kindString, def: ".", validate: validateDir},
+		{name: "databases", kind: kindInt, def: "16", immutable: true, min: 1, max: math.MaxInt32},
+		{name: "dbfilename", kind: kindString, def: "dump.trdb", validate: validateFilename},
+		{name: "proto-max-bulk-len", kind: kindMemory, def: "536870912", min: 1024 * 1024, max: math.MaxInt64},
+		{name: "client-query-buffer-limit", kind: kindMemory, def: "1073741824", min: 1024 * 1024, max: math.MaxInt64},
+		{name: "requirepass", kind: kindString, def: ""},
+		{name: "aclfile", kind: kindString, def: "", immutable: true},
+		{name: "acllog-max-len", kind: kindInt, def: "128", min: 0, max: math.MaxInt32},
+		{name: "unixsocket", kind: kindString, def: "", immutable: true},
+		{name: "unixsocketperm", kind: kindOctal, def: "0", immutable: true, min: 0, max: 0777},
+		{name: "tls-port", kind: kindInt, def: "0", immutable: true, min: 0, max: 65535},
+		{name: "tls-cert-file", kind: kindString, def: "", immutable: true},
+		{name: "tls-key-file", kind: kindString, def: "", immutable: true},
+		{name: "tls-ca-cert-file", kind: kindString, def: "", immutable: true},
+		{name: "tls-auth-clients", kind: kindEnum, def: "yes", immutable: true, enum: []string{"yes", "no", "optional"}},
+		{name: "maxmemory", kind: kindMemory, def: "0", min: 0, max: math.MaxInt64},
+		{name: "maxmemory-policy", kind: kindEnum, def: "noeviction", enum: []string{
+			"volatile-lru", "volatile-lfu", "volatile-random", "volatile-ttl",
+			"allkeys-lru", "allkeys-lfu", "allkeys-random", "noeviction",
+		}},
+		{name: "maxmemory-samples", kind: kindInt, def: "5", min: 1, max: 64},
+		{name: "lfu-log-factor", kind: kindInt, def: "10", min: 0, max: math.MaxInt32},
+		{name: "lfu-decay-time", kind: kindInt, def: "1", min: 0, max: math.MaxInt32},
+		{name: "hll-sparse-max-bytes", kind: kindMemory, def: "3000", min: 0, max: math.MaxInt32},
+	}
+}
+
+// Path returns the file the registry was loaded from, if any.
+func (r *Registry) Path() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.path
+}
+
+// Names returns the names of all parameters in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.params))
+	for name := range r.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the current value of a parameter.
+func (r *Registry) Get(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.params[strings.ToLower(name)]
+	if !ok {
+		return "", false
+	}
+	return p.value, true
+}
+
+// String returns the value of a parameter, or "" if it doesn't exist.
+func (r *Registry) String(name string) string {
+	v, _ := r.Get(name)
+	return v
+}
+
+// Int returns the value of an integer parameter.
+func (r *Registry) Int(name string) int64 {
+	n, _ := strconv.ParseInt(r.String(name), 10, 64)
+	return n
+}
+
+// Octal returns the value of an octal parameter.
+func (r *Registry) Octal(name string) int64 {
+	n, _ := strconv.ParseInt(r.String(name), 8, 64)
+	return n
+}
+
+// Bool returns the value of a yes/no parameter.
+func (r *Registry) Bool(name string) bool {
+	return r.String(name) == "yes"
+}
+
+// Set changes a parameter at runtime, the way CONFIG SET does. Immutable
+// parameters can only be set from the config file or with Init.
+func (r *Registry) Set(name, value string) error {
+	return r.set(name, value, false)
+}
+
+// Init sets a parameter regardless of whether it is mutable. It is meant for
+// startup code, e.g. command line flags overriding the config file.
+func (r *Registry) Init(name, value string) error {
+	return r.set(name, value, true)
+}
+
+func (r *Registry) set(name, value string, force bool) error {
+	r.mu.Lock()
+	p, ok := r.params[strings.ToLower(name)]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("%w '%s'", ErrUnknownParam, name)
+	}
+	if p.immutable && !force {
+		r.mu.Unlock()
+		return ErrImmutableParam
+	}
+	v, err := p.normalize(value)
+	if err != nil {
+		r.mu.Unlock()
+		return err
+	}
+	p.value = v
+	hooks := append([]func(string){}, p.onChange...)
+	r.mu.Unlock()
+	for _, fn := range hooks {
+		fn(v)
+	}
+	return nil
+}
+
+// OnChange registers fn to be called with the new value every time the
+// parameter is set.
+func (r *Registry) OnChange(name string, fn func(value string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.params[name]; ok {
+		p.onChange = append(p.onChange, fn)
+	}
+}
+
+// normalize validates value and returns the canonical form stored in the
+// registry.
+func (p *param) normalize(value string) (string, error) {
+	switch p.kind {
+	case kindInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", errors.New("argument couldn't be parsed into an integer")
+		}
+		if n < p.min || n > p.max {
+			return "", fmt.Errorf("argument must be between %d and %d inclusive", p.min, p.max)
+		}
+		value = strconv.FormatInt(n, 10)
+	case kindOctal:
+		n, err := strconv.ParseInt(value, 8, 64)
+		if err != nil {
+			return "", errors.New("argument couldn't be parsed into an octal integer")
+		}
+		if n < p.min || n > p.max {
+			return "", fmt.Errorf("argument must be between %o and %o inclusive", p.min, p.max)
+		}
+		value = strconv.FormatInt(n, 8)
+	case kindMemory:
+		n, err := ParseMemory(value)
+		if err != nil {
+			return "", err
+		}
+		if n < p.min || n > p.max {
+			return "", fmt.Errorf("argument must be between %d and %d inclusive", p.min, p.max)
+		}
+		value = strconv.FormatInt(n, 10)
+	case kindBool:
+		switch strings.ToLower(value) {
+		case "yes", "no":
+			value = strings.ToLower(value)
+		default:
+			return "", errors.New("argument must be 'yes' or 'no'")
+		}
+	case kindEnum:
+		found := false
+		for _, e := range p.enum {
+			if strings.EqualFold(e, value) {
+				value, found = e, true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(p.enum, ", "))
+		}
+	}
+	if p.validate != nil {
+		if err := p.validate(value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+// memoryUnits are the suffixes accepted by memory parameters, as in
+// redis.conf: 1k => 1000 bytes, 1kb => 1024 bytes and so on.
+var memoryUnits = []struct {
+	suffix string
+	mul    int64
+}{
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+	{"b", 1},
+}
+
+// ParseMemory parses a byte count such as "512mb" or "100".
+func ParseMemory(value string) (int64, error) {
+	s := strings.ToLower(value)
+	mul := int64(1)
+	for _, u := range memoryUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mul = strings.TrimSuffix(s, u.suffix), u.mul
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/mul {
+		return 0, errors.New("argument must be a memory value")
+	}
+	return n * mul, nil
+}
+
+func validateDir(value string) error {
+	fi, err := os.Stat(value)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", value)
+	}
+	return nil
+}
+
+func validateFilename(value string) error {
+	if value == "" || strings.ContainsRune(value, os.PathSeparator) {
+		return errors.New("dbfilename can't be a path, just a filename")
+	}
+	return nil
+}
+
+// Mutable reports whether a parameter can be changed with Set. ok is false
+// for unknown parameters.
+func (r *Registry) Mutable(name string) (mutable bool, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.params[strings.ToLower(name)]
+	if !ok {
+		return false, false
+	}
+	return !p.immutable, true
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test__SplitArgs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		err      error
+	}{
+		{"set foo bar", []string{"set", "foo", "bar"}, nil},
+		{"  set   foo\tbar  ", []string{"set", "foo", "bar"}, nil},
+		{`set "foo bar" baz`, []string{"set", "foo bar", "baz"}, nil},
+		{`set 'foo bar' baz`, []string{"set", "foo bar", "baz"}, nil},
+		{`set "a\nb\x41\"" ''`, []string{"set", "a\nbA\"", ""}, nil},
+		{`set 'it\'s'`, []string{"set", "it's"}, nil},
+		{`set "\x00"`, []string{"set", "\x00"}, nil},
+		{`set "foo`, nil, ErrUnbalancedQuotes},
+		{`set 'foo`, nil, ErrUnbalancedQuotes},
+		{`set "foo"bar`, nil, ErrUnbalancedQuotes},
+		{"", []string{}, nil},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.input)
+		if err != tt.err || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SplitArgs(%q): got %q, %v want %q, %v", tt.input, got, err, tt.expected, tt.err)
+		}
+	}
+}
+
+func Test__Registry(t *testing.T) {
+	r := New()
+	if got := r.Int("port"); got != 8001 {
+		t.Errorf("default port: got %d", got)
+	}
+	if err := r.Set("port", "6379"); err != ErrImmutableParam {
+		t.Errorf("Set(port): got %v want %v", err, ErrImmutableParam)
+	}
+	if err := r.Init("port", "70000"); err == nil {
+		t.Errorf("Init(port, 70000): expected range error")
+	}
+	if err := r.Set("nosuch", "x"); err == nil {
+		t.Errorf("Set(nosuch): expected error")
+	}
+
+	var changed string
+	r.OnChange("dbfilename", func(v string) { changed = v })
+	if err := r.Set("DBFILENAME", "other.trdb"); err != nil {
+		t.Fatalf("Set(dbfilename): %v", err)
+	}
+	if changed != "other.trdb" || r.String("dbfilename") != "other.trdb" {
+		t.Errorf("dbfilename: got %q, hook saw %q", r.String("dbfilename"), changed)
+	}
+	if err := r.Set("dbfilename", "a/b"); err == nil {
+		t.Errorf("Set(dbfilename, a/b): expected error")
+	}
+
+	if err := r.Init("unixsocketperm", "0770"); err != nil {
+		t.Fatalf("Init(unixsocketperm): %v", err)
+	}
+	if r.String("unixsocketperm") != "770" || r.Octal("unixsocketperm") != 0770 {
+		t.Errorf("unixsocketperm: got %q", r.String("unixsocketperm"))
+	}
+	for _, v := range []string{"8", "1000"} {
+		if err := r.Init("unixsocketperm", v); err == nil {
+			t.Errorf("Init(unixsocketperm, %s): expected error", v)
+		}
+	}
+}
+
+func Test__ParseMemory(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		ok       bool
+	}{
+		{"100", 100, true},
+		{"1k", 1000, true},
+		{"1KB", 1024, true},
+		{"512mb", 512 << 20, true},
+		{"2g", 2000000000, true},
+		{"10b", 10, true},
+		{"-1", 0, false},
+		{"1tb", 0, false},
+		{"mb", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseMemory(tt.input)
+		if (err == nil) != tt.ok || got != tt.expected {
+			t.Errorf("ParseMemory(%q): got %d, %v want %d", tt.input, got, err, tt.expected)
+		}
+	}
+
+	r := New()
+	if err := r.Set("proto-max-bulk-len", "2mb"); err != nil {
+		t.Fatalf("Set(proto-max-bulk-len): %v", err)
+	}
+	if got := r.String("proto-max-bulk-len"); got != "2097152" {
+		t.Errorf("proto-max-bulk-len: got %q", got)
+	}
+	if err := r.Set("proto-max-bulk-len", "1k"); err == nil {
+		t.Errorf("Set(proto-max-bulk-len, 1k): expected range error")
+	}
+}
+
+func Test__Parse(t *testing.T) {
+	r := New()
+	err := r.Parse(strings.NewReader("# comment\n\nport 6380\nBIND \"127.0.0.1\" ::1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Int("port") != 6380 || r.String("bind") != "127.0.0.1 ::1" {
+		t.Errorf("got port %d bind %q", r.Int("port"), r.String("bind"))
+	}
+	bad := []string{"nosuch 1\n", "port\n", "port abc\n", "port \"1\n"}
+	for _, src := range bad {
+		if err := New().Parse(strings.NewReader(src)); err == nil {
+			t.Errorf("Parse(%q): expected error", src)
+		}
+	}
+}
+
+func Test__Rewrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tiny-redis.conf")
+	src := "# the port\nport 6380\n\n# where to save\ndbfilename a.trdb\nunknownline stays\ndbfilename b.trdb\n"
+	if err := ioutil.WriteFile(path, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New()
+	if err := New().Rewrite(); err != ErrNoConfigFile {
+		t.Errorf("Rewrite without file: got %v", err)
+	}
+	// an unknown directive is rejected by Load, so parse the known part
+	if err := r.Parse(strings.NewReader("port 6380\ndbfilename b.trdb\n")); err != nil {
+		t.Fatal(err)
+	}
+	r.path = path
+	r.Set("dbfilename", "c.trdb")
+	r.Set("dir", dir)
+	if err := r.Rewrite(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(path)
+	want := "# the port\nport 6380\n\n# where to save\ndbfilename c.trdb\nunknownline stays\n\n# Generated by CONFIG REWRITE\ndir " + dir + "\n"
+	if string(got) != want {
+		t.Errorf("Rewrite: got\n%s\nwant\n%s", got, want)
+	}
+	fi, _ := os.Stat(path)
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Rewrite changed file mode to %v", fi.Mode())
+	}
+
+	reloaded := New()
+	if err := reloaded.Parse(strings.NewReader(strings.Replace(string(got), "unknownline stays\n", "", 1))); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.String("dbfilename") != "c.trdb" || reloaded.String("dir") != dir {
+		t.Errorf("reloaded config differs: %q %q", reloaded.String("dbfilename"), reloaded.String("dir"))
+	}
+}
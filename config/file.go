@@ -0,0 +1,194 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrNoConfigFile = errors.New("the server is running without a config file")
+
+// Load reads a redis.conf style file into the registry and remembers its
+// path for Rewrite.
+func (r *Registry) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := r.Parse(f); err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.path = abs
+	r.mu.Unlock()
+	return nil
+}
+
+// Parse reads directives from src. Every non empty line that doesn't start
+// with '#' is a parameter name followed by its arguments, which may be
+// quoted. Parameters with several arguments store them space separated.
+func (r *Registry) Parse(src io.Reader) error {
+	scanner := bufio.NewScanner(src)
+	line := 0
+	for scanner.Scan() {
+		line++
+		args, err := directive(scanner.Text())
+		if err == nil && len(args) > 0 {
+			if len(args) < 2 {
+				err = errors.New("wrong number of arguments")
+			} else {
+				err = r.Init(args[0], strings.Join(args[1:], " "))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("config: line %d: '%s': %v", line, strings.TrimSpace(scanner.Text()), err)
+		}
+	}
+	return scanner.Err()
+}
+
+// directive splits a config file line. Comments and blank lines yield no
+// arguments.
+func directive(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return nil, nil
+	}
+	args, err := SplitArgs(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 {
+		args[0] = strings.ToLower(args[0])
+	}
+	return args, nil
+}
+
+// Rewrite saves the current configuration back to the file it was loaded
+// from. Comments and unknown lines are kept as they are, the first line
+// setting a parameter is updated in place and later duplicates are dropped.
+// Parameters that differ from their default but don't appear in the file are
+// appended at the end.
+func (r *Registry) Rewrite() error {
+	path := r.Path()
+	if path == "" {
+		return ErrNoConfigFile
+	}
+	old, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	r.mu.RLock()
+	var out []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSuffix(string(old), "\n"), "\n") {
+		args, err := directive(line)
+		if err != nil || len(args) == 0 {
+			out = append(out, line)
+			continue
+		}
+		p, ok := r.params[args[0]]
+		if !ok {
+			out = append(out, line)
+			continue
+		}
+		if seen[p.name] {
+			continue
+		}
+		seen[p.name] = true
+		out = append(out, p.line())
+	}
+	var appended []string
+	for _, p := range r.params {
+		if !seen[p.name] && p.value != p.def {
+			appended = append(appended, p.line())
+		}
+	}
+	r.mu.RUnlock()
+
+	if len(appended) > 0 {
+		if len(old) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, "# Generated by CONFIG REWRITE")
+		sort.Strings(appended)
+		out = append(out, appended...)
+	}
+	content := strings.Join(out, "\n") + "\n"
+
+	dir := filepath.Dir(path)
+	f, err := ioutil.TempFile(dir, "temp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode()
+	}
+	if err = os.Chmod(f.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// line formats the parameter as a config file directive.
+func (p *param) line() string {
+	if p.kind == kindString {
+		return p.name + " " + quote(p.value)
+	}
+	return p.name + " " + p.value
+}
+
+// quote returns s as a single config file argument. Values made of several
+// space separated arguments (e.g. "bind 127.0.0.1 ::1") are kept split.
+func quote(s string) string {
+	if s == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(s, "\"'\\\r\n\t") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
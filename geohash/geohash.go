@@ -0,0 +1,218 @@
+// Package geohash implements the geohashes of the Redis geo commands:
+// positions are encoded as 52 bit integers interleaving 26 bits of
+// latitude and longitude, which are used as sorted set scores. Searches
+// look up the 9 areas of a geohash around the center and filter their
+// members by distance.
+//
+// The computations follow geohash.c and geohash_helper.c of Redis so that
+// the results are the same.
+package geohash
+
+import (
+	"math"
+)
+
+const (
+	// StepMax is the number of bits of each coordinate.
+	StepMax = 26
+
+	LonMin = -180.0
+	LonMax = 180.0
+	// LatMin and LatMax are the limits of the EPSG:900913 projection.
+	LatMin = -85.05112878
+	LatMax = 85.05112878
+
+	// earthRadius is the radius of the earth in meters used by Redis.
+	earthRadius = 6372797.560856
+	// mercatorMax is half the circumference of the earth in the
+	// projection, in meters.
+	mercatorMax = 20037726.37
+
+	alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// Range is the interval of a coordinate.
+type Range struct {
+	Min, Max float64
+}
+
+var (
+	lonRange = Range{LonMin, LonMax}
+	latRange = Range{LatMin, LatMax}
+)
+
+// Bits is a geohash of step bits per coordinate, the latitude in the even
+// bits and the longitude in the odd ones.
+type Bits struct {
+	Bits uint64
+	Step uint
+}
+
+// isZero reports whether h is an area excluded from a search.
+func (h Bits) isZero() bool {
+	return h.Bits == 0 && h.Step == 0
+}
+
+// Area is the rectangle covered by a geohash.
+type Area struct {
+	Lon, Lat Range
+}
+
+// Valid reports whether lon and lat can be encoded.
+func Valid(lon, lat float64) bool {
+	return lon >= LonMin && lon <= LonMax && lat >= LatMin && lat <= LatMax
+}
+
+func encode(lonR, latR Range, lon, lat float64, step uint) (Bits, bool) {
+	if !Valid(lon, lat) || lon < lonR.Min || lon > lonR.Max || lat < latR.Min || lat > latR.Max {
+		return Bits{}, false
+	}
+	latOffset := (lat - latR.Min) / (latR.Max - latR.Min)
+	lonOffset := (lon - lonR.Min) / (lonR.Max - lonR.Min)
+	latOffset *= float64(uint64(1) << step)
+	lonOffset *= float64(uint64(1) << step)
+	return Bits{interleave(uint32(latOffset), uint32(lonOffset)), step}, true
+}
+
+// Encode returns the 52 bit geohash of a position, ok is false when it is
+// out of the valid range.
+func Encode(lon, lat float64) (hash uint64, ok bool) {
+	h, ok := encode(lonRange, latRange, lon, lat, StepMax)
+	return h.Bits, ok
+}
+
+func decode(lonR, latR Range, h Bits) Area {
+	sep := deinterleave(h.Bits)
+	ilat, ilon := float64(uint32(sep)), float64(uint32(sep>>32))
+	cells := float64(uint64(1) << h.Step)
+	latScale, lonScale := latR.Max-latR.Min, lonR.Max-lonR.Min
+	// the float64 conversions prevent fused multiply-adds
+	return Area{
+		Lat: Range{
+			latR.Min + float64(ilat/cells*latScale),
+			latR.Min + float64((ilat+1)/cells*latScale),
+		},
+		Lon: Range{
+			lonR.Min + float64(ilon/cells*lonScale),
+			lonR.Min + float64((ilon+1)/cells*lonScale),
+		},
+	}
+}
+
+// Decode returns the center of the area of a 52 bit geohash.
+func Decode(hash uint64) (lon, lat float64) {
+	a := decode(lonRange, latRange, Bits{hash, StepMax})
+	lon = math.Max(LonMin, math.Min(LonMax, (a.Lon.Min+a.Lon.Max)/2))
+	lat = math.Max(LatMin, math.Min(LatMax, (a.Lat.Min+a.Lat.Max)/2))
+	return lon, lat
+}
+
+// String returns the standard 11 character geohash of a 52 bit geohash, as
+// replied by GEOHASH. The standard geohash covers latitudes from -90 to 90
+// and has 55 bits, the last character is always 0.
+func String(hash uint64) string {
+	lon, lat := Decode(hash)
+	h, _ := encode(lonRange, Range{-90, 90}, lon, lat, StepMax)
+	var b [11]byte
+	for i := range b {
+		idx := 0
+		if i < 10 {
+			idx = int(h.Bits>>(52-(uint(i)+1)*5)) & 0x1f
+		}
+		b[i] = alphabet[idx]
+	}
+	return string(b[:])
+}
+
+// interleave returns the bits of x in the even positions and the ones of y
+// in the odd positions.
+func interleave(x, y uint32) uint64 {
+	return spread(x) | spread(y)<<1
+}
+
+func spread(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000ffff0000ffff
+	x = (x | x<<8) & 0x00ff00ff00ff00ff
+	x = (x | x<<4) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+// deinterleave returns the even bits of v in the low 32 bits and the odd
+// ones in the high 32 bits.
+func deinterleave(v uint64) uint64 {
+	return squash(v) | squash(v>>1)<<32
+}
+
+func squash(x uint64) uint64 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x>>4) & 0x00ff00ff00ff00ff
+	x = (x | x>>8) & 0x0000ffff0000ffff
+	x = (x | x>>16) & 0x00000000ffffffff
+	return x
+}
+
+// moveX moves h by d areas along the longitude, wrapping around.
+func (h Bits) moveX(d int) Bits {
+	x := h.Bits & 0xaaaaaaaaaaaaaaaa
+	y := h.Bits & 0x5555555555555555
+	zz := uint64(0x5555555555555555) >> (64 - h.Step*2)
+	if d > 0 {
+		x = x + (zz + 1)
+	} else {
+		x = x | zz
+		x = x - (zz + 1)
+	}
+	x &= 0xaaaaaaaaaaaaaaaa >> (64 - h.Step*2)
+	return Bits{x | y, h.Step}
+}
+
+// moveY moves h by d areas along the latitude, wrapping around.
+func (h Bits) moveY(d int) Bits {
+	x := h.Bits & 0xaaaaaaaaaaaaaaaa
+	y := h.Bits & 0x5555555555555555
+	zz := uint64(0xaaaaaaaaaaaaaaaa) >> (64 - h.Step*2)
+	if d > 0 {
+		y = y + (zz + 1)
+	} else {
+		y = y | zz
+		y = y - (zz + 1)
+	}
+	y &= 0x5555555555555555 >> (64 - h.Step*2)
+	return Bits{x | y, h.Step}
+}
+
+// dr is computed at run time like the D_R of Redis, the constant
+// expression would be rounded differently.
+var pi = math.Pi
+var dr = pi / 180
+
+func degRad(deg float64) float64 {
+	return deg * dr
+}
+
+func radDeg(rad float64) float64 {
+	return rad / dr
+}
+
+func latDistance(lat1, lat2 float64) float64 {
+	return earthRadius * math.Abs(degRad(lat2)-degRad(lat1))
+}
+
+// Distance returns the distance in meters between two positions, computed
+// with the haversine formula.
+func Distance(lon1, lat1, lon2, lat2 float64) float64 {
+	v := math.Sin((degRad(lon2) - degRad(lon1)) / 2)
+	// the positions are on the same meridian
+	if v == 0 {
+		return latDistance(lat1, lat2)
+	}
+	lat1r, lat2r := degRad(lat1), degRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := float64(u*u) + float64(math.Cos(lat1r)*math.Cos(lat2r)*v*v)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,122 @@
+package geohash
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// the positions of the examples of the Redis docs
+var sicily = []struct {
+	name     string
+	lon, lat float64
+	hash     uint64
+	str      string
+	// the center of the area of the geohash
+	lonC, latC string
+}{
+	{"Palermo", 13.361389, 38.115556, 3479099956230698, "sqc8b49rny0", "13.36138933897018433", "38.11555639549629859"},
+	{"Catania", 15.087269, 37.502669, 3479447370796909, "sqdtr74hyu0", "15.08726745843887329", "37.50266842333162032"},
+	{"edge1", 12.758489, 38.788135, 3479273021651468, "sqchdm4mq20", "12.75848776102066040", "38.78813451624225195"},
+	{"edge2", 17.241510, 38.788135, 3481342659049484, "squk8m4vk20", "17.24151045083999634", "38.78813451624225195"},
+}
+
+func Test__Encode(t *testing.T) {
+	for _, tt := range sicily {
+		hash, ok := Encode(tt.lon, tt.lat)
+		if !ok || hash != tt.hash {
+			t.Errorf("Encode(%s): got %d, %v want %d", tt.name, hash, ok, tt.hash)
+		}
+		lon, lat := Decode(hash)
+		if got := strconv.FormatFloat(lon, 'f', 17, 64); got != tt.lonC {
+			t.Errorf("Decode(%s): got longitude %s want %s", tt.name, got, tt.lonC)
+		}
+		if got := strconv.FormatFloat(lat, 'f', 17, 64); got != tt.latC {
+			t.Errorf("Decode(%s): got latitude %s want %s", tt.name, got, tt.latC)
+		}
+		if got := String(hash); got != tt.str {
+			t.Errorf("String(%s): got %s want %s", tt.name, got, tt.str)
+		}
+	}
+	for _, pos := range [][2]float64{{180.1, 0}, {0, 85.06}, {-180.1, 0}, {0, -85.06}} {
+		if _, ok := Encode(pos[0], pos[1]); ok {
+			t.Errorf("Encode(%v): out of range position encoded", pos)
+		}
+	}
+}
+
+func Test__Distance(t *testing.T) {
+	palermoLon, palermoLat := Decode(sicily[0].hash)
+	cataniaLon, cataniaLat := Decode(sicily[1].hash)
+	tests := []struct {
+		lon1, lat1, lon2, lat2 float64
+		expected               string
+	}{
+		{palermoLon, palermoLat, cataniaLon, cataniaLat, "166274.1516"},
+		{15, 37, palermoLon, palermoLat, "190442.4298"},
+		{15, 37, cataniaLon, cataniaLat, "56441.2579"},
+		// the same meridian
+		{0, 0, 0, 1, "111226.3000"},
+		{0, 0, 0, 0, "0.0000"},
+	}
+	for _, tt := range tests {
+		d := Distance(tt.lon1, tt.lat1, tt.lon2, tt.lat2)
+		if got := strconv.FormatFloat(d, 'f', 4, 64); got != tt.expected {
+			t.Errorf("Distance(%v, %v, %v, %v): got %s want %s", tt.lon1, tt.lat1, tt.lon2, tt.lat2, got, tt.expected)
+		}
+	}
+}
+
+func Test__Neighbors(t *testing.T) {
+	// a 2 bit geohash of latitude bit 1 and longitude bit 0
+	n := Bits{0x1, 1}.neighbors()
+	want := [9]Bits{{0x1, 1}, {0x0, 1}, {0x0, 1}, {0x3, 1}, {0x3, 1}, {0x2, 1}, {0x2, 1}, {0x2, 1}, {0x2, 1}}
+	if n != want {
+		t.Errorf("neighbors: got %v want %v", n, want)
+	}
+}
+
+func Test__Areas(t *testing.T) {
+	tests := []struct {
+		name     string
+		shape    Shape
+		expected []Bits
+	}{
+		{"radius", Shape{Lon: 15, Lat: 37, Radius: 200, Conversion: 1000},
+			[]Bits{{3161, 6}, {3164, 6}, {3163, 6}, {3166, 6}}},
+		{"box", Shape{Lon: 15, Lat: 37, Box: true, Width: 400, Height: 400, Conversion: 1000},
+			[]Bits{{3161, 6}, {3164, 6}, {3163, 6}, {3166, 6}}},
+		{"whole earth", Shape{Radius: 30000, Conversion: 1000},
+			[]Bits{{3, 1}, {2, 1}, {1, 1}, {0, 1}}},
+	}
+	for _, tt := range tests {
+		if got := tt.shape.Areas(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: got %v want %v", tt.name, got, tt.expected)
+		}
+	}
+	min, max := Bits{3161, 6}.Scores()
+	if min != 3161<<40 || max != 3162<<40 {
+		t.Errorf("Scores: got %d, %d", min, max)
+	}
+}
+
+func Test__Contains(t *testing.T) {
+	palermo := sicily[0]
+	lon, lat := Decode(palermo.hash)
+	tests := []struct {
+		shape Shape
+		ok    bool
+	}{
+		{Shape{Lon: 15, Lat: 37, Radius: 190.4425, Conversion: 1000}, true},
+		{Shape{Lon: 15, Lat: 37, Radius: 190.4424, Conversion: 1000}, false},
+		{Shape{Lon: 15, Lat: 37, Box: true, Width: 400, Height: 400, Conversion: 1000}, true},
+		// Palermo is 124 km north of the center
+		{Shape{Lon: 15, Lat: 37, Box: true, Width: 400, Height: 240, Conversion: 1000}, false},
+		{Shape{Lon: 15, Lat: 37, Box: true, Width: 280, Height: 400, Conversion: 1000}, false},
+	}
+	for _, tt := range tests {
+		if _, ok := tt.shape.Contains(lon, lat); ok != tt.ok {
+			t.Errorf("%+v: got %v", tt.shape, ok)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package geohash
+
+import "math"
+
+// Shape is the area of a search around a center: a circle of Radius, or a
+// box of Width by Height when Box is set. The sizes are in a unit of
+// Conversion meters.
+type Shape struct {
+	Lon, Lat      float64
+	Box           bool
+	Radius        float64
+	Width, Height float64
+	Conversion    float64
+}
+
+// Contains reports whether a position is in s and returns its distance to
+// the center in meters.
+func (s *Shape) Contains(lon, lat float64) (dist float64, ok bool) {
+	if !s.Box {
+		dist = Distance(s.Lon, s.Lat, lon, lat)
+		return dist, dist <= s.Radius*s.Conversion
+	}
+	// the latitude distance is cheaper, it is checked first
+	if latDistance(lat, s.Lat) > s.Height*s.Conversion/2 {
+		return 0, false
+	}
+	if Distance(lon, lat, s.Lon, lat) > s.Width*s.Conversion/2 {
+		return 0, false
+	}
+	return Distance(s.Lon, s.Lat, lon, lat), true
+}
+
+// boundingBox returns the minimum and maximum longitudes and latitudes of
+// s.
+func (s *Shape) boundingBox() (minLon, minLat, maxLon, maxLat float64) {
+	height, width := s.Conversion*s.Radius, s.Conversion*s.Radius
+	if s.Box {
+		height, width = s.Conversion*(s.Height/2), s.Conversion*(s.Width/2)
+	}
+	latDelta := radDeg(height / earthRadius)
+	lonDeltaTop := radDeg(width / earthRadius / math.Cos(degRad(s.Lat+latDelta)))
+	lonDeltaBottom := radDeg(width / earthRadius / math.Cos(degRad(s.Lat-latDelta)))
+	// the widest side is at the top in the southern hemisphere
+	if s.Lat < 0 {
+		return s.Lon - lonDeltaBottom, s.Lat - latDelta, s.Lon + lonDeltaBottom, s.Lat + latDelta
+	}
+	return s.Lon - lonDeltaTop, s.Lat - latDelta, s.Lon + lonDeltaTop, s.Lat + latDelta
+}
+
+// estimateSteps returns the precision of the geohashes whose areas are
+// about the size of a search of radius meters at latitude lat.
+func estimateSteps(radius, lat float64) uint {
+	if radius == 0 {
+		return StepMax
+	}
+	step := 1
+	for radius < mercatorMax {
+		radius *= 2
+		step++
+	}
+	// makes sure the radius is included in most cases
+	step -= 2
+	// the areas are narrower towards the poles
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	if step < 1 {
+		step = 1
+	}
+	if step > StepMax {
+		step = StepMax
+	}
+	return uint(step)
+}
+
+// neighbors returns h and the 8 geohashes around it, in the order Redis
+// searches them: center, north, south, east, west, north east, north
+// west, south east and south west.
+func (h Bits) neighbors() [9]Bits {
+	return [9]Bits{
+		h,
+		h.moveY(1),
+		h.moveY(-1),
+		h.moveX(1),
+		h.moveX(-1),
+		h.moveX(1).moveY(1),
+		h.moveX(-1).moveY(1),
+		h.moveX(1).moveY(-1),
+		h.moveX(-1).moveY(-1),
+	}
+}
+
+// Areas returns the geohashes whose areas cover s, in the order Redis
+// searches them.
+func (s *Shape) Areas() []Bits {
+	minLon, minLat, maxLon, maxLat := s.boundingBox()
+	radius := s.Radius
+	if s.Box {
+		radius = math.Sqrt(float64((s.Width/2)*(s.Width/2)) + float64((s.Height/2)*(s.Height/2)))
+	}
+	radius *= s.Conversion
+
+	steps := estimateSteps(radius, s.Lat)
+	h, _ := encode(lonRange, latRange, s.Lon, s.Lat, steps)
+	n := h.neighbors()
+	// the areas next to the center may be too close to it to cover the
+	// search, the precision is lowered then
+	north, south := decode(lonRange, latRange, n[1]), decode(lonRange, latRange, n[2])
+	east, west := decode(lonRange, latRange, n[3]), decode(lonRange, latRange, n[4])
+	if steps > 1 && (north.Lat.Max < maxLat || south.Lat.Min > minLat ||
+		east.Lon.Max < maxLon || west.Lon.Min > minLon) {
+		steps--
+		h, _ = encode(lonRange, latRange, s.Lon, s.Lat, steps)
+		n = h.neighbors()
+	}
+	// excludes the areas that are useless
+	if steps >= 2 {
+		a := decode(lonRange, latRange, h)
+		if a.Lat.Min < minLat {
+			n[2], n[8], n[7] = Bits{}, Bits{}, Bits{}
+		}
+		if a.Lat.Max > maxLat {
+			n[1], n[5], n[6] = Bits{}, Bits{}, Bits{}
+		}
+		if a.Lon.Min < minLon {
+			n[4], n[8], n[6] = Bits{}, Bits{}, Bits{}
+		}
+		if a.Lon.Max > maxLon {
+			n[3], n[7], n[5] = Bits{}, Bits{}, Bits{}
+		}
+	}
+	var areas []Bits
+	last := 0
+	for i, b := range n {
+		if b.isZero() {
+			continue
+		}
+		// the neighbors of a large area may be the same one, like Redis
+		// only the previous area is compared and never the center
+		if last != 0 && b == n[last] {
+			continue
+		}
+		areas = append(areas, b)
+		last = i
+	}
+	return areas
+}
+
+// Scores returns the range of the 52 bit geohashes in the area of h, from
+// min included to max excluded.
+func (h Bits) Scores() (min, max uint64) {
+	shift := 52 - h.Step*2
+	return h.Bits << shift, (h.Bits + 1) << shift
+}
@@ -0,0 +1,135 @@
+// Package glob implements the glob-style pattern matching used by Redis for
+// KEYS, SCAN MATCH, CONFIG GET and ACL patterns.
+package glob
+
+// Match reports whether str matches pattern. Supported are:
+//
+//   - * matches any sequence of bytes, including the empty one
+//   - ? matches a single byte
+//   - [abc] matches one of the listed bytes, ranges ([a-z]) and negation
+//     ([^a]) work too
+//   - \x matches x literally
+func Match(pattern, str []byte, nocase bool) bool {
+	var skipLonger bool
+	return match(pattern, str, nocase, &skipLonger, 0)
+}
+
+// MatchString is like Match for strings.
+func MatchString(pattern, str string, nocase bool) bool {
+	return Match([]byte(pattern), []byte(str), nocase)
+}
+
+// maxNesting bounds the recursion on '*' so that a pathological pattern
+// can't blow the stack.
+const maxNesting = 1000
+
+// match is the matcher of Redis' stringmatchlen. skipLonger is set once a
+// '*' tried every suffix of the string in vain: the '*' before it then
+// fails too, since giving it more bytes leaves fewer to the rest of the
+// pattern. Without that, each '*' retries every suffix and matching takes
+// exponential time (CVE-2022-36021).
+func match(p, s []byte, nocase bool, skipLonger *bool, nesting int) bool {
+	if nesting > maxNesting {
+		return false
+	}
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for len(p) > 1 && p[1] == '*' {
+				p = p[1:]
+			}
+			if len(p) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if match(p[1:], s[i:], nocase, skipLonger, nesting+1) {
+					return true
+				}
+				if *skipLonger {
+					return false
+				}
+			}
+			*skipLonger = true
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			p = p[1:]
+			not := len(p) > 0 && p[0] == '^'
+			if not {
+				p = p[1:]
+			}
+			matched := false
+			for len(p) > 0 && p[0] != ']' {
+				switch {
+				case p[0] == '\\' && len(p) >= 2:
+					p = p[1:]
+					if p[0] == s[0] {
+						matched = true
+					}
+				case len(p) >= 3 && p[1] == '-':
+					start, end := p[0], p[2]
+					if start > end {
+						start, end = end, start
+					}
+					c := s[0]
+					if nocase {
+						start, end, c = lower(start), lower(end), lower(c)
+					}
+					p = p[2:]
+					if c >= start && c <= end {
+						matched = true
+					}
+				default:
+					if equal(p[0], s[0], nocase) {
+						matched = true
+					}
+				}
+				p = p[1:]
+			}
+			if not {
+				matched = !matched
+			}
+			if !matched {
+				return false
+			}
+			s = s[1:]
+			if len(p) == 0 {
+				// unterminated class, the pattern is exhausted
+				return len(s) == 0
+			}
+		case '\\':
+			if len(p) >= 2 {
+				p = p[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || !equal(p[0], s[0], nocase) {
+				return false
+			}
+			s = s[1:]
+		}
+		p = p[1:]
+	}
+	return len(s) == 0
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func equal(a, b byte, nocase bool) bool {
+	if nocase {
+		return lower(a) == lower(b)
+	}
+	return a == b
+}
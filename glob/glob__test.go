@@ -0,0 +1,67 @@
+package glob
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test__Match(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		str      string
+		nocase   bool
+		expected bool
+	}{
+		{"*", "", false, true},
+		{"*", "anything", false, true},
+		{"h?llo", "hello", false, true},
+		{"h?llo", "hllo", false, false},
+		{"h*llo", "heeeello", false, true},
+		{"h*llo", "hllo", false, true},
+		{"h[ae]llo", "hallo", false, true},
+		{"h[ae]llo", "hillo", false, false},
+		{"h[^e]llo", "hallo", false, true},
+		{"h[^e]llo", "hello", false, false},
+		{"h[a-b]llo", "hbllo", false, true},
+		{"h[b-a]llo", "hbllo", false, true},
+		{"h[a-b]llo", "hcllo", false, false},
+		{"h\\*llo", "h*llo", false, true},
+		{"h\\*llo", "hello", false, false},
+		{"HELLO", "hello", true, true},
+		{"HELLO", "hello", false, false},
+		{"max*", "maxmemory", false, true},
+		{"*-port", "tls-port", false, true},
+		{"a*b*c", "aXbYbZc", false, true},
+		{"a*b*c", "aXbYbZ", false, false},
+		{"*a*b", "xaxxab", false, true},
+		{"*a*b", "xaxxba", false, false},
+	}
+	for _, tt := range tests {
+		got := MatchString(tt.pattern, tt.str, tt.nocase)
+		if got != tt.expected {
+			t.Errorf("Match(%q, %q, %v): got %v want %v", tt.pattern, tt.str, tt.nocase, got, tt.expected)
+		}
+	}
+}
+
+func Test__MatchPathological(t *testing.T) {
+	// each '*' retrying every suffix takes exponential time on these
+	tests := []struct {
+		pattern string
+		str     string
+	}{
+		{"*a*a*a*a*a*a*a*a*a*a*a*ab", strings.Repeat("a", 60)},
+		{strings.Repeat("*a", 100) + "b", strings.Repeat("a", 1000)},
+		{strings.Repeat("?*", 50) + "b", strings.Repeat("a", 1000)},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		if MatchString(tt.pattern, tt.str, false) {
+			t.Errorf("Match(%q, %q): got true", tt.pattern, tt.str)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("Match(%q, %q): took %v", tt.pattern, tt.str, d)
+		}
+	}
+}
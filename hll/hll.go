@@ -0,0 +1,354 @@
+// Package hll implements the HyperLogLog of Redis, with the same string
+// layout so that values can be moved between tiny-redis and Redis.
+//
+// A value starts with a 16 byte header: the "HYLL" magic, the encoding, 3
+// unused bytes and the cached cardinality as a little endian uint64 whose
+// most significant bit marks it invalid. The 16384 registers of 6 bits
+// follow, either packed (dense) or run length encoded (sparse).
+package hll
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	// P is the number of bits of the hash that select a register.
+	P = 14
+	// Registers is the number of registers.
+	Registers = 1 << P
+	// q is the number of bits of the hash that the registers count zeros in.
+	q        = 64 - P
+	regBits  = 6
+	regMax   = 1<<regBits - 1
+	hdrSize  = 16
+	denseLen = hdrSize + (Registers*regBits+7)/8
+
+	encDense  = 0
+	encSparse = 1
+
+	// the opcodes of the sparse encoding:
+	//	00xxxxxx           ZERO: xxxxxx+1 registers set to 0
+	//	01xxxxxx yyyyyyyy  XZERO: xxxxxxyyyyyyyy+1 registers set to 0
+	//	1vvvvvxx           VAL: xx+1 registers set to vvvvv+1
+	sparseZeroMax  = 64
+	sparseXZeroMax = 16384
+	sparseValMax   = 32
+	sparseValLen   = 4
+
+	// alphaInf is 0.5/ln(2), the constant of the estimator
+	alphaInf = 0.721347520444481703680
+)
+
+var magic = []byte("HYLL")
+
+var (
+	// ErrInvalid is returned for values that aren't HyperLogLogs.
+	ErrInvalid = errors.New("hll: not a HyperLogLog")
+	// ErrCorrupt is returned for sparse HyperLogLogs whose opcodes don't
+	// describe exactly Registers registers.
+	ErrCorrupt = errors.New("hll: corrupted HyperLogLog")
+)
+
+// Sketch is a decoded HyperLogLog.
+type Sketch struct {
+	regs [Registers]uint8
+	// dense is set once the sketch uses the dense encoding, it never goes
+	// back to the sparse one
+	dense bool
+}
+
+// New returns an empty sketch, which is encoded as sparse.
+func New() *Sketch {
+	return new(Sketch)
+}
+
+// check returns ErrInvalid when b doesn't have the header of a HyperLogLog
+// or is a dense one of the wrong size.
+func check(b []byte) error {
+	if len(b) < hdrSize || !bytes.Equal(b[:4], magic) || b[4] > encSparse {
+		return ErrInvalid
+	}
+	if b[4] == encDense && len(b) != denseLen {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// Parse decodes b.
+func Parse(b []byte) (*Sketch, error) {
+	if err := check(b); err != nil {
+		return nil, err
+	}
+	s := new(Sketch)
+	if b[4] == encDense {
+		s.dense = true
+		regs := b[hdrSize:]
+		for i := range s.regs {
+			s.regs[i] = denseGet(regs, i)
+		}
+		return s, nil
+	}
+	i := 0
+	for p := hdrSize; p < len(b); p++ {
+		op := b[p]
+		var n int
+		var v uint8
+		switch {
+		case op&0xc0 == 0x00:
+			n = int(op&0x3f) + 1
+		case op&0xc0 == 0x40:
+			if p+1 >= len(b) {
+				return nil, ErrCorrupt
+			}
+			n = int(op&0x3f)<<8 | int(b[p+1]) + 1
+			p++
+		default:
+			n = int(op&0x3) + 1
+			v = (op>>2)&0x1f + 1
+		}
+		if i+n > Registers {
+			return nil, ErrCorrupt
+		}
+		for ; n > 0; n-- {
+			s.regs[i] = v
+			i++
+		}
+	}
+	if i != Registers {
+		return nil, ErrCorrupt
+	}
+	return s, nil
+}
+
+// denseGet returns register i of the packed registers, the least
+// significant bits first.
+func denseGet(regs []byte, i int) uint8 {
+	byt, fb := i*regBits/8, uint(i*regBits&7)
+	v := uint(regs[byt]) >> fb
+	if byt+1 < len(regs) {
+		v |= uint(regs[byt+1]) << (8 - fb)
+	}
+	return uint8(v & regMax)
+}
+
+func denseSet(regs []byte, i int, v uint8) {
+	byt, fb := i*regBits/8, uint(i*regBits&7)
+	regs[byt] &^= regMax << fb
+	regs[byt] |= v << fb
+	if byt+1 < len(regs) {
+		regs[byt+1] &^= regMax >> (8 - fb)
+		regs[byt+1] |= v >> (8 - fb)
+	}
+}
+
+// position returns the register elem is counted in and the value it
+// proposes for it: the number of trailing zeros of the rest of its hash,
+// plus one.
+func position(elem []byte) (int, uint8) {
+	h := murmurHash64A(elem, 0xadc83b19)
+	i := int(h & (Registers - 1))
+	h >>= P
+	// bounds the count to q+1
+	h |= 1 << q
+	count := uint8(1)
+	for bit := uint64(1); h&bit == 0; bit <<= 1 {
+		count++
+	}
+	return i, count
+}
+
+// Add counts elem and reports whether a register changed.
+func (s *Sketch) Add(elem []byte) bool {
+	i, count := position(elem)
+	if count > s.regs[i] {
+		s.regs[i] = count
+		return true
+	}
+	return false
+}
+
+// Merge sets every register to the maximum of s and o, so that s counts the
+// union of both sets. s becomes dense when o is.
+func (s *Sketch) Merge(o *Sketch) {
+	for i, v := range o.regs {
+		if v > s.regs[i] {
+			s.regs[i] = v
+		}
+	}
+	s.dense = s.dense || o.dense
+}
+
+// Register returns the value of register i.
+func (s *Sketch) Register(i int) uint8 {
+	return s.regs[i]
+}
+
+// Count returns the estimated cardinality, computed with the estimator of
+// Otmar Ertl like Redis does.
+func (s *Sketch) Count() uint64 {
+	var histo [64]int
+	for _, v := range s.regs {
+		histo[v]++
+	}
+	m := float64(Registers)
+	z := m * tau((m-float64(histo[q+1]))/m)
+	for j := q; j >= 1; j-- {
+		z += float64(histo[j])
+		z *= 0.5
+	}
+	z += m * sigma(float64(histo[0])/m)
+	return uint64(math.Round(alphaInf * m * m / z))
+}
+
+// The float64 conversions below prevent fused multiply-adds, so that the
+// results are rounded like the ones of Redis on every architecture.
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= float64(math.Pow(1-x, 2) * y)
+		if prev == z {
+			return z / 3
+		}
+	}
+}
+
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += float64(x * y)
+		y += y
+		if prev == z {
+			return z
+		}
+	}
+}
+
+// Bytes encodes s with an invalid cached cardinality. s is encoded as
+// sparse unless it is already dense, has a register over the largest
+// value of the sparse encoding or would take more than sparseMax bytes.
+// The sparse encoding of an empty sketch is always used.
+func (s *Sketch) Bytes(sparseMax int) []byte {
+	if !s.dense {
+		if b := s.sparse(); b != nil && (len(b) <= sparseMax || len(b) == hdrSize+2) {
+			return b
+		}
+		s.dense = true
+	}
+	b := make([]byte, denseLen)
+	copy(b, magic)
+	b[4] = encDense
+	invalidate(b)
+	regs := b[hdrSize:]
+	for i, v := range s.regs {
+		denseSet(regs, i, v)
+	}
+	return b
+}
+
+// sparse returns the sparse encoding of s, nil if a register doesn't fit.
+func (s *Sketch) sparse() []byte {
+	b := make([]byte, hdrSize, hdrSize+64)
+	copy(b, magic)
+	b[4] = encSparse
+	for i := 0; i < Registers; {
+		v := s.regs[i]
+		n := 1
+		for i+n < Registers && s.regs[i+n] == v {
+			n++
+		}
+		i += n
+		switch {
+		case v > sparseValMax:
+			return nil
+		case v == 0:
+			for ; n > 0; n -= sparseXZeroMax {
+				run := n
+				if run > sparseXZeroMax {
+					run = sparseXZeroMax
+				}
+				if run > sparseZeroMax {
+					b = append(b, 0x40|byte((run-1)>>8), byte(run-1))
+				} else {
+					b = append(b, byte(run-1))
+				}
+			}
+		default:
+			for ; n > 0; n -= sparseValLen {
+				run := n
+				if run > sparseValLen {
+					run = sparseValLen
+				}
+				b = append(b, 0x80|(v-1)<<2|byte(run-1))
+			}
+		}
+	}
+	// the cached cardinality of an empty sketch is valid
+	if len(b) > hdrSize+2 {
+		invalidate(b)
+	}
+	return b
+}
+
+func invalidate(b []byte) {
+	b[15] |= 1 << 7
+}
+
+// Cached returns the cardinality cached in the header of b, ok is false when
+// it has to be computed.
+func Cached(b []byte) (n uint64, ok bool, err error) {
+	if err := check(b); err != nil {
+		return 0, false, err
+	}
+	if b[15]&(1<<7) != 0 {
+		return 0, false, nil
+	}
+	return binary.LittleEndian.Uint64(b[8:16]), true, nil
+}
+
+// SetCached returns a copy of b with n as its cached cardinality.
+func SetCached(b []byte, n uint64) []byte {
+	r := append([]byte(nil), b...)
+	binary.LittleEndian.PutUint64(r[8:16], n)
+	return r
+}
+
+// murmurHash64A is the endian neutral MurmurHash64A used by Redis.
+func murmurHash64A(key []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ uint64(len(key))*m
+	for len(key) >= 8 {
+		k := binary.LittleEndian.Uint64(key)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+		key = key[8:]
+	}
+	if len(key) > 0 {
+		for i := len(key) - 1; i >= 0; i-- {
+			h ^= uint64(key[i]) << (8 * uint(i))
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
@@ -0,0 +1,114 @@
+package hll
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func Test__murmurHash64A(t *testing.T) {
+	// computed with the C implementation of Redis
+	tests := []struct {
+		input    string
+		expected uint64
+	}{
+		{"", 15627466953755236146},
+		{"a", 6039968161137406375},
+		{"foo", 16592960565925911732},
+		{"hello world", 12184977182547125431},
+		{"0123456789abcdef!", 13337350489090520692},
+		{"ele:12345", 17655910721786995200},
+	}
+	for _, tt := range tests {
+		if got := murmurHash64A([]byte(tt.input), 0xadc83b19); got != tt.expected {
+			t.Errorf("murmurHash64A(%q): got %d want %d", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func Test__Count(t *testing.T) {
+	tests := []int{0, 1, 7, 100, 1000, 10000, 100000}
+	for _, n := range tests {
+		s := New()
+		for i := 0; i < n; i++ {
+			s.Add([]byte("ele:" + strconv.Itoa(i)))
+		}
+		got := float64(s.Count())
+		// the standard error is 0.81%
+		if got < float64(n)*0.97 || got > float64(n)*1.03 {
+			t.Errorf("Count after %d elements: got %v", n, got)
+		}
+	}
+}
+
+func Test__Encoding(t *testing.T) {
+	empty := New().Bytes(3000)
+	if want := []byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff"); !bytes.Equal(empty, want) {
+		t.Errorf("empty sketch: got %q want %q", empty, want)
+	}
+	if n, ok, err := Cached(empty); n != 0 || !ok || err != nil {
+		t.Errorf("Cached(empty): got %d, %v, %v", n, ok, err)
+	}
+
+	s := New()
+	for i := 0; i < 100; i++ {
+		s.Add([]byte(strconv.Itoa(i)))
+	}
+	// a register over the largest value of the sparse encoding
+	big := New()
+	big.regs[Registers-1] = 40
+	tests := []struct {
+		name      string
+		s         *Sketch
+		sparseMax int
+		dense     bool
+	}{
+		{"sparse", s, 3000, false},
+		{"over hll-sparse-max-bytes", s, 100, true},
+		{"value over 32", big, 3000, true},
+	}
+	for _, tt := range tests {
+		b := tt.s.Bytes(tt.sparseMax)
+		if dense := b[4] == encDense; dense != tt.dense {
+			t.Errorf("%s: got dense %v with %d bytes", tt.name, dense, len(b))
+		}
+		if _, ok, _ := Cached(b); ok {
+			t.Errorf("%s: the cached cardinality is valid", tt.name)
+		}
+		got, err := Parse(b)
+		if err != nil || got.regs != tt.s.regs || got.dense != tt.dense {
+			t.Errorf("%s: Parse got %v", tt.name, err)
+		}
+	}
+	// once dense, a sketch stays dense
+	if b := s.Bytes(3000); b[4] != encDense {
+		t.Errorf("the sketch went back to the sparse encoding")
+	}
+
+	b := SetCached(empty, 42)
+	if n, ok, err := Cached(b); n != 42 || !ok || err != nil {
+		t.Errorf("Cached after SetCached: got %d, %v, %v", n, ok, err)
+	}
+}
+
+func Test__ParseErrors(t *testing.T) {
+	empty := New().Bytes(3000)
+	tests := []struct {
+		name     string
+		input    []byte
+		expected error
+	}{
+		{"short", []byte("HYLL\x01"), ErrInvalid},
+		{"magic", append([]byte("HYLX"), empty[4:]...), ErrInvalid},
+		{"encoding", append([]byte("HYLL\x02"), empty[5:]...), ErrInvalid},
+		{"dense size", append([]byte("HYLL\x00"), empty[5:]...), ErrInvalid},
+		{"additional opcode", append(empty[:len(empty):len(empty)], 0x00), ErrCorrupt},
+		{"missing registers", append(empty[:hdrSize:hdrSize], 0x7f, 0xfe), ErrCorrupt},
+		{"truncated opcode", empty[:hdrSize+1], ErrCorrupt},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.input); err != tt.expected {
+			t.Errorf("%s: got %v want %v", tt.name, err, tt.expected)
+		}
+	}
+}
@@ -4,7 +4,9 @@
 package resp
 
 import (
+	"bytes"
 	"errors"
+	"math"
 	"math/big"
 	"strconv"
 )
@@ -12,6 +14,19 @@ import (
 var (
 	ErrInvalidSyntax = errors.New("ERR invalid syntax")
 	ErrInvalidInput  = errors.New("ERR invalid input")
+	// ErrIncomplete is returned by Parse when input ends in the middle of a
+	// value. More data has to be read before parsing again.
+	ErrIncomplete             = errors.New("incomplete RESP value")
+	ErrInvalidBulkLength      = errors.New("ERR Protocol error: invalid bulk length")
+	ErrInvalidMultibulkLength = errors.New("ERR Protocol error: invalid multibulk length")
+)
+
+const (
+	// DefaultMaxBulkLen is the bulk string length limit of a zero Parser,
+	// the default proto-max-bulk-len of Redis.
+	DefaultMaxBulkLen = 512 * 1024 * 1024
+	// DefaultMaxMultibulkLen is the aggregate length limit of a zero Parser.
+	DefaultMaxMultibulkLen = math.MaxInt32
 )
 
 const (
@@ -26,10 +41,85 @@ const (
 	VERBATIM_STRING = '='
 	ARRAY           = '*'
 	SET             = '~'
+	MAP             = '%'
+	PUSH            = '>'
+	ATTRIBUTE       = '|'
 	NULL            = '_'
+	// CHUNK prefixes the parts of a streamed string
+	CHUNK = ';'
+	// END terminates a streamed aggregate
+	END = '.'
 )
 
+// streamed is the length of strings and aggregates sent as "$?" or "*?"
+const streamed = -2
+
+// Parser decodes RESP values. The zero value is ready to use.
+type Parser struct {
+	// MaxBulkLen limits the length of bulk strings, DefaultMaxBulkLen if 0
+	MaxBulkLen int64
+	// MaxMultibulkLen limits the number of items of aggregates,
+	// DefaultMaxMultibulkLen if 0
+	MaxMultibulkLen int64
+}
+
+// Decode decodes the first value of input with a zero Parser. read is the
+// number of bytes used, not counting the type byte. Decode panics if input
+// is malformed or incomplete; use Parse to get these as errors.
 func Decode(input []byte) (decodedValue interface{}, read int) {
+	var p Parser
+	return p.decode(input)
+}
+
+// Parse decodes the first value of input with a zero Parser.
+func Parse(input []byte) (v interface{}, n int, err error) {
+	var p Parser
+	return p.Parse(input)
+}
+
+// Parse decodes the first value of input and returns it along with the number
+// of bytes it took. It returns ErrIncomplete if input holds only part of a
+// value, and a protocol error such as ErrInvalidBulkLength if it is invalid.
+func (p *Parser) Parse(input []byte) (v interface{}, n int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok || !isParseError(e) {
+				panic(r)
+			}
+			v, n, err = nil, 0, e
+		}
+	}()
+	v, read := p.decode(input)
+	return v, read + 1, nil
+}
+
+func isParseError(err error) bool {
+	switch err {
+	case ErrIncomplete, ErrInvalidBulkLength, ErrInvalidMultibulkLength, ErrInvalidInput, ErrInvalidSyntax:
+		return true
+	}
+	return false
+}
+
+func (p *Parser) maxBulkLen() int64 {
+	if p.MaxBulkLen <= 0 {
+		return DefaultMaxBulkLen
+	}
+	return p.MaxBulkLen
+}
+
+func (p *Parser) maxMultibulkLen() int64 {
+	if p.MaxMultibulkLen <= 0 {
+		return DefaultMaxMultibulkLen
+	}
+	return p.MaxMultibulkLen
+}
+
+func (p *Parser) decode(input []byte) (decodedValue interface{}, read int) {
+	if len(input) == 0 {
+		panic(ErrIncomplete)
+	}
 	switch first_byte := input[0]; first_byte {
 	case SIMPLE_STRING:
 		return handleSimpleString(input[1:])
@@ -43,35 +133,37 @@ func Decode(input []byte) (decodedValue interface{}, read int) {
 		return handleBigInt(input[1:])
 	case BOOLEAN:
 		return handleBoolean(input[1:])
-	case BULK_STRING, VERBATIM_STRING:
-		return handleBulkString(input[1:])
+	case BULK_STRING:
+		return p.handleBulkString(input[1:])
+	case VERBATIM_STRING:
+		return p.handleVerbatimString(input[1:])
 	case BULK_ERROR:
-		return handleBulkError(input[1:])
+		return p.handleBulkError(input[1:])
 	case ARRAY:
-		return handleArray(input[1:])
+		return p.handleArray(input[1:])
 	case SET:
-		return handleSet(input[1:])
+		return p.handleSet(input[1:])
+	case MAP:
+		return p.handleMap(input[1:])
+	case PUSH:
+		return p.handlePush(input[1:])
+	case ATTRIBUTE:
+		return p.handleAttribute(input[1:])
 	case NULL:
-		return nil, 2
+		_, read := readUntilCRLF(input[1:])
+		return nil, read
 	}
 	return ErrInvalidSyntax, 1
 }
 
-func readUntilCRLF(bytes []byte) (string, int) {
-	str := ""
-	var c byte
-	read := 0
-	for i := 0; i < len(bytes); i++ {
-		c = bytes[i]
-		read++
-		if c == '\n' {
-			break
-		}
-		if c != '\r' {
-			str += string(c)
-		}
+// readUntilCRLF returns the line at the start of in without its CRLF, and
+// the length of the line including the line ending.
+func readUntilCRLF(in []byte) (string, int) {
+	i := bytes.IndexByte(in, '\n')
+	if i < 0 {
+		panic(ErrIncomplete)
 	}
-	return str, read
+	return string(bytes.TrimSuffix(in[:i], []byte("\r"))), i + 1
 }
 
 func handleSimpleString(in []byte) (string, int) {
@@ -93,7 +185,7 @@ func handleInteger(in []byte) (int, int) {
 
 func handleDouble(in []byte) (float64, int) {
 	str, read := readUntilCRLF(in)
-	// TODO: Handle inf
+	// ParseFloat also accepts the "inf", "-inf" and "nan" of RESP3
 	v, _ := strconv.ParseFloat(str, 64)
 	return v, read
 }
@@ -116,66 +208,189 @@ func handleBoolean(in []byte) (bool, int) {
 	}
 }
 
-func handleBulkString(in []byte) (interface{}, int) {
+// readLen reads the length line of an aggregate and checks it against the
+// limit. "?" yields streamed and -1 stands for the RESP2 null.
+func (p *Parser) readLen(in []byte) (int, int) {
 	length, read := readUntilCRLF(in)
-	size, _ := strconv.Atoi(length)
-	switch size {
-	case 0:
-		return []byte(""), read + 2
-	default:
-		val, r := readUntilCRLF(in[read:])
-		return []byte(val), read + r
+	if length == "?" {
+		return streamed, read
+	}
+	size, err := strconv.ParseInt(length, 10, 64)
+	if err != nil || size < -1 || size > p.maxMultibulkLen() {
+		panic(ErrInvalidMultibulkLength)
+	}
+	return int(size), read
+}
+
+// readBulkLen reads the length of a bulk string and checks it against the
+// limit. -1 stands for the RESP2 null.
+func (p *Parser) readBulkLen(in []byte) (int, int) {
+	length, read := readUntilCRLF(in)
+	switch length {
+	case "?":
+		return streamed, read
+	case "-1":
+		return -1, read
 	}
+	size, err := strconv.ParseInt(length, 10, 64)
+	if err != nil || size < 0 || size > p.maxBulkLen() {
+		panic(ErrInvalidBulkLength)
+	}
+	return int(size), read
 }
 
-func handleBulkError(in []byte) (error, int) {
-	v, read := handleBulkString(in)
-	return errors.New(v.(string)), read
+// readBulk reads size bytes followed by CRLF. The data is copied, so the
+// result doesn't keep in alive.
+func readBulk(in []byte, size int) ([]byte, int) {
+	if len(in) < size+2 {
+		panic(ErrIncomplete)
+	}
+	if in[size] != '\r' || in[size+1] != '\n' {
+		panic(ErrInvalidBulkLength)
+	}
+	b := make([]byte, size)
+	copy(b, in)
+	return b, size + 2
 }
 
-func handleArray(in []byte) ([]interface{}, int) {
-	length, read := readUntilCRLF(in)
-	size, _ := strconv.Atoi((length))
+func (p *Parser) handleBulkString(in []byte) (interface{}, int) {
+	size, read := p.readBulkLen(in)
 	switch size {
-	case 0:
-		empty := make([]interface{}, 0)
-		return empty, read
-	default:
-		in = in[read:]
-		totalRead := read
-		items := make([]interface{}, size)
-		for counter := 0; counter < size; counter++ {
-			item, r := Decode([]byte(in))
-			// first byte is skipped in Decode
-			totalRead += r + 1
-			in = in[r+1:]
-			items[counter] = item
+	case streamed:
+		return p.readChunks(in[read:], read)
+	case -1:
+		// RESP2 null bulk string
+		return nil, read
+	}
+	b, r := readBulk(in[read:], size)
+	return b, read + r
+}
+
+// readChunks reads the ";<len>" parts of a streamed string up to the empty
+// terminating chunk.
+func (p *Parser) readChunks(in []byte, read int) ([]byte, int) {
+	val := []byte{}
+	for {
+		if len(in) == 0 {
+			panic(ErrIncomplete)
+		}
+		if in[0] != CHUNK {
+			panic(ErrInvalidSyntax)
+		}
+		size, r := p.readBulkLen(in[1:])
+		read += 1 + r
+		in = in[1+r:]
+		if size <= 0 {
+			return val, read
 		}
-		return items, totalRead
+		if int64(len(val)+size) > p.maxBulkLen() {
+			panic(ErrInvalidBulkLength)
+		}
+		b, r := readBulk(in, size)
+		val = append(val, b...)
+		read += r
+		in = in[r:]
 	}
 }
 
-func handleSet(in []byte) (interface{}, int) {
-	length, read := readUntilCRLF(in)
-	size, _ := strconv.Atoi((length))
+// readString reads a bulk string that can't be null, e.g. a verbatim string.
+func (p *Parser) readString(in []byte) ([]byte, int) {
+	v, read := p.handleBulkString(in)
+	b, ok := v.([]byte)
+	if !ok {
+		panic(ErrInvalidBulkLength)
+	}
+	return b, read
+}
+
+func (p *Parser) handleVerbatimString(in []byte) (interface{}, int) {
+	b, read := p.readString(in)
+	if len(b) < 4 || b[3] != ':' {
+		return Verbatim{Format: "txt", Text: b}, read
+	}
+	return Verbatim{Format: string(b[:3]), Text: b[4:]}, read
+}
 
-	set := make(map[interface{}]bool)
+func (p *Parser) handleBulkError(in []byte) (error, int) {
+	b, read := p.readString(in)
+	return BulkError(b), read
+}
 
-	switch size {
-	case 0:
-		return set, read
-	case -1:
-		return nil, read
-	default:
-		in = in[read:]
-		totalRead := read
-		for counter := 0; counter < size; counter++ {
-			item, r := Decode([]byte(in))
-			// First byte is skipped in Decode
-			totalRead += r + 1
-			in = in[r+1:]
-			set[item] = true
+// readItems decodes size values, or values up to the END marker of a
+// streamed aggregate.
+func (p *Parser) readItems(in []byte, size int) ([]interface{}, int) {
+	items := []interface{}{}
+	totalRead := 0
+	for counter := 0; size == streamed || counter < size; counter++ {
+		if size == streamed && len(in) > 0 && in[0] == END {
+			_, r := readUntilCRLF(in[1:])
+			return items, totalRead + 1 + r
 		}
-		return set, totalRead
+		item, r := p.decode(in)
+		// first byte is skipped in decode
+		totalRead += r + 1
+		in = in[r+1:]
+		items = append(items, item)
+	}
+	return items, totalRead
+}
+
+func (p *Parser) handleArray(in []byte) (interface{}, int) {
+	size, read := p.readLen(in)
+	if size == -1 {
+		return nil, read
+	}
+	items, r := p.readItems(in[read:], size)
+	return items, read + r
+}
+
+func (p *Parser) handleSet(in []byte) (interface{}, int) {
+	size, read := p.readLen(in)
+	if size == -1 {
+		return nil, read
+	}
+	items, r := p.readItems(in[read:], size)
+	return Set(items), read + r
+}
+
+func (p *Parser) handlePush(in []byte) (interface{}, int) {
+	size, read := p.readLen(in)
+	if size == -1 {
+		panic(ErrInvalidMultibulkLength)
+	}
+	items, r := p.readItems(in[read:], size)
+	return Push(items), read + r
+}
+
+func (p *Parser) handleMap(in []byte) (interface{}, int) {
+	size, read := p.readLen(in)
+	if size == -1 {
+		return nil, read
+	}
+	if size != streamed {
+		size *= 2
+	}
+	items, r := p.readItems(in[read:], size)
+	return toMap(items), read + r
+}
+
+func (p *Parser) handleAttribute(in []byte) (interface{}, int) {
+	size, read := p.readLen(in)
+	if size < 0 {
+		// attributes are neither null nor streamed
+		panic(ErrInvalidMultibulkLength)
+	}
+	items, r := p.readItems(in[read:], 2*size)
+	read += r
+	// the attributes are followed by the reply they describe
+	v, r := p.decode(in[read:])
+	return Attribute{Attrs: toMap(items), Value: v}, read + r + 1
+}
+
+func toMap(items []interface{}) Map {
+	m := make(Map, len(items)/2)
+	for i := range m {
+		m[i] = Pair{Key: items[2*i], Value: items[2*i+1]}
 	}
+	return m
 }
@@ -2,6 +2,7 @@ package resp
 
 import (
 	"errors"
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -84,3 +85,94 @@ func Test__Error(t *testing.T) {
 		t.Errorf("got %q want %q", got, want)
 	}
 }
+
+func Test__RESP3Types(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{",1.5\r\n", 1.5},
+		{"(3492890328409238509324850943850943825024385\r\n", bigint("3492890328409238509324850943850943825024385")},
+		{"#t\r\n", true},
+		{"$-1\r\n", nil},
+		{"=15\r\ntxt:Some string\r\n", Verbatim{Format: "txt", Text: []byte("Some string")}},
+		{"!21\r\nSYNTAX invalid syntax\r\n", BulkError("SYNTAX invalid syntax")},
+		{"~2\r\n+a\r\n:1\r\n", Set{"a", 1}},
+		{">2\r\n+message\r\n$2\r\nch\r\n", Push{"message", []byte("ch")}},
+		{"%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n", Map{{Key: "first", Value: 1}, {Key: "second", Value: 2}}},
+		{"|1\r\n+ttl\r\n:3600\r\n$1\r\nv\r\n", Attribute{Attrs: Map{{Key: "ttl", Value: 3600}}, Value: []byte("v")}},
+		{"$?\r\n;4\r\nHell\r\n;5\r\no wor\r\n;1\r\nd\r\n;0\r\n", []byte("Hello word")},
+		{"*?\r\n:1\r\n:2\r\n.\r\n", []interface{}{1, 2}},
+		{"~?\r\n:1\r\n.\r\n", Set{1}},
+		{"%?\r\n+a\r\n:1\r\n.\r\n", Map{{Key: "a", Value: 1}}},
+		{"*2\r\n*?\r\n:1\r\n.\r\n:2\r\n", []interface{}{[]interface{}{1}, 2}},
+	}
+	for _, tt := range tests {
+		got, read := Decode([]byte(tt.input))
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Decode(%q): got %#v want %#v", tt.input, got, tt.expected)
+		}
+		// the read count excludes the type byte
+		if read+1 != len(tt.input) {
+			t.Errorf("Decode(%q): read %d bytes want %d", tt.input, read+1, len(tt.input))
+		}
+	}
+}
+
+func bigint(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+func Test__BinaryBulkString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"$8\r\nfoo\r\nbar\r\n", []byte("foo\r\nbar")},
+		{"$4\r\n\x00\r\xff\n\r\n", []byte("\x00\r\xff\n")},
+		{"$-1\r\n", nil},
+		{"*2\r\n$2\r\n\r\n\r\n$1\r\n\n\r\n", []interface{}{[]byte("\r\n"), []byte("\n")}},
+	}
+	for _, tt := range tests {
+		got, n, err := Parse([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.input, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) || n != len(tt.input) {
+			t.Errorf("Parse(%q): got %q, %d want %q, %d", tt.input, got, n, tt.expected, len(tt.input))
+		}
+	}
+}
+
+func Test__ParseErrors(t *testing.T) {
+	tests := []struct {
+		input      string
+		maxBulkLen int64
+		expected   error
+	}{
+		{"$6\r\nfoo", 0, ErrIncomplete},
+		{"$6\r\nfoobar", 0, ErrIncomplete},
+		{"$6", 0, ErrIncomplete},
+		{"*2\r\n$3\r\nfoo\r\n", 0, ErrIncomplete},
+		{"$3\r\nfoobar\r\n", 0, ErrInvalidBulkLength},
+		{"$-2\r\n", 0, ErrInvalidBulkLength},
+		{"$x\r\n", 0, ErrInvalidBulkLength},
+		{"$4\r\nfoo!\r\n", 3, ErrInvalidBulkLength},
+		{"*1\r\n$4\r\nfoo!\r\n", 3, ErrInvalidBulkLength},
+		{"$?\r\n;2\r\nab\r\n;2\r\ncd\r\n;0\r\n", 3, ErrInvalidBulkLength},
+		{"*x\r\n", 0, ErrInvalidMultibulkLength},
+		{"*-2\r\n", 0, ErrInvalidMultibulkLength},
+		{"*2147483648\r\n", 0, ErrInvalidMultibulkLength},
+		{"%1x\r\n", 0, ErrInvalidMultibulkLength},
+		{">-1\r\n", 0, ErrInvalidMultibulkLength},
+		{"|-1\r\n+OK\r\n", 0, ErrInvalidMultibulkLength},
+	}
+	for _, tt := range tests {
+		p := Parser{MaxBulkLen: tt.maxBulkLen}
+		_, _, err := p.Parse([]byte(tt.input))
+		if err != tt.expected {
+			t.Errorf("Parse(%q): got %v want %v", tt.input, err, tt.expected)
+		}
+	}
+}
@@ -1,44 +1,28 @@
 package resp
 
 import (
-	"fmt"
-	"reflect"
+	"strings"
 )
 
 const NIL = "_\r\n"
 
+// NIL2 is the RESP2 null, sent as a null bulk string.
+const NIL2 = "$-1\r\n"
+
+// Encode encodes input as RESP3.
 func Encode(input interface{}) string {
-	switch input.(type) {
-	case int:
-		return fmt.Sprintf(":%d\r\n", input)
-	case string:
-		return fmt.Sprintf("+%s\r\n", input)
-	case []byte:
-		len := len(reflect.ValueOf(input).Bytes())
-		return fmt.Sprintf("$%v\r\n%s\r\n", len, input)
-	case error:
-		return fmt.Sprintf("-%s\r\n", input)
-	case [][]byte:
-		s := reflect.ValueOf(input)
-		v := fmt.Sprintf("*%v\r\n", s.Len())
-		for i := 0; i < s.Len(); i++ {
-			b := (s.Index(i).Interface()).([]byte)
-			if len(b) > 0 {
-				v += Encode(b)
-			} else {
-				v += Encode(nil)
-			}
-		}
-		return v
-	case []interface{}:
-		s := reflect.ValueOf(input)
-		v := fmt.Sprintf("*%v\r\n", s.Len())
-		for i := 0; i < s.Len(); i++ {
-			v += Encode(s.Index(i).Interface())
-		}
-		return v
-	case nil:
-		return NIL
+	return EncodeProto(input, 3)
+}
+
+// EncodeProto encodes input for the given protocol version, 2 or 3. Types
+// that RESP2 lacks are sent as their closest RESP2 equivalent, e.g. nulls as
+// null bulk strings, maps as flat arrays and doubles as bulk strings.
+func EncodeProto(input interface{}, proto int) string {
+	var b strings.Builder
+	w := NewWriter(&b)
+	w.SetProto(proto)
+	if err := w.Encode(input); err != nil {
+		panic(err)
 	}
-	panic(ErrInvalidSyntax)
+	return b.String()
 }
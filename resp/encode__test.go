@@ -2,6 +2,8 @@ package resp
 
 import (
 	"errors"
+	"math"
+	"math/big"
 	"testing"
 )
 
@@ -80,3 +82,79 @@ func Test_NilEn(t *testing.T) {
 		t.Errorf("got %q want %q", got, want)
 	}
 }
+
+func Test__EncodeProto(t *testing.T) {
+	m := Map{{Key: "a", Value: 1}, {Key: []byte("b"), Value: nil}}
+	tests := []struct {
+		input    interface{}
+		proto    int
+		expected string
+	}{
+		{nil, 2, "$-1\r\n"},
+		{nil, 3, "_\r\n"},
+		{[][]byte{[]byte("foo"), nil}, 2, "*2\r\n$3\r\nfoo\r\n$-1\r\n"},
+		{m, 2, "*4\r\n+a\r\n:1\r\n$1\r\nb\r\n$-1\r\n"},
+		{m, 3, "%2\r\n+a\r\n:1\r\n$1\r\nb\r\n_\r\n"},
+		{[]interface{}{Map{}}, 3, "*1\r\n%0\r\n"},
+	}
+	for _, tt := range tests {
+		got := EncodeProto(tt.input, tt.proto)
+		if got != tt.expected {
+			t.Errorf("EncodeProto(%q, %d): got %q want %q", tt.input, tt.proto, got, tt.expected)
+		}
+	}
+}
+
+func Test__RESP3TypesEn(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{1.5, ",1.5\r\n"},
+		{math.Inf(-1), ",-inf\r\n"},
+		{new(big.Int).Lsh(big.NewInt(1), 70), "(1180591620717411303424\r\n"},
+		{true, "#t\r\n"},
+		{false, "#f\r\n"},
+		{Verbatim{Format: "txt", Text: []byte("Some string")}, "=15\r\ntxt:Some string\r\n"},
+		{BulkError("SYNTAX invalid\r\nsyntax"), "!22\r\nSYNTAX invalid\r\nsyntax\r\n"},
+		{Set{[]byte("a"), 1}, "~2\r\n$1\r\na\r\n:1\r\n"},
+		{Push{[]byte("message"), []byte("ch")}, ">2\r\n$7\r\nmessage\r\n$2\r\nch\r\n"},
+		{Map{{Key: "first", Value: 1}}, "%1\r\n+first\r\n:1\r\n"},
+		{
+			Attribute{Attrs: Map{{Key: "ttl", Value: 3600}}, Value: []byte("v")},
+			"|1\r\n+ttl\r\n:3600\r\n$1\r\nv\r\n",
+		},
+		{StreamedString{[]byte("Hell"), []byte("o")}, "$?\r\n;4\r\nHell\r\n;1\r\no\r\n;0\r\n"},
+		{Streamed{[]interface{}{1, 2}}, "*?\r\n:1\r\n:2\r\n.\r\n"},
+		{Streamed{Map{{Key: "a", Value: 1}}}, "%?\r\n+a\r\n:1\r\n.\r\n"},
+	}
+	for _, tt := range tests {
+		got := Encode(tt.input)
+		if got != tt.expected {
+			t.Errorf("Encode(%v): got %q want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func Test__RESP2FallbackEn(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{1.5, "$3\r\n1.5\r\n"},
+		{true, ":1\r\n"},
+		{Verbatim{Format: "txt", Text: []byte("hi")}, "$2\r\nhi\r\n"},
+		{BulkError("ERR a\nb"), "-ERR a b\r\n"},
+		{Set{1}, "*1\r\n:1\r\n"},
+		{Push{1}, "*1\r\n:1\r\n"},
+		{Attribute{Attrs: Map{{Key: "a", Value: 1}}, Value: 2}, ":2\r\n"},
+		{StreamedString{[]byte("ab"), []byte("c")}, "$3\r\nabc\r\n"},
+		{Streamed{Set{1}}, "*1\r\n:1\r\n"},
+	}
+	for _, tt := range tests {
+		got := EncodeProto(tt.input, 2)
+		if got != tt.expected {
+			t.Errorf("EncodeProto(%v, 2): got %q want %q", tt.input, got, tt.expected)
+		}
+	}
+}
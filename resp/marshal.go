@@ -0,0 +1,539 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnsupportedTypeError is returned by Marshal for Go values that have no
+// RESP representation, e.g. channels and functions.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "resp: unsupported type: " + e.Type.String()
+}
+
+// InvalidUnmarshalError is returned by Unmarshal when the destination isn't
+// a non-nil pointer.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "resp: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "resp: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "resp: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// UnmarshalTypeError describes a RESP value that can't be stored in a Go
+// value of the given type.
+type UnmarshalTypeError struct {
+	Kind Kind
+	Type reflect.Type
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return "resp: cannot unmarshal " + e.Kind.String() + " into Go value of type " + e.Type.String()
+}
+
+// Marshal returns the RESP3 encoding of v:
+//
+//	strings, []byte        bulk strings
+//	bool                   booleans
+//	integers               integers, or big numbers above math.MaxInt64
+//	floats                 doubles
+//	error                  errors
+//	slices and arrays      arrays
+//	maps and structs       maps, map keys are sorted
+//	nil pointers, slices   null
+//
+// Struct fields are named after the "resp" tag if present, e.g.
+// `resp:"name,omitempty"`, or else the field name. Fields tagged "-" and
+// unexported fields are skipped, omitempty skips zero values. The fields of
+// embedded structs are promoted as with encoding/json, the ones behind a nil
+// pointer are skipped. The types of
+// this package, such as Map, Set or Value, are encoded as they are.
+func Marshal(v interface{}) ([]byte, error) {
+	x, err := marshalValue(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := NewWriter(&b).Encode(x); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	valueType = reflect.TypeOf(Value{})
+	bigType   = reflect.TypeOf(big.Int{})
+)
+
+// marshalValue converts rv into the form accepted by Writer.
+func marshalValue(rv reflect.Value) (interface{}, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	switch x := rv.Interface().(type) {
+	case Value, Map, Set, Push, Verbatim, BulkError, Attribute, StreamedString, Streamed:
+		return x, nil
+	case *big.Int:
+		if x == nil {
+			return nil, nil
+		}
+		return x, nil
+	}
+	if rv.Type().Implements(errorType) && (rv.Kind() != reflect.Ptr || !rv.IsNil()) {
+		return rv.Interface().(error), nil
+	}
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return marshalValue(rv.Elem())
+	case reflect.String:
+		return []byte(rv.String()), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := rv.Uint(); n > math.MaxInt64 {
+			return new(big.Int).SetUint64(n), nil
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+		return marshalItems(rv)
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return b, nil
+		}
+		return marshalItems(rv)
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+		m := make(Map, 0, len(keys))
+		for _, k := range keys {
+			key, err := marshalValue(k)
+			if err != nil {
+				return nil, err
+			}
+			val, err := marshalValue(rv.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, Pair{Key: key, Value: val})
+		}
+		return m, nil
+	case reflect.Struct:
+		fields := structFields(rv.Type())
+		m := make(Map, 0, len(fields))
+		for _, f := range fields {
+			fv, ok := fieldByIndex(rv, f.index, false)
+			if !ok || f.omitEmpty && fv.IsZero() {
+				continue
+			}
+			val, err := marshalValue(fv)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, Pair{Key: []byte(f.name), Value: val})
+		}
+		return m, nil
+	}
+	return nil, &UnsupportedTypeError{rv.Type()}
+}
+
+// keyLess orders map keys: strings by their bytes, numbers by value and
+// other keys by their formatting.
+func keyLess(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+func marshalItems(rv reflect.Value) ([]interface{}, error) {
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		x, err := marshalValue(rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		items[i] = x
+	}
+	return items, nil
+}
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	tagged    bool
+}
+
+// fieldCache maps struct types to their []field.
+var fieldCache sync.Map
+
+func structFields(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+	fields := typeFields(t)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// typeFields returns the fields of t, with the ones of embedded structs
+// promoted like with encoding/json: a name hides the same name deeper in
+// the embedded structs, and between fields of the same depth the tagged one
+// wins, or else the name is dropped.
+func typeFields(t reflect.Type) []field {
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	var fields []field
+	// names holds the names found at a lower depth
+	names := map[string]bool{}
+	visited := map[reflect.Type]bool{}
+	next := []embedded{{t, nil}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+		var level []field
+		for _, e := range current {
+			// a struct embedded twice at the same depth makes its fields
+			// ambiguous, it's only skipped once seen at a lower depth
+			if visited[e.t] {
+				continue
+			}
+			for i := 0; i < e.t.NumField(); i++ {
+				sf := e.t.Field(i)
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.PkgPath != "" && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+					// unexported, but the fields of an embedded struct are
+					// promoted anyway
+					continue
+				}
+				tag := sf.Tag.Get("resp")
+				if tag == "-" {
+					continue
+				}
+				parts := strings.Split(tag, ",")
+				index := append(append([]int(nil), e.index...), i)
+				if parts[0] == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{ft, index})
+					continue
+				}
+				if sf.PkgPath != "" {
+					continue
+				}
+				f := field{name: parts[0], index: index, tagged: parts[0] != ""}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				for _, opt := range parts[1:] {
+					if opt == "omitempty" {
+						f.omitEmpty = true
+					}
+				}
+				level = append(level, f)
+			}
+		}
+		for _, e := range current {
+			visited[e.t] = true
+		}
+		byName := map[string][]field{}
+		for _, f := range level {
+			byName[f.name] = append(byName[f.name], f)
+		}
+		for name, fs := range byName {
+			if names[name] {
+				continue
+			}
+			names[name] = true
+			if f, ok := dominantField(fs); ok {
+				fields = append(fields, f)
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// dominantField returns the field that a name refers to among fields of
+// the same depth, ok is false when it is ambiguous.
+func dominantField(fs []field) (field, bool) {
+	if len(fs) == 1 {
+		return fs[0], true
+	}
+	var tagged []field
+	for _, f := range fs {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return field{}, false
+}
+
+// fieldByIndex is like FieldByIndex, but ok is false when the field is in
+// an embedded struct through a nil pointer. With alloc the pointer is set
+// to a new struct instead, when it can be.
+func fieldByIndex(rv reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				if !alloc || !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// Unmarshal decodes the first RESP value of data into the value pointed to
+// by v, using the mapping of Marshal in reverse. Numbers are also read from
+// strings, and maps and structs from arrays of alternating keys and values,
+// as Redis replies that way in RESP2. Struct fields match map keys by tag or
+// name, ignoring case. Nulls leave v at its zero value. If data is an error
+// reply, Unmarshal returns it unless v points to a Value or an interface{}.
+func Unmarshal(data []byte, v interface{}) error {
+	val, _, err := ParseValue(data)
+	if err != nil {
+		return err
+	}
+	return val.Unmarshal(v)
+}
+
+// Unmarshal stores v in the value pointed to by dst, like Unmarshal.
+func (v Value) Unmarshal(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(dst)}
+	}
+	return unmarshalValue(v, rv.Elem())
+}
+
+func unmarshalValue(v Value, rv reflect.Value) error {
+	switch rv.Type() {
+	case valueType:
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	case bigType:
+		n, err := v.BigInt()
+		if err != nil {
+			return typeError(v, rv)
+		}
+		rv.Set(reflect.ValueOf(*n))
+		return nil
+	}
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		if x := v.Interface(); x != nil {
+			rv.Set(reflect.ValueOf(x))
+		} else {
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return nil
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	if rv.Kind() == reflect.Ptr {
+		if v.IsNull() {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalValue(v, rv.Elem())
+	}
+	if v.IsNull() {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		if v.isAggregate() {
+			return typeError(v, rv)
+		}
+		rv.SetString(string(v.Bytes()))
+	case reflect.Bool:
+		b, err := v.Bool()
+		if err != nil {
+			return typeError(v, rv)
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := v.Int()
+		if err != nil || rv.OverflowInt(n) {
+			return typeError(v, rv)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := v.BigInt()
+		if err != nil || !n.IsUint64() || rv.OverflowUint(n.Uint64()) {
+			return typeError(v, rv)
+		}
+		rv.SetUint(n.Uint64())
+	case reflect.Float32, reflect.Float64:
+		f, err := v.Float()
+		if err != nil || rv.OverflowFloat(f) {
+			return typeError(v, rv)
+		}
+		rv.SetFloat(f)
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && !v.isAggregate() {
+			rv.SetBytes(append([]byte{}, v.Bytes()...))
+			return nil
+		}
+		if !v.isAggregate() {
+			return typeError(v, rv)
+		}
+		items := v.Array()
+		s := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := unmarshalValue(item, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(s)
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && !v.isAggregate() {
+			reflect.Copy(rv, reflect.ValueOf(v.Bytes()))
+			return nil
+		}
+		if !v.isAggregate() {
+			return typeError(v, rv)
+		}
+		items := v.Array()
+		for i := 0; i < rv.Len(); i++ {
+			if i < len(items) {
+				if err := unmarshalValue(items[i], rv.Index(i)); err != nil {
+					return err
+				}
+			} else {
+				rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+			}
+		}
+	case reflect.Map:
+		if !v.isMapLike() {
+			return typeError(v, rv)
+		}
+		pairs := v.Map()
+		t := rv.Type()
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(t, len(pairs)))
+		}
+		for _, p := range pairs {
+			key := reflect.New(t.Key()).Elem()
+			if err := unmarshalValue(p.Key, key); err != nil {
+				return err
+			}
+			val := reflect.New(t.Elem()).Elem()
+			if err := unmarshalValue(p.Value, val); err != nil {
+				return err
+			}
+			rv.SetMapIndex(key, val)
+		}
+	case reflect.Struct:
+		if !v.isMapLike() {
+			return typeError(v, rv)
+		}
+		pairs := v.Map()
+		fields := structFields(rv.Type())
+		for _, p := range pairs {
+			f, ok := lookupField(fields, string(p.Key.Bytes()))
+			if !ok {
+				continue
+			}
+			fv, ok := fieldByIndex(rv, f.index, true)
+			if !ok {
+				continue
+			}
+			if err := unmarshalValue(p.Value, fv); err != nil {
+				return err
+			}
+		}
+	default:
+		return typeError(v, rv)
+	}
+	return nil
+}
+
+// lookupField prefers an exact match of the name over a case insensitive
+// one.
+func lookupField(fields []field, name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func typeError(v Value, rv reflect.Value) error {
+	return &UnmarshalTypeError{Kind: v.Kind(), Type: rv.Type()}
+}
@@ -0,0 +1,235 @@
+package resp
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+type server struct {
+	Name    string            `resp:"name"`
+	Port    int               `resp:"port"`
+	Ratio   float64           `resp:"ratio,omitempty"`
+	Tags    []string          `resp:"tags"`
+	Enabled bool              `resp:"enabled"`
+	Limits  map[string]uint16 `resp:"limits,omitempty"`
+	Secret  string            `resp:"-"`
+	ignored int
+}
+
+func Test__Marshal(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{"foo", "$3\r\nfoo\r\n"},
+		{[]byte("foo"), "$3\r\nfoo\r\n"},
+		{int8(-3), ":-3\r\n"},
+		{uint64(math.MaxUint64), "(18446744073709551615\r\n"},
+		{float32(0.5), ",0.5\r\n"},
+		{true, "#t\r\n"},
+		{errors.New("ERR x"), "-ERR x\r\n"},
+		{(*int)(nil), "_\r\n"},
+		{[]int(nil), "_\r\n"},
+		{[2]string{"a", "b"}, "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{map[string]int{"b": 2, "a": 1}, "%2\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n:2\r\n"},
+		{
+			map[string]int{"a": 1, "c": 3, "d": 2, "z": 4},
+			"%4\r\n$1\r\na\r\n:1\r\n$1\r\nc\r\n:3\r\n$1\r\nd\r\n:2\r\n$1\r\nz\r\n:4\r\n",
+		},
+		{map[int]bool{10: true, 9: false}, "%2\r\n:9\r\n#f\r\n:10\r\n#t\r\n"},
+		{Set{"x"}, "~1\r\n+x\r\n"},
+		{
+			server{Name: "r", Port: 1, Tags: []string{"t"}, Secret: "s"},
+			"%4\r\n$4\r\nname\r\n$1\r\nr\r\n$4\r\nport\r\n:1\r\n$4\r\ntags\r\n*1\r\n$1\r\nt\r\n$7\r\nenabled\r\n#f\r\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.input)
+		if err != nil {
+			t.Fatalf("Marshal(%v): %v", tt.input, err)
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Marshal(%v): got %q want %q", tt.input, got, tt.expected)
+		}
+	}
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Errorf("Marshal(chan): expected error")
+	}
+}
+
+func Test__Unmarshal(t *testing.T) {
+	in := server{
+		Name:    "redis",
+		Port:    6379,
+		Ratio:   0.25,
+		Tags:    []string{"a", "b"},
+		Enabled: true,
+		Limits:  map[string]uint16{"clients": 100},
+	}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out server
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip: got %+v want %+v", out, in)
+	}
+
+	// RESP2 replies: a flat array for a map, numbers in bulk strings
+	var h struct {
+		Name  string
+		Count int
+		Score float64
+	}
+	err = Unmarshal([]byte("*6\r\n$4\r\nname\r\n$1\r\nx\r\n$5\r\ncount\r\n$2\r\n42\r\n$5\r\nscore\r\n$3\r\n1.5\r\n"), &h)
+	if err != nil || h.Name != "x" || h.Count != 42 || h.Score != 1.5 {
+		t.Errorf("RESP2 struct: got %+v, %v", h, err)
+	}
+
+	var p *int
+	if err := Unmarshal([]byte(":5\r\n"), &p); err != nil || p == nil || *p != 5 {
+		t.Errorf("pointer: got %v, %v", p, err)
+	}
+	if err := Unmarshal([]byte("_\r\n"), &p); err != nil || p != nil {
+		t.Errorf("null pointer: got %v, %v", p, err)
+	}
+	var n big.Int
+	if err := Unmarshal([]byte("(123456789012345678901234567890\r\n"), &n); err != nil || n.String() != "123456789012345678901234567890" {
+		t.Errorf("big.Int: got %v, %v", &n, err)
+	}
+	var x interface{}
+	if err := Unmarshal([]byte("*1\r\n:1\r\n"), &x); err != nil || !reflect.DeepEqual(x, []interface{}{int64(1)}) {
+		t.Errorf("interface{}: got %#v, %v", x, err)
+	}
+	var v Value
+	if err := Unmarshal([]byte("-ERR x\r\n"), &v); err != nil || v.Kind() != KindError {
+		t.Errorf("Value: got %v, %v", v, err)
+	}
+}
+
+func Test__UnmarshalErrors(t *testing.T) {
+	var s string
+	var i8 int8
+	var u uint
+	var m map[string]int
+	tests := []struct {
+		input    string
+		dst      interface{}
+		expected string
+	}{
+		{"-ERR x\r\n", &s, "ERR x"},
+		{"*1\r\n:1\r\n", &s, "resp: cannot unmarshal array into Go value of type string"},
+		{":300\r\n", &i8, "resp: cannot unmarshal integer into Go value of type int8"},
+		{":-1\r\n", &u, "resp: cannot unmarshal integer into Go value of type uint"},
+		{"*1\r\n:1\r\n", &m, "resp: cannot unmarshal array into Go value of type map[string]int"},
+		{":1\r\n", s, "resp: Unmarshal(non-pointer string)"},
+		{"$5\r\nab", &s, ErrIncomplete.Error()},
+	}
+	for _, tt := range tests {
+		err := Unmarshal([]byte(tt.input), tt.dst)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Unmarshal(%q): got %v want %s", tt.input, err, tt.expected)
+		}
+	}
+}
+
+type Base struct {
+	ID   int    `resp:"id"`
+	Name string `resp:"name"`
+}
+
+type Audit struct {
+	By string `resp:"by"`
+	ID int    `resp:"id"`
+}
+
+type meta struct {
+	Version int `resp:"version"`
+}
+
+type record struct {
+	Base
+	*Audit
+	meta
+	Name  string `resp:"name"`
+	Inner Base   `resp:"inner,omitempty"`
+}
+
+type tagX struct {
+	Y int `resp:"X"`
+}
+
+type plainX struct{ X int }
+
+type otherX struct{ X int }
+
+type wrapA struct{ plainX }
+
+type wrapB struct{ plainX }
+
+func Test__MarshalEmbedded(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		// the name of record hides the one of Base, the ids of Base and
+		// Audit are at the same depth and both tagged so they are dropped
+		{
+			record{Base: Base{ID: 1, Name: "base"}, Audit: &Audit{By: "bob", ID: 2}, meta: meta{3}, Name: "r"},
+			"%3\r\n$2\r\nby\r\n$3\r\nbob\r\n$7\r\nversion\r\n:3\r\n$4\r\nname\r\n$1\r\nr\r\n",
+		},
+		// the fields behind a nil pointer are skipped
+		{
+			record{Inner: Base{ID: 1}},
+			"%3\r\n$7\r\nversion\r\n:0\r\n$4\r\nname\r\n$0\r\n\r\n$5\r\ninner\r\n%2\r\n$2\r\nid\r\n:1\r\n$4\r\nname\r\n$0\r\n\r\n",
+		},
+		{
+			struct {
+				*Audit
+				A struct{ *Audit }
+			}{},
+			"%1\r\n$1\r\nA\r\n%0\r\n",
+		},
+		// a tagged field wins over an untagged one of the same depth
+		{struct {
+			tagX
+			plainX
+		}{tagX{1}, plainX{2}}, "%1\r\n$1\r\nX\r\n:1\r\n"},
+		{struct {
+			plainX
+			otherX
+		}{plainX{1}, otherX{2}}, "%0\r\n"},
+		{struct {
+			wrapA
+			wrapB
+		}{}, "%0\r\n"},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.input)
+		if err != nil {
+			t.Fatalf("Marshal(%+v): %v", tt.input, err)
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Marshal(%+v): got %q want %q", tt.input, got, tt.expected)
+		}
+	}
+
+	in := record{Audit: &Audit{By: "bob"}, meta: meta{3}, Name: "r", Inner: Base{ID: 4}}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out record
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip: got %+v want %+v", out, in)
+	}
+}
@@ -0,0 +1,133 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// InlineMaxSize bounds the lines of a request that are read before their
+// CRLF is found: inline commands and the length lines of a multibulk
+// request. It is PROTO_INLINE_MAX_SIZE in Redis.
+const InlineMaxSize = 64 * 1024
+
+var (
+	ErrTooBigMultibulkCount = errors.New("ERR Protocol error: too big mbulk count string")
+	ErrTooBigBulkCount      = errors.New("ERR Protocol error: too big bulk count string")
+)
+
+// ParseCommand decodes the command at the start of input, sent by a client
+// as an array of bulk strings, and returns its arguments along with the
+// number of bytes it took. Unlike Parse it accepts nothing else, as Redis,
+// and reports the same protocol errors. An array of length 0 or less yields
+// no arguments. It returns ErrIncomplete if input holds only part of the
+// command.
+func (p *Parser) ParseCommand(input []byte) (args [][]byte, n int, err error) {
+	c := CommandParser{Parser: *p}
+	return c.Parse(input)
+}
+
+// CommandParser parses the commands of a connection as they arrive. Like
+// processMultibulkBuffer in Redis, it remembers how far it got in a command
+// that is still incomplete, so that the arguments already received aren't
+// parsed again when more data comes in. The zero value is ready to use.
+type CommandParser struct {
+	Parser
+	// multibulkLen is the number of arguments of the command being parsed,
+	// 0 until its length line is read
+	multibulkLen int64
+	// pos is where the parsing resumes in the command
+	pos int
+	// bounds holds the start and end offsets of the arguments read so far
+	bounds [][2]int
+}
+
+// Parse is like ParseCommand but resumes the parsing of a command that was
+// incomplete on the previous call. input must then start with the same
+// bytes as before, followed by the new data. The arguments are copied
+// once the whole command has arrived.
+func (p *CommandParser) Parse(input []byte) (args [][]byte, n int, err error) {
+	args, n, err = p.parse(input)
+	if err != ErrIncomplete {
+		p.reset()
+	}
+	return args, n, err
+}
+
+func (p *CommandParser) reset() {
+	p.multibulkLen = 0
+	p.pos = 0
+	p.bounds = p.bounds[:0]
+}
+
+func (p *CommandParser) parse(input []byte) ([][]byte, int, error) {
+	if p.multibulkLen == 0 {
+		if len(input) == 0 {
+			return nil, 0, ErrIncomplete
+		}
+		if input[0] != ARRAY {
+			return nil, 0, fmt.Errorf("ERR Protocol error: expected '*', got '%c'", input[0])
+		}
+		line, n, err := readLine(input[1:], ErrTooBigMultibulkCount)
+		if err != nil {
+			return nil, 0, err
+		}
+		count, err := strconv.ParseInt(line, 10, 64)
+		if err != nil || count > p.maxMultibulkLen() {
+			return nil, 0, ErrInvalidMultibulkLength
+		}
+		if count <= 0 {
+			return nil, 1 + n, nil
+		}
+		p.multibulkLen = count
+		p.pos = 1 + n
+	}
+	for int64(len(p.bounds)) < p.multibulkLen {
+		n := p.pos
+		if n == len(input) {
+			return nil, 0, ErrIncomplete
+		}
+		if input[n] != BULK_STRING {
+			return nil, 0, fmt.Errorf("ERR Protocol error: expected '$', got '%c'", input[n])
+		}
+		line, r, err := readLine(input[n+1:], ErrTooBigBulkCount)
+		if err != nil {
+			return nil, 0, err
+		}
+		n += 1 + r
+		l, err := strconv.ParseInt(line, 10, 64)
+		if err != nil || l < 0 || l > p.maxBulkLen() {
+			return nil, 0, ErrInvalidBulkLength
+		}
+		if int64(len(input)-n) < l+2 {
+			return nil, 0, ErrIncomplete
+		}
+		end := n + int(l)
+		if input[end] != '\r' || input[end+1] != '\n' {
+			return nil, 0, ErrInvalidBulkLength
+		}
+		p.bounds = append(p.bounds, [2]int{n, end})
+		p.pos = end + 2
+	}
+	// copied, so that the arguments don't keep input alive
+	args := make([][]byte, len(p.bounds))
+	for i, b := range p.bounds {
+		args[i] = append([]byte{}, input[b[0]:b[1]]...)
+	}
+	return args, p.pos, nil
+}
+
+// readLine returns the line at the start of in without its CRLF, and the
+// length of the line including the line ending. A missing line ending is
+// ErrIncomplete, or tooBig once InlineMaxSize bytes have been read.
+func readLine(in []byte, tooBig error) (string, int, error) {
+	i := bytes.IndexByte(in, '\n')
+	if i < 0 {
+		if len(in) > InlineMaxSize {
+			return "", 0, tooBig
+		}
+		return "", 0, ErrIncomplete
+	}
+	return string(bytes.TrimSuffix(in[:i], []byte("\r"))), i + 1, nil
+}
@@ -0,0 +1,112 @@
+package resp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test__ParseCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected [][]byte
+		n        int
+	}{
+		{"*1\r\n$4\r\nPING\r\n", [][]byte{[]byte("PING")}, 14},
+		{"*2\r\n$3\r\nGET\r\n$0\r\n\r\n*1\r\n", [][]byte{[]byte("GET"), {}}, 19},
+		{"*2\r\n$3\r\nSET\r\n$4\r\na\r\nb\r\n", [][]byte{[]byte("SET"), []byte("a\r\nb")}, 23},
+		{"*0\r\n", nil, 4},
+		{"*-1\r\n", nil, 5},
+	}
+	for _, tt := range tests {
+		var p Parser
+		got, n, err := p.ParseCommand([]byte(tt.input))
+		if err != nil || !reflect.DeepEqual(got, tt.expected) || n != tt.n {
+			t.Errorf("ParseCommand(%q): got %q, %d, %v want %q, %d", tt.input, got, n, err, tt.expected, tt.n)
+		}
+	}
+}
+
+func Test__ParseCommandErrors(t *testing.T) {
+	long := strings.Repeat("1", InlineMaxSize+1)
+	tests := []struct {
+		input      string
+		maxBulkLen int64
+		expected   string
+	}{
+		{"", 0, ErrIncomplete.Error()},
+		{"*2\r\n$3\r\nGET\r\n", 0, ErrIncomplete.Error()},
+		{"*1\r\n$3\r\nGET", 0, ErrIncomplete.Error()},
+		{"*1", 0, ErrIncomplete.Error()},
+		{"*" + long, 0, ErrTooBigMultibulkCount.Error()},
+		{"*1\r\n$" + long, 0, ErrTooBigBulkCount.Error()},
+		{"*x\r\n", 0, ErrInvalidMultibulkLength.Error()},
+		{"*2147483648\r\n", 0, ErrInvalidMultibulkLength.Error()},
+		{"*1\r\n$-1\r\n", 0, ErrInvalidBulkLength.Error()},
+		{"*1\r\n$4\r\nPING\r\n", 3, ErrInvalidBulkLength.Error()},
+		{"*1\r\n$3\r\nPING\r\n", 0, ErrInvalidBulkLength.Error()},
+		{"*1\r\n:1\r\n", 0, "ERR Protocol error: expected '$', got ':'"},
+		{"+PING\r\n", 0, "ERR Protocol error: expected '*', got '+'"},
+	}
+	for _, tt := range tests {
+		p := Parser{MaxBulkLen: tt.maxBulkLen}
+		_, _, err := p.ParseCommand([]byte(tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("ParseCommand(%.20q): got %v want %v", tt.input, err, tt.expected)
+		}
+	}
+}
+
+func Test__CommandParser(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected [][]byte
+	}{
+		{"*1\r\n$4\r\nPING\r\n", [][]byte{[]byte("PING")}},
+		{"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$5\r\nv\r\n\r\n\r\n", [][]byte{[]byte("SET"), []byte("k"), []byte("v\r\n\r\n")}},
+		{"*0\r\n", nil},
+	}
+	for _, tt := range tests {
+		// the command arrives one byte at a time
+		var p CommandParser
+		for i := 0; i <= len(tt.input); i++ {
+			got, n, err := p.Parse([]byte(tt.input[:i]))
+			if i < len(tt.input) {
+				if err != ErrIncomplete {
+					t.Fatalf("Parse(%q): got %v want %v", tt.input[:i], err, ErrIncomplete)
+				}
+				continue
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.expected) || n != len(tt.input) {
+				t.Errorf("Parse(%q): got %q, %d, %v want %q, %d", tt.input, got, n, err, tt.expected, len(tt.input))
+			}
+		}
+		// the state is reset for the next command
+		got, _, err := p.Parse([]byte("*1\r\n$4\r\nPING\r\n"))
+		if err != nil || !reflect.DeepEqual(got, [][]byte{[]byte("PING")}) {
+			t.Errorf("Parse after %q: got %q, %v", tt.input, got, err)
+		}
+	}
+}
+
+func BenchmarkCommandParser(b *testing.B) {
+	// a 1MB MSET received in chunks of 16KB
+	var cmd strings.Builder
+	cmd.WriteString("*20001\r\n$4\r\nMSET\r\n")
+	for i := 0; i < 10000; i++ {
+		cmd.WriteString("$8\r\nkey:0000\r\n$32\r\n" + strings.Repeat("v", 32) + "\r\n")
+	}
+	input := []byte(cmd.String())
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		var p CommandParser
+		for end := 16 * 1024; ; end += 16 * 1024 {
+			if end > len(input) {
+				end = len(input)
+			}
+			if _, _, err := p.Parse(input[:end]); err != ErrIncomplete {
+				break
+			}
+		}
+	}
+}
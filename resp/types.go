@@ -0,0 +1,58 @@
+package resp
+
+// The types below map the RESP3 types that have no natural Go counterpart.
+// Values that do are used directly: int and int64 for integers, string for
+// simple strings, []byte for bulk strings, error for errors, float64 for
+// doubles, *big.Int for big numbers, bool for booleans, []interface{} for
+// arrays and nil for null.
+
+// Map is an ordered RESP3 map. In RESP2 it is flattened to an array of
+// alternating keys and values.
+type Map []Pair
+
+// Pair is an entry of a Map.
+type Pair struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Set is a RESP3 set, sent as an array in RESP2.
+type Set []interface{}
+
+// Push is an out of band RESP3 push message, e.g. a Pub/Sub message. It is
+// sent as an array in RESP2.
+type Push []interface{}
+
+// Verbatim is a RESP3 verbatim string. Format is a three character hint
+// such as "txt" or "mkd". RESP2 receives Text as a bulk string.
+type Verbatim struct {
+	Format string
+	Text   []byte
+}
+
+// BulkError is an error that may contain CR or LF, sent with the RESP3 '!'
+// type.
+type BulkError []byte
+
+func (e BulkError) Error() string {
+	return string(e)
+}
+
+// Attribute is a reply preceded by RESP3 attributes, auxiliary data such as
+// key popularity. RESP2 only receives Value.
+type Attribute struct {
+	Attrs Map
+	Value interface{}
+}
+
+// StreamedString is a string sent in chunks ($? followed by ;<len> parts)
+// when its length isn't known upfront. It is decoded as a plain []byte and
+// sent as a single bulk string in RESP2.
+type StreamedString [][]byte
+
+// Streamed wraps an aggregate, []interface{}, Set or Map, to send it with
+// an unknown length: its items follow *?, ~? or %? and a final ".\r\n"
+// ends it. Decoding yields the plain aggregate.
+type Streamed struct {
+	Aggregate interface{}
+}
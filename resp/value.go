@@ -0,0 +1,422 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// Kind is the RESP type of a Value.
+type Kind int
+
+const (
+	KindNull Kind = iota
+	KindSimpleString
+	KindError
+	KindInteger
+	KindDouble
+	KindBigNumber
+	KindBoolean
+	KindBulkString
+	KindBulkError
+	KindVerbatim
+	KindArray
+	KindSet
+	KindMap
+	KindPush
+)
+
+var kindNames = [...]string{
+	KindNull:         "null",
+	KindSimpleString: "simple string",
+	KindError:        "error",
+	KindInteger:      "integer",
+	KindDouble:       "double",
+	KindBigNumber:    "big number",
+	KindBoolean:      "boolean",
+	KindBulkString:   "bulk string",
+	KindBulkError:    "bulk error",
+	KindVerbatim:     "verbatim string",
+	KindArray:        "array",
+	KindSet:          "set",
+	KindMap:          "map",
+	KindPush:         "push",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return kindNames[k]
+}
+
+// Value is a decoded RESP value. Its accessors spare callers the type
+// assertions needed on the interface{} returned by Decode. The zero Value is
+// a null.
+type Value struct {
+	kind Kind
+	// str holds strings and errors, num integers and booleans
+	str    []byte
+	format string
+	num    int64
+	float  float64
+	big    *big.Int
+	// items holds aggregates, maps alternate keys and values
+	items []Value
+	attrs []Value
+}
+
+// ValuePair is an entry of a map Value.
+type ValuePair struct {
+	Key   Value
+	Value Value
+}
+
+var errNotRESP = errors.New("resp: value has no RESP representation")
+
+// ValueOf converts a value as returned by Decode, or accepted by Encode,
+// into a Value.
+func ValueOf(x interface{}) (Value, error) {
+	switch v := x.(type) {
+	case nil:
+		return Value{}, nil
+	case Value:
+		return v, nil
+	case string:
+		return Value{kind: KindSimpleString, str: []byte(v)}, nil
+	case []byte:
+		return Value{kind: KindBulkString, str: v}, nil
+	case BulkError:
+		return Value{kind: KindBulkError, str: v}, nil
+	case error:
+		return Value{kind: KindError, str: []byte(v.Error())}, nil
+	case int:
+		return Value{kind: KindInteger, num: int64(v)}, nil
+	case int64:
+		return Value{kind: KindInteger, num: v}, nil
+	case float64:
+		return Value{kind: KindDouble, float: v}, nil
+	case *big.Int:
+		return Value{kind: KindBigNumber, big: v}, nil
+	case bool:
+		n := int64(0)
+		if v {
+			n = 1
+		}
+		return Value{kind: KindBoolean, num: n}, nil
+	case Verbatim:
+		return Value{kind: KindVerbatim, str: v.Text, format: v.Format}, nil
+	case StreamedString:
+		var b []byte
+		for _, c := range v {
+			b = append(b, c...)
+		}
+		return Value{kind: KindBulkString, str: b}, nil
+	case [][]byte:
+		items := make([]Value, len(v))
+		for i, b := range v {
+			// empty strings stand for null, as in Encode
+			if len(b) > 0 {
+				items[i] = Value{kind: KindBulkString, str: b}
+			}
+		}
+		return Value{kind: KindArray, items: items}, nil
+	case []interface{}:
+		return aggregateOf(KindArray, v)
+	case Set:
+		return aggregateOf(KindSet, v)
+	case Push:
+		return aggregateOf(KindPush, v)
+	case Map:
+		return mapOf(v)
+	case Attribute:
+		val, err := ValueOf(v.Value)
+		if err != nil {
+			return Value{}, err
+		}
+		attrs, err := mapOf(v.Attrs)
+		if err != nil {
+			return Value{}, err
+		}
+		val.attrs = attrs.items
+		return val, nil
+	case Streamed:
+		return ValueOf(v.Aggregate)
+	}
+	return Value{}, errNotRESP
+}
+
+func aggregateOf(kind Kind, xs []interface{}) (Value, error) {
+	items := make([]Value, len(xs))
+	for i, x := range xs {
+		v, err := ValueOf(x)
+		if err != nil {
+			return Value{}, err
+		}
+		items[i] = v
+	}
+	return Value{kind: kind, items: items}, nil
+}
+
+func mapOf(m Map) (Value, error) {
+	items := make([]Value, 0, 2*len(m))
+	for _, p := range m {
+		k, err := ValueOf(p.Key)
+		if err != nil {
+			return Value{}, err
+		}
+		v, err := ValueOf(p.Value)
+		if err != nil {
+			return Value{}, err
+		}
+		items = append(items, k, v)
+	}
+	return Value{kind: KindMap, items: items}, nil
+}
+
+// ParseValue decodes the first value of input, like Parse.
+func ParseValue(input []byte) (Value, int, error) {
+	var p Parser
+	return p.ParseValue(input)
+}
+
+// ParseValue decodes the first value of input, like Parse.
+func (p *Parser) ParseValue(input []byte) (Value, int, error) {
+	x, n, err := p.Parse(input)
+	if err != nil {
+		return Value{}, 0, err
+	}
+	v, err := ValueOf(x)
+	return v, n, err
+}
+
+// Kind returns the RESP type of v.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// IsNull reports whether v is a null.
+func (v Value) IsNull() bool {
+	return v.kind == KindNull
+}
+
+// Bytes returns the contents of strings and errors, and the text form of
+// numbers and booleans. It returns nil for nulls and aggregates.
+func (v Value) Bytes() []byte {
+	switch v.kind {
+	case KindSimpleString, KindError, KindBulkString, KindBulkError, KindVerbatim:
+		return v.str
+	case KindInteger:
+		return strconv.AppendInt(nil, v.num, 10)
+	case KindDouble:
+		return appendDouble(nil, v.float)
+	case KindBigNumber:
+		return v.big.Append(nil, 10)
+	case KindBoolean:
+		if v.num != 0 {
+			return []byte("t")
+		}
+		return []byte("f")
+	}
+	return nil
+}
+
+// String returns the text of v as Bytes does. Aggregates are formatted for
+// debugging.
+func (v Value) String() string {
+	switch v.kind {
+	case KindArray, KindSet, KindPush:
+		return fmt.Sprint(v.items)
+	case KindMap:
+		return fmt.Sprint(v.Map())
+	}
+	return string(v.Bytes())
+}
+
+// Format returns the format of a verbatim string, e.g. "txt".
+func (v Value) Format() string {
+	return v.format
+}
+
+// Int returns v as an integer. Strings holding a number are converted, as
+// Redis often replies with numbers in bulk strings.
+func (v Value) Int() (int64, error) {
+	switch v.kind {
+	case KindInteger, KindBoolean:
+		return v.num, nil
+	case KindBigNumber:
+		if v.big.IsInt64() {
+			return v.big.Int64(), nil
+		}
+	case KindSimpleString, KindBulkString, KindVerbatim:
+		if n, err := strconv.ParseInt(string(v.str), 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("resp: can't convert %s %q to an integer", v.kind, v.String())
+}
+
+// Float returns v as a float64, converting integers and numeric strings.
+func (v Value) Float() (float64, error) {
+	switch v.kind {
+	case KindDouble:
+		return v.float, nil
+	case KindInteger:
+		return float64(v.num), nil
+	case KindBigNumber:
+		f, _ := new(big.Float).SetInt(v.big).Float64()
+		return f, nil
+	case KindSimpleString, KindBulkString, KindVerbatim:
+		if f, err := strconv.ParseFloat(string(v.str), 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("resp: can't convert %s %q to a float", v.kind, v.String())
+}
+
+// Bool returns v as a boolean. Integers are true unless 0.
+func (v Value) Bool() (bool, error) {
+	switch v.kind {
+	case KindBoolean, KindInteger:
+		return v.num != 0, nil
+	}
+	return false, fmt.Errorf("resp: can't convert %s %q to a boolean", v.kind, v.String())
+}
+
+// BigInt returns v as a big integer, converting integers and numeric
+// strings.
+func (v Value) BigInt() (*big.Int, error) {
+	switch v.kind {
+	case KindBigNumber:
+		return new(big.Int).Set(v.big), nil
+	case KindInteger:
+		return big.NewInt(v.num), nil
+	case KindSimpleString, KindBulkString, KindVerbatim:
+		if n, ok := new(big.Int).SetString(string(v.str), 10); ok {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("resp: can't convert %s %q to a big number", v.kind, v.String())
+}
+
+// Err returns the error carried by an error reply, or nil.
+func (v Value) Err() error {
+	switch v.kind {
+	case KindError:
+		return errors.New(string(v.str))
+	case KindBulkError:
+		return BulkError(v.str)
+	}
+	return nil
+}
+
+// Array returns the items of an array, set or push. A map yields its keys
+// and values alternated, the way RESP2 sends maps.
+func (v Value) Array() []Value {
+	if v.isAggregate() {
+		return v.items
+	}
+	return nil
+}
+
+func (v Value) isAggregate() bool {
+	switch v.kind {
+	case KindArray, KindSet, KindPush, KindMap:
+		return true
+	}
+	return false
+}
+
+// isMapLike reports whether Map can be used on v.
+func (v Value) isMapLike() bool {
+	return v.kind == KindMap || v.kind == KindArray && len(v.items)%2 == 0
+}
+
+// Len returns the number of items of an aggregate, or entries of a map.
+func (v Value) Len() int {
+	if v.kind == KindMap {
+		return len(v.items) / 2
+	}
+	return len(v.Array())
+}
+
+// Map returns the entries of a map. Arrays with an even number of items are
+// accepted too, since RESP2 sends maps that way.
+func (v Value) Map() []ValuePair {
+	if !v.isMapLike() {
+		return nil
+	}
+	m := make([]ValuePair, len(v.items)/2)
+	for i := range m {
+		m[i] = ValuePair{Key: v.items[2*i], Value: v.items[2*i+1]}
+	}
+	return m
+}
+
+// Get returns the value of a map entry with the given key.
+func (v Value) Get(key string) (Value, bool) {
+	for _, p := range v.Map() {
+		if string(p.Key.Bytes()) == key {
+			return p.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// Attrs returns the RESP3 attributes sent along with v, if any.
+func (v Value) Attrs() []ValuePair {
+	return Value{kind: KindMap, items: v.attrs}.Map()
+}
+
+// Interface converts v back to the form used by Decode and Encode.
+func (v Value) Interface() interface{} {
+	var x interface{}
+	switch v.kind {
+	case KindNull:
+		return nil
+	case KindSimpleString:
+		x = string(v.str)
+	case KindError:
+		x = v.Err()
+	case KindBulkError:
+		x = BulkError(v.str)
+	case KindInteger:
+		x = v.num
+	case KindDouble:
+		x = v.float
+	case KindBigNumber:
+		x = v.big
+	case KindBoolean:
+		x = v.num != 0
+	case KindBulkString:
+		x = v.str
+	case KindVerbatim:
+		x = Verbatim{Format: v.format, Text: v.str}
+	case KindArray:
+		x = interfaces(v.items)
+	case KindSet:
+		x = Set(interfaces(v.items))
+	case KindPush:
+		x = Push(interfaces(v.items))
+	case KindMap:
+		m := make(Map, 0, len(v.items)/2)
+		for _, p := range v.Map() {
+			m = append(m, Pair{Key: p.Key.Interface(), Value: p.Value.Interface()})
+		}
+		x = m
+	}
+	if len(v.attrs) > 0 {
+		attrs := Value{kind: KindMap, items: v.attrs}.Interface().(Map)
+		return Attribute{Attrs: attrs, Value: x}
+	}
+	return x
+}
+
+func interfaces(vs []Value) []interface{} {
+	xs := make([]interface{}, len(vs))
+	for i, v := range vs {
+		xs[i] = v.Interface()
+	}
+	return xs
+}
@@ -0,0 +1,71 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test__Value(t *testing.T) {
+	input := "%2\r\n+name\r\n$5\r\nredis\r\n+ports\r\n*2\r\n:6379\r\n$4\r\n6380\r\n"
+	v, n, err := ParseValue([]byte(input))
+	if err != nil || n != len(input) {
+		t.Fatalf("ParseValue: %v, %d bytes", err, n)
+	}
+	if v.Kind() != KindMap || v.Len() != 2 {
+		t.Fatalf("got %s of %d entries", v.Kind(), v.Len())
+	}
+	name, ok := v.Get("name")
+	if !ok || name.String() != "redis" {
+		t.Errorf("name: got %q", name)
+	}
+	ports, _ := v.Get("ports")
+	for i, want := range []int64{6379, 6380} {
+		if got, err := ports.Array()[i].Int(); err != nil || got != want {
+			t.Errorf("ports[%d]: got %d, %v want %d", i, got, err, want)
+		}
+	}
+	if _, err := name.Int(); err == nil {
+		t.Errorf("Int of %q: expected error", name)
+	}
+}
+
+func Test__ValueInterface(t *testing.T) {
+	tests := []interface{}{
+		nil,
+		"OK",
+		[]byte("foo"),
+		int64(1),
+		1.5,
+		true,
+		Verbatim{Format: "txt", Text: []byte("hi")},
+		BulkError("ERR x"),
+		[]interface{}{int64(1), []byte("a"), nil},
+		Set{"a"},
+		Push{"message"},
+		Map{{Key: "k", Value: []interface{}{int64(2)}}},
+		Attribute{Attrs: Map{{Key: "ttl", Value: int64(10)}}, Value: []byte("v")},
+	}
+	for _, x := range tests {
+		v, err := ValueOf(x)
+		if err != nil {
+			t.Fatalf("ValueOf(%v): %v", x, err)
+		}
+		if got := v.Interface(); !reflect.DeepEqual(got, x) {
+			t.Errorf("ValueOf(%#v).Interface(): got %#v", x, got)
+		}
+	}
+	if _, err := ValueOf(struct{}{}); err == nil {
+		t.Errorf("ValueOf(struct{}{}): expected error")
+	}
+}
+
+func Test__ValueErr(t *testing.T) {
+	v, _, _ := ParseValue([]byte("-ERR unknown command\r\n"))
+	if v.Kind() != KindError || v.Err() == nil || v.Err().Error() != "ERR unknown command" {
+		t.Errorf("got %s %v", v.Kind(), v.Err())
+	}
+	v, _, _ = ParseValue([]byte("+OK\r\n"))
+	if v.Err() != nil {
+		t.Errorf("Err of a simple string: got %v", v.Err())
+	}
+}
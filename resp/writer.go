@@ -0,0 +1,295 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// bufferedWriter is implemented by *bufio.Writer, *bytes.Buffer and
+// *strings.Builder, which Writer can use without an additional buffer.
+type bufferedWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+// Writer encodes values straight to an underlying writer, without building
+// the reply in memory first. Replies are buffered until Flush is called, so a
+// batch of pipelined replies can be sent at once. Write errors are sticky in
+// bufio.Writer, so only the last write of a frame is checked.
+type Writer struct {
+	w     bufferedWriter
+	proto int
+	// scratch holds frame headers and num the digits of doubles and big
+	// numbers, so formatting them doesn't allocate
+	scratch [24]byte
+	num     [64]byte
+}
+
+// NewWriter returns a RESP3 Writer that writes to w. Unless w is already
+// buffered it is wrapped in a bufio.Writer.
+func NewWriter(w io.Writer) *Writer {
+	bw, ok := w.(bufferedWriter)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	return &Writer{w: bw, proto: 3}
+}
+
+// SetProto sets the protocol version, 2 or 3, used by the following calls
+// to Encode.
+func (w *Writer) SetProto(proto int) {
+	w.proto = proto
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	if f, ok := w.w.(*bufio.Writer); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Encode writes x with the same mapping as EncodeProto. It returns
+// ErrInvalidSyntax for values that have no RESP representation, in which
+// case part of the reply may already have been written.
+func (w *Writer) Encode(x interface{}) error {
+	proto := w.proto
+	switch v := x.(type) {
+	case int:
+		return w.writeHeader(':', int64(v))
+	case int64:
+		return w.writeHeader(':', v)
+	case string:
+		return w.writeSimple('+', v)
+	case []byte:
+		return w.writeBulk('$', v)
+	case BulkError:
+		if proto == 2 {
+			return w.writeSimple('-', string(v))
+		}
+		return w.writeBulk('!', v)
+	case error:
+		return w.writeSimple('-', v.Error())
+	case float64:
+		if proto == 2 {
+			return w.writeBulk('$', appendDouble(w.num[:0], v))
+		}
+		w.w.WriteByte(',')
+		w.w.Write(appendDouble(w.num[:0], v))
+		return w.writeCRLF()
+	case *big.Int:
+		n := v.Append(w.num[:0], 10)
+		if proto == 2 {
+			return w.writeBulk('$', n)
+		}
+		w.w.WriteByte('(')
+		w.w.Write(n)
+		return w.writeCRLF()
+	case bool:
+		switch {
+		case proto == 2 && v:
+			_, err := w.w.WriteString(":1\r\n")
+			return err
+		case proto == 2:
+			_, err := w.w.WriteString(":0\r\n")
+			return err
+		case v:
+			_, err := w.w.WriteString("#t\r\n")
+			return err
+		}
+		_, err := w.w.WriteString("#f\r\n")
+		return err
+	case Verbatim:
+		if proto == 2 {
+			return w.writeBulk('$', v.Text)
+		}
+		w.writeHeader('=', int64(len(v.Text)+4))
+		w.w.WriteString(v.Format)
+		w.w.WriteByte(':')
+		w.w.Write(v.Text)
+		return w.writeCRLF()
+	case StreamedString:
+		if proto == 2 {
+			n := 0
+			for _, c := range v {
+				n += len(c)
+			}
+			w.writeHeader('$', int64(n))
+			for _, c := range v {
+				w.w.Write(c)
+			}
+			return w.writeCRLF()
+		}
+		w.w.WriteString("$?\r\n")
+		for _, c := range v {
+			if len(c) > 0 {
+				w.writeBulk(';', c)
+			}
+		}
+		_, err := w.w.WriteString(";0\r\n")
+		return err
+	case [][]byte:
+		w.writeHeader('*', int64(len(v)))
+		for _, b := range v {
+			// empty strings are sent as null to report missing keys
+			if len(b) == 0 {
+				w.writeNull()
+			} else {
+				w.writeBulk('$', b)
+			}
+		}
+		return nil
+	case []interface{}:
+		return w.writeAggregate('*', v)
+	case Set:
+		if proto == 2 {
+			return w.writeAggregate('*', v)
+		}
+		return w.writeAggregate('~', v)
+	case Push:
+		if proto == 2 {
+			return w.writeAggregate('*', v)
+		}
+		return w.writeAggregate('>', v)
+	case Map:
+		if proto == 2 {
+			w.writeHeader('*', int64(2*len(v)))
+		} else {
+			w.writeHeader('%', int64(len(v)))
+		}
+		return w.writePairs(v)
+	case Attribute:
+		if proto == 3 {
+			w.writeHeader('|', int64(len(v.Attrs)))
+			if err := w.writePairs(v.Attrs); err != nil {
+				return err
+			}
+		}
+		return w.Encode(v.Value)
+	case Streamed:
+		if proto == 2 {
+			return w.Encode(v.Aggregate)
+		}
+		var err error
+		switch items := v.Aggregate.(type) {
+		case []interface{}:
+			w.w.WriteString("*?\r\n")
+			err = w.writeItems(items)
+		case Set:
+			w.w.WriteString("~?\r\n")
+			err = w.writeItems(items)
+		case Map:
+			w.w.WriteString("%?\r\n")
+			err = w.writePairs(items)
+		default:
+			return ErrInvalidSyntax
+		}
+		if err != nil {
+			return err
+		}
+		_, err = w.w.WriteString(".\r\n")
+		return err
+	case Value:
+		return w.Encode(v.Interface())
+	case nil:
+		return w.writeNull()
+	}
+	return ErrInvalidSyntax
+}
+
+func (w *Writer) writeCRLF() error {
+	_, err := w.w.WriteString("\r\n")
+	return err
+}
+
+func (w *Writer) writeNull() error {
+	if w.proto == 2 {
+		_, err := w.w.WriteString(NIL2)
+		return err
+	}
+	_, err := w.w.WriteString(NIL)
+	return err
+}
+
+// writeSimple writes a simple string or error of type t. CR and LF are
+// replaced with spaces like Redis does, since they would end the reply
+// early and let the rest of s be read as more replies.
+func (w *Writer) writeSimple(t byte, s string) error {
+	w.w.WriteByte(t)
+	if strings.IndexAny(s, "\r\n") < 0 {
+		w.w.WriteString(s)
+	} else {
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			if c == '\r' || c == '\n' {
+				c = ' '
+			}
+			w.w.WriteByte(c)
+		}
+	}
+	return w.writeCRLF()
+}
+
+// writeHeader writes a type byte followed by a number, e.g. ":1\r\n" or
+// "*3\r\n".
+func (w *Writer) writeHeader(t byte, n int64) error {
+	b := append(w.scratch[:0], t)
+	b = strconv.AppendInt(b, n, 10)
+	b = append(b, '\r', '\n')
+	_, err := w.w.Write(b)
+	return err
+}
+
+// writeBulk writes a length prefixed string of type t.
+func (w *Writer) writeBulk(t byte, b []byte) error {
+	w.writeHeader(t, int64(len(b)))
+	w.w.Write(b)
+	return w.writeCRLF()
+}
+
+func (w *Writer) writeAggregate(t byte, items []interface{}) error {
+	if err := w.writeHeader(t, int64(len(items))); err != nil {
+		return err
+	}
+	return w.writeItems(items)
+}
+
+func (w *Writer) writeItems(items []interface{}) error {
+	for _, x := range items {
+		if err := w.Encode(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writePairs(m Map) error {
+	for _, p := range m {
+		if err := w.Encode(p.Key); err != nil {
+			return err
+		}
+		if err := w.Encode(p.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendDouble formats f the way RESP3 expects: the shortest representation
+// that round trips, and inf, -inf or nan for the special values.
+func appendDouble(dst []byte, f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return append(dst, "inf"...)
+	case math.IsInf(f, -1):
+		return append(dst, "-inf"...)
+	case math.IsNaN(f):
+		return append(dst, "nan"...)
+	}
+	return strconv.AppendFloat(dst, f, 'g', -1, 64)
+}
@@ -0,0 +1,85 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func Test__Writer(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		proto    int
+		expected string
+	}{
+		{[]interface{}{1, []byte("a"), nil}, 3, "*3\r\n:1\r\n$1\r\na\r\n_\r\n"},
+		{[]interface{}{1, []byte("a"), nil}, 2, "*3\r\n:1\r\n$1\r\na\r\n$-1\r\n"},
+		{Map{{Key: "k", Value: 2.5}}, 3, "%1\r\n+k\r\n,2.5\r\n"},
+		{Map{{Key: "k", Value: 2.5}}, 2, "*2\r\n+k\r\n$3\r\n2.5\r\n"},
+		// CR and LF in simple replies would inject more replies
+		{errors.New("ERR unknown subcommand 'x\r\n+OK'"), 2, "-ERR unknown subcommand 'x  +OK'\r\n"},
+		{"a\nb\rc", 3, "+a b c\r\n"},
+		{BulkError("ERR a\r\nb"), 2, "-ERR a  b\r\n"},
+		{BulkError("ERR a\r\nb"), 3, "!8\r\nERR a\r\nb\r\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		w := NewWriter(&b)
+		w.SetProto(tt.proto)
+		if err := w.Encode(tt.input); err != nil {
+			t.Fatalf("Encode(%v): %v", tt.input, err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		if got := b.String(); got != tt.expected {
+			t.Errorf("Encode(%v, %d): got %q want %q", tt.input, tt.proto, got, tt.expected)
+		}
+	}
+}
+
+func Test__WriterInvalidType(t *testing.T) {
+	w := NewWriter(ioutil.Discard)
+	if err := w.Encode(struct{}{}); err != ErrInvalidSyntax {
+		t.Errorf("got %v want %v", err, ErrInvalidSyntax)
+	}
+}
+
+// mgetReply is the reply to an MGET of n keys.
+func mgetReply(n int) [][]byte {
+	r := make([][]byte, n)
+	for i := range r {
+		r[i] = []byte(fmt.Sprintf("value:%d", i))
+	}
+	return r
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		// boxed once so the benchmark measures encoding only
+		var reply interface{} = mgetReply(n)
+		b.Run(fmt.Sprintf("MGET-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ioutil.Discard.Write([]byte(Encode(reply)))
+			}
+		})
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		// boxed once so the benchmark measures encoding only
+		var reply interface{} = mgetReply(n)
+		b.Run(fmt.Sprintf("MGET-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			w := NewWriter(ioutil.Discard)
+			for i := 0; i < b.N; i++ {
+				w.Encode(reply)
+				w.Flush()
+			}
+		})
+	}
+}
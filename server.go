@@ -1,95 +1,77 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/tinfoil-knight/tiny-redis/commands"
-	"github.com/tinfoil-knight/tiny-redis/resp"
-	"github.com/tinfoil-knight/tiny-redis/store"
+	"github.com/tinfoil-knight/tiny-redis/config"
+	"github.com/tinfoil-knight/tiny-redis/server"
 )
 
-var (
-	LF = []byte("\n")
-	SP = []byte(" ")
-)
-
-func untilEOF(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	if atEOF {
-		if len(data) == 0 {
-			return 0, nil, nil
-		}
-		return 0, []byte{}, nil
+func main() {
+	flag.String("bind", "[::]", "sets host")
+	flag.Int("port", 8001, "sets tcp port")
+	flag.Int("tls-port", 0, "sets the TLS port, see the tls-* parameters")
+	flag.String("unixsocket", "", "sets the path of a unix socket to listen on")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [/path/to/tiny-redis.conf]\n", os.Args[0])
+		flag.PrintDefaults()
 	}
-	return len(data), data, nil
-}
+	flag.Parse()
 
-func handleConn(kv *store.Store, c net.Conn) {
-	defer c.Close()
-	scanner := bufio.NewScanner(c)
-	scanner.Split(untilEOF)
-	scanner.Scan()
-	if err := scanner.Err(); err != nil {
-		panic(err)
-	}
-	byts := scanner.Bytes()
-	if !(len(byts) == 0) {
-		fmt.Printf("Recv: %+q\n", byts)
-		var val interface{}
-		if byts[0] == '*' {
-			// resp
-			val, _ = resp.Decode(byts)
-		} else {
-			// inline command format
-			val = bytes.Split(bytes.TrimSuffix(byts, LF), SP)
-		}
-		// TODO: find a better (faster) way to do type conversion here
-		// (encoding/gob?)
-		t := (val).([]interface{})
-		// error handling
-		s := make([][]byte, len(t))
-		for i, x := range t {
-			s[i] = x.([]byte)
+	reg := config.New()
+	if path := flag.Arg(0); path != "" {
+		if err := reg.Load(path); err != nil {
+			log.Fatal(err)
 		}
-		fmt.Printf("Parse: %+q\n", s)
-		// TODO(fix): flow control and error as per Redis
-		if len(s) != 0 {
-			r, err := commands.ExecuteCommand(kv, s)
-			if err != nil {
-				fmt.Printf("Send: %+q\n", resp.Encode(err))
-				c.Write([]byte(resp.Encode(err)))
-			} else {
-				fmt.Printf("Send: %+q\n", resp.Encode(r))
-				c.Write([]byte(resp.Encode(r)))
-			}
-		} else {
-			c.Write([]byte(resp.Encode(resp.ErrInvalidSyntax)))
+	}
+	// flags given on the command line override the config file
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if err == nil {
+			err = reg.Init(f.Name, f.Value.String())
 		}
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
-}
 
-func main() {
-	host := flag.String("bind", "[::]", "sets host")
-	port := flag.Int("port", 8001, "sets tcp port")
-	flag.Parse()
-	address := fmt.Sprintf("%s:%d", *host, *port)
-	l, err := net.Listen("tcp", address)
-	if err != nil {
+	logger := log.New(os.Stdout, "", 0)
+	srv := server.New(server.Config{
+		Registry: reg,
+		Logger:   logger,
+	})
+	if err := srv.Start(context.Background()); err != nil {
 		log.Fatal(err)
 	}
-	fmt.Printf("Listening at: %s\n", l.Addr())
-	defer l.Close()
-	kv := store.New()
-	for {
-		conn, err := l.Accept()
-		if err != nil {
-			log.Print(err)
-			continue
-		}
-		go handleConn(kv, conn)
+	if _, ok := srv.Addr().(*net.TCPAddr); ok {
+		fmt.Printf("Listening at: %s\n", srv.Addr())
+	}
+	if addr := srv.TLSAddr(); addr != nil {
+		fmt.Printf("Listening for TLS at: %s\n", addr)
+	}
+	if path := reg.String("unixsocket"); path != "" {
+		fmt.Printf("Listening on unix socket: %s\n", path)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		logger.Printf("Received %v scheduling shutdown...", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), server.ShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(ctx, true)
+	}()
+
+	if err := srv.Wait(); err != nil {
+		logger.Printf("Errors trying to shut down the server: %v", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,613 @@
+// Package server runs tiny-redis over TCP. It can be embedded in other
+// programs, e.g. to start an in-process instance from integration tests.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/acl"
+	"github.com/tinfoil-knight/tiny-redis/commands"
+	"github.com/tinfoil-knight/tiny-redis/config"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+var (
+	ErrServerClosed   = errors.New("server: closed")
+	ErrShutdownFailed = errors.New("ERR Errors trying to SHUTDOWN. Check logs.")
+
+	errInlineTooBig     = errors.New("ERR Protocol error: too big inline request")
+	errUnbalancedQuotes = errors.New("ERR Protocol error: unbalanced quotes in request")
+)
+
+const readBufferSize = 16 * 1024
+
+// ShutdownTimeout bounds how long a shutdown, by SHUTDOWN or by a signal,
+// waits for the clients to finish their commands.
+const ShutdownTimeout = 10 * time.Second
+
+// Config holds the settings for a Server.
+type Config struct {
+	// Bind is the IP or hostname to listen on, several addresses may be
+	// given separated by spaces. Defaults to "[::]".
+	Bind string
+	// Port is the TCP port. 0 picks a free port, see Server.Addr.
+	Port int
+	// Registry is the full configuration, as loaded from a config file and
+	// changed by CONFIG SET. When set, Bind and Port are read from it and the
+	// fields above are ignored.
+	Registry *config.Registry
+	// Store is the keyspace to serve. When nil, a store persisted at
+	// dir/dbfilename of the registry is opened.
+	Store *store.Store
+	// Logger receives connection and command traces. Nothing is logged
+	// when nil.
+	Logger *log.Logger
+}
+
+// Server is a tiny-redis instance.
+type Server struct {
+	// nextID is the last client ID handed out. It is accessed atomically
+	// and kept first for 64 bit alignment.
+	nextID int64
+
+	cfg   Config
+	reg   *config.Registry
+	kv    *store.Store
+	stats *commands.Stats
+	acl   *acl.ACL
+	log   *log.Logger
+	// tracing is set when the logger writes somewhere, the command traces
+	// aren't formatted otherwise
+	tracing bool
+
+	mu      sync.Mutex
+	ls      []net.Listener
+	tlsAddr net.Addr
+	conns   map[net.Conn]chan struct{}
+	closing chan struct{}
+	// stopped is closed once shutdown has completed, err holds its result
+	stopped chan struct{}
+	err     error
+	accept  sync.WaitGroup
+}
+
+// New creates a Server from cfg. It doesn't start listening, see Start.
+func New(cfg Config) *Server {
+	reg := cfg.Registry
+	if reg == nil {
+		reg = config.New()
+		if cfg.Bind != "" {
+			reg.Init("bind", cfg.Bind)
+		}
+		reg.Init("port", strconv.Itoa(cfg.Port))
+	}
+	dbPath := func() string {
+		return filepath.Join(reg.String("dir"), reg.String("dbfilename"))
+	}
+	kv := cfg.Store
+	if kv == nil {
+		kv = store.OpenDatabases(dbPath(), int(reg.Int("databases")))
+	}
+	reg.OnChange("dir", func(string) { kv.SetPath(dbPath()) })
+	reg.OnChange("dbfilename", func(string) { kv.SetPath(dbPath()) })
+	kv.SetEviction(eviction(reg))
+	for _, name := range []string{"maxmemory", "maxmemory-policy", "maxmemory-samples", "lfu-log-factor", "lfu-decay-time"} {
+		reg.OnChange(name, func(string) { kv.SetEviction(eviction(reg)) })
+	}
+	users := acl.New(commands.Categories)
+	users.SetRequirePass(reg.String("requirepass"))
+	reg.OnChange("requirepass", users.SetRequirePass)
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+	return &Server{
+		cfg:     cfg,
+		reg:     reg,
+		kv:      kv,
+		stats:   &commands.Stats{Start: time.Now()},
+		acl:     users,
+		log:     logger,
+		tracing: logger.Writer() != ioutil.Discard,
+		conns:   make(map[net.Conn]chan struct{}),
+		closing: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// eviction returns the memory limit configured in reg.
+func eviction(reg *config.Registry) store.Eviction {
+	policy, _ := store.ParsePolicy(reg.String("maxmemory-policy"))
+	return store.Eviction{
+		MaxMemory:    reg.Int("maxmemory"),
+		Policy:       policy,
+		Samples:      int(reg.Int("maxmemory-samples")),
+		LFULogFactor: int(reg.Int("lfu-log-factor")),
+		LFUDecayTime: int(reg.Int("lfu-decay-time")),
+	}
+}
+
+// Start binds the listener and serves connections in the background. The
+// server is closed when ctx is done.
+func (srv *Server) Start(ctx context.Context) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.isClosing() {
+		return ErrServerClosed
+	}
+	if srv.ls != nil {
+		return errors.New("server: already started")
+	}
+	if path := srv.reg.String("aclfile"); path != "" {
+		if err := srv.acl.Load(path); err != nil {
+			return fmt.Errorf("server: loading the ACL file: %w", err)
+		}
+	}
+	var tlsCfg *tls.Config
+	if srv.reg.Int("tls-port") != 0 {
+		cfg, err := tlsConfig(srv.reg)
+		if err != nil {
+			return fmt.Errorf("server: TLS: %w", err)
+		}
+		tlsCfg = cfg
+	}
+	port, err := srv.listen(srv.reg.String("port"), nil)
+	if err == nil && tlsCfg != nil {
+		n := len(srv.ls)
+		if _, err = srv.listen(srv.reg.String("tls-port"), tlsCfg); err == nil && len(srv.ls) > n {
+			srv.tlsAddr = srv.ls[n].Addr()
+		}
+	}
+	if path := srv.reg.String("unixsocket"); err == nil && path != "" {
+		err = srv.listenUnix(path, os.FileMode(srv.reg.Octal("unixsocketperm")))
+	}
+	if err == nil && len(srv.ls) == 0 {
+		err = errors.New("server: no address to listen on")
+	}
+	if err != nil {
+		for _, l := range srv.ls {
+			l.Close()
+		}
+		srv.ls = nil
+		return err
+	}
+	srv.reg.Init("port", port)
+	srv.accept.Add(len(srv.ls))
+	for _, l := range srv.ls {
+		go srv.serve(l)
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			srv.Close()
+		case <-srv.closing:
+		}
+	}()
+	return nil
+}
+
+// listen binds every address of the bind parameter to port, wrapping the
+// listeners in TLS when cfg is set. It returns the port, which is picked
+// by the first address when it is 0.
+func (srv *Server) listen(port string, cfg *tls.Config) (string, error) {
+	n := 0
+	hosts := strings.Fields(srv.reg.String("bind"))
+	for _, host := range hosts {
+		// a leading '-' marks an address that may be unavailable
+		optional := strings.HasPrefix(host, "-")
+		host = strings.Trim(strings.TrimPrefix(host, "-"), "[]")
+		l, err := net.Listen("tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			if optional {
+				srv.log.Printf("Skipping optional address %s: %v", host, err)
+				continue
+			}
+			return "", err
+		}
+		// with port 0 every address after the first reuses the picked port
+		port = strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+		if cfg != nil {
+			l = tls.NewListener(l, cfg)
+		}
+		srv.ls = append(srv.ls, l)
+		n++
+	}
+	// an empty bind listens on no TCP address, e.g. with only a unix socket
+	if n == 0 && len(hosts) > 0 {
+		return "", errors.New("server: no address to listen on")
+	}
+	return port, nil
+}
+
+// listenUnix listens on the unix socket at path, replacing a stale socket
+// left by a previous run. The permissions of the socket are set to perm
+// unless it is 0.
+func (srv *Server) listenUnix(path string, perm os.FileMode) error {
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			l.Close()
+			return err
+		}
+	}
+	srv.ls = append(srv.ls, l)
+	return nil
+}
+
+// Addr returns the address the server is listening on, or nil before Start.
+// With several bind addresses it is the first one, and it is the unix socket
+// when no TCP address is bound.
+func (srv *Server) Addr() net.Addr {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.ls) == 0 {
+		return nil
+	}
+	return srv.ls[0].Addr()
+}
+
+// TLSAddr returns the address the server accepts TLS connections on, or nil
+// when tls-port isn't set.
+func (srv *Server) TLSAddr() net.Addr {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.tlsAddr
+}
+
+// Registry returns the configuration of srv.
+func (srv *Server) Registry() *config.Registry {
+	return srv.reg
+}
+
+// Store returns the keyspace served by srv.
+func (srv *Server) Store() *store.Store {
+	return srv.kv
+}
+
+// Close stops the listener and closes all client connections immediately,
+// without saving.
+func (srv *Server) Close() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := srv.shutdown(ctx, false, nil)
+	if err == ErrServerClosed {
+		<-srv.stopped
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops the server gracefully. The listener is closed first, then
+// every connection finishes the commands it has already read and is closed.
+// If ctx is done before that, the remaining connections are closed
+// forcibly. When save is set the keyspace is written to disk last, and a
+// failure to do so is returned.
+func (srv *Server) Shutdown(ctx context.Context, save bool) error {
+	return srv.shutdown(ctx, save, nil)
+}
+
+// Wait blocks until the server has shut down and returns the shutdown error.
+func (srv *Server) Wait() error {
+	<-srv.stopped
+	return srv.err
+}
+
+func (srv *Server) isClosing() bool {
+	select {
+	case <-srv.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown implements Shutdown. self is the connection that requested the
+// shutdown, if any; it is not waited for since it is the caller.
+func (srv *Server) shutdown(ctx context.Context, save bool, self net.Conn) error {
+	srv.mu.Lock()
+	if srv.isClosing() {
+		srv.mu.Unlock()
+		return ErrServerClosed
+	}
+	close(srv.closing)
+	for _, l := range srv.ls {
+		l.Close()
+	}
+	pending := make(map[net.Conn]chan struct{}, len(srv.conns))
+	for c, done := range srv.conns {
+		if c == self {
+			continue
+		}
+		// wake up readers, commands already read are still executed
+		c.SetReadDeadline(time.Now())
+		pending[c] = done
+	}
+	srv.mu.Unlock()
+
+	srv.accept.Wait()
+	for c, done := range pending {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			srv.log.Printf("Closing connection from %s: %v", clientAddr(c), ctx.Err())
+			c.Close()
+			<-done
+		}
+	}
+
+	var err error
+	if save {
+		srv.log.Print("Saving the final snapshot before exiting.")
+		if err = srv.kv.Save(); err != nil {
+			srv.log.Printf("Error trying to save the DB: %v", err)
+		} else {
+			srv.log.Print("DB saved on disk")
+		}
+	}
+	srv.log.Print("tiny-redis is now ready to exit, bye bye...")
+	srv.err = err
+	close(srv.stopped)
+	return err
+}
+
+func (srv *Server) serve(l net.Listener) {
+	defer srv.accept.Done()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if srv.isClosing() {
+				return
+			}
+			srv.log.Print(err)
+			continue
+		}
+		done, ok := srv.track(conn)
+		if !ok {
+			conn.Close()
+			return
+		}
+		go srv.handleConn(conn, done)
+	}
+}
+
+func (srv *Server) track(c net.Conn) (chan struct{}, bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.isClosing() {
+		return nil, false
+	}
+	done := make(chan struct{})
+	srv.conns[c] = done
+	return done, true
+}
+
+func (srv *Server) untrack(c net.Conn) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.conns, c)
+}
+
+func (srv *Server) handleConn(c net.Conn, done chan struct{}) {
+	defer close(done)
+	defer srv.untrack(c)
+	defer c.Close()
+	defer func() {
+		// drop the client instead of the whole server if a command panics
+		if r := recover(); r != nil {
+			srv.log.Printf("Closing connection from %s: %v", clientAddr(c), r)
+		}
+	}()
+	atomic.AddInt64(&srv.stats.Connections, 1)
+	atomic.AddInt64(&srv.stats.Clients, 1)
+	defer atomic.AddInt64(&srv.stats.Clients, -1)
+	client := &commands.Client{
+		Store:  srv.kv,
+		Config: srv.reg,
+		Stats:  srv.stats,
+		ACL:    srv.acl,
+		ID:     atomic.AddInt64(&srv.nextID, 1),
+		User:   srv.acl.NoAuthUser(),
+		Addr:   clientAddr(c),
+	}
+	w := resp.NewWriter(c)
+	buf := make([]byte, readBufferSize)
+	// query holds the received data that doesn't form a whole command yet,
+	// parser how far it is parsed
+	var query []byte
+	var parser resp.CommandParser
+	for {
+		n, err := c.Read(buf)
+		if n > 0 {
+			query = append(query, buf[:n]...)
+			if limit := srv.reg.Int("client-query-buffer-limit"); int64(len(query)) > limit {
+				// as in Redis the client is dropped without a reply
+				srv.log.Printf("Closing client %s that reached max query buffer length (%d bytes)", clientAddr(c), limit)
+				return
+			}
+			rest, ok := srv.process(c, w, client, &parser, query)
+			if !ok {
+				return
+			}
+			if len(rest) < len(query) {
+				query = query[:copy(query, rest)]
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// process executes every command found in byts and writes the replies to w,
+// flushing them together once the whole chunk is handled. It returns the
+// trailing part of byts that holds an incomplete command, and false when
+// the connection must be closed. parser keeps the progress in that command
+// until the next call.
+func (srv *Server) process(c net.Conn, w *resp.Writer, client *commands.Client, parser *resp.CommandParser, byts []byte) ([]byte, bool) {
+	parser.MaxBulkLen = srv.reg.Int("proto-max-bulk-len")
+	for len(byts) > 0 {
+		var s [][]byte
+		var err error
+		if byts[0] == '*' {
+			// resp
+			var n int
+			s, n, err = parser.Parse(byts)
+			if err == resp.ErrIncomplete {
+				break
+			}
+			if err != nil {
+				return nil, srv.protocolError(c, w, err)
+			}
+			byts = byts[n:]
+			if len(s) == 0 {
+				// "*0" and "*-1" are ignored, as in Redis
+				continue
+			}
+		} else {
+			// inline command format
+			i := bytes.IndexByte(byts, '\n')
+			if i < 0 {
+				if len(byts) > resp.InlineMaxSize {
+					return nil, srv.protocolError(c, w, errInlineTooBig)
+				}
+				break
+			}
+			line := byts[:i]
+			byts = byts[i+1:]
+			if s, err = inlineArgs(line); err != nil {
+				return nil, srv.protocolError(c, w, err)
+			}
+			if len(s) == 0 {
+				// blank lines are ignored, as in Redis
+				continue
+			}
+		}
+		if srv.tracing {
+			srv.log.Printf("Parse: %+q\n", redact(s))
+		}
+		r, err := client.Execute(s)
+		if req, ok := r.(commands.ShutdownRequest); ok {
+			srv.log.Printf("User requested shutdown...")
+			// replies to the commands pipelined before SHUTDOWN
+			w.Flush()
+			ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+			err = srv.shutdown(ctx, req.Save, c)
+			cancel()
+			if err == nil {
+				return nil, false
+			}
+			// the server is stopped either way, report and hang up
+			w.Encode(ErrShutdownFailed)
+			w.Flush()
+			return nil, false
+		}
+		if err != nil {
+			r = err
+		}
+		srv.tracef("Send: %+q\n", r)
+		w.SetProto(client.Protocol())
+		if err := w.Encode(r); err != nil {
+			srv.log.Printf("Closing connection from %s: %v", clientAddr(c), err)
+			return nil, false
+		}
+	}
+	return byts, w.Flush() == nil
+}
+
+// tracef logs a trace of the commands of a connection.
+func (srv *Server) tracef(format string, v ...interface{}) {
+	if srv.tracing {
+		srv.log.Printf(format, v...)
+	}
+}
+
+// protocolError replies with err and reports that the connection must be
+// closed, since the rest of the stream can't be trusted.
+func (srv *Server) protocolError(c net.Conn, w *resp.Writer, err error) bool {
+	srv.log.Printf("Protocol error from %s: %v", clientAddr(c), err)
+	w.Encode(err)
+	w.Flush()
+	return false
+}
+
+// redacted replaces the secrets in the traces.
+var redacted = []byte("(redacted)")
+
+// redact returns the arguments of a command with the passwords of AUTH,
+// HELLO, ACL SETUSER and CONFIG SET requirepass redacted, like the slowlog
+// of Redis.
+func redact(s [][]byte) [][]byte {
+	r := append([][]byte(nil), s...)
+	switch strings.ToLower(string(s[0])) {
+	case "auth":
+		for i := 1; i < len(r); i++ {
+			r[i] = redacted
+		}
+	case "hello":
+		for i := 2; i+2 < len(r); i++ {
+			if strings.EqualFold(string(r[i]), "auth") {
+				r[i+1], r[i+2] = redacted, redacted
+				i += 2
+			}
+		}
+	case "acl":
+		if len(r) > 3 && strings.EqualFold(string(r[1]), "setuser") {
+			for i := 3; i < len(r); i++ {
+				r[i] = redacted
+			}
+		}
+	case "config":
+		if len(r) > 3 && strings.EqualFold(string(r[1]), "set") {
+			for i := 2; i+1 < len(r); i += 2 {
+				if strings.EqualFold(string(r[i]), "requirepass") {
+					r[i+1] = redacted
+				}
+			}
+		}
+	}
+	return r
+}
+
+// inlineArgs splits an inline command, e.g. `SET "a key" 'a value'`, with
+// the quoting rules of sdssplitargs in Redis. A trailing CR is dropped.
+func inlineArgs(line []byte) ([][]byte, error) {
+	args, err := config.SplitArgs(string(bytes.TrimSuffix(line, []byte("\r"))))
+	if err != nil {
+		return nil, errUnbalancedQuotes
+	}
+	s := make([][]byte, len(args))
+	for i, a := range args {
+		s[i] = []byte(a)
+	}
+	return s, nil
+}
+
+// clientAddr describes the peer of c. Clients of a unix socket have no
+// address, so the socket path is used like in Redis.
+func clientAddr(c net.Conn) string {
+	if addr := c.RemoteAddr(); addr != nil && addr.String() != "" {
+		return addr.String()
+	}
+	return c.LocalAddr().String() + ":0"
+}
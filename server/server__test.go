@@ -0,0 +1,485 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/config"
+	"github.com/tinfoil-knight/tiny-redis/resp"
+	"github.com/tinfoil-knight/tiny-redis/store"
+)
+
+// tempStore returns a store that saves its snapshots in a temporary
+// directory rather than in the working directory.
+func tempStore(t *testing.T) *store.Store {
+	return store.Open(filepath.Join(t.TempDir(), "dump.trdb"))
+}
+
+func startServer(t *testing.T) *Server {
+	t.Helper()
+	srv := New(Config{Bind: "127.0.0.1", Store: tempStore(t)})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func dial(t *testing.T, srv *Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	c, err := net.Dial("tcp", srv.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	return c, bufio.NewReader(c)
+}
+
+func expect(t *testing.T, r *bufio.Reader, want string) {
+	t.Helper()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatalf("read reply: %v (got %q want %q)", err, got, want)
+	}
+	if string(got) != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func Test__Server(t *testing.T) {
+	srv := startServer(t)
+	c, r := dial(t, srv)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"*1\r\n$4\r\nPING\r\n", "+PONG\r\n"},
+		{"*3\r\n$3\r\nSET\r\n$5\r\nhello\r\n$5\r\nworld\r\n", "+OK\r\n"},
+		{"*2\r\n$3\r\nGET\r\n$5\r\nhello\r\n", "$5\r\nworld\r\n"},
+		{"PING\n", "+PONG\r\n"},
+		{"*0\r\n*1\r\n$4\r\nPING\r\n", "+PONG\r\n"},
+	}
+	// commands are sent one after the other over a single connection
+	for _, tt := range tests {
+		if _, err := c.Write([]byte(tt.input)); err != nil {
+			t.Fatal(err)
+		}
+		expect(t, r, tt.expected)
+	}
+}
+
+func Test__ServerInline(t *testing.T) {
+	srv := startServer(t)
+	c, r := dial(t, srv)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"PING\r\n", "+PONG\r\n"},
+		{"  SET   k   v  \r\n", "+OK\r\n"},
+		{"\r\n\n", ""},
+		{"SET \"a key\" 'it\\'s'\n", "+OK\r\n"},
+		{"GET \"a key\"\n", "$4\r\nit's\r\n"},
+		{"SET bin \"\\x00\\xff\\r\\n\"\n", "+OK\r\n"},
+		{"GET bin\n", "$4\r\n\x00\xff\r\n\r\n"},
+		{"ECHO \"\"\n", "$0\r\n\r\n"},
+	}
+	for _, tt := range tests {
+		if _, err := c.Write([]byte(tt.input)); err != nil {
+			t.Fatal(err)
+		}
+		expect(t, r, tt.expected)
+	}
+}
+
+func Test__ServerInlineErrors(t *testing.T) {
+	srv := startServer(t)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"SET k \"v\n", "-ERR Protocol error: unbalanced quotes in request\r\n"},
+		{"SET k 'v'x\n", "-ERR Protocol error: unbalanced quotes in request\r\n"},
+		{"PING\nSET " + strings.Repeat("x", resp.InlineMaxSize), "+PONG\r\n-ERR Protocol error: too big inline request\r\n"},
+	}
+	for _, tt := range tests {
+		c, r := dial(t, srv)
+		c.Write([]byte(tt.input))
+		expect(t, r, tt.expected)
+		if _, err := r.ReadByte(); err != io.EOF {
+			t.Errorf("%q: connection still open after protocol error: %v", tt.input, err)
+		}
+	}
+}
+
+func Test__ServerPipeline(t *testing.T) {
+	srv := startServer(t)
+	c, r := dial(t, srv)
+	c.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n*1\r\n$4\r\nPING\r\n"))
+	expect(t, r, "+OK\r\n$1\r\nv\r\n+PONG\r\n")
+}
+
+func Test__ServerClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := New(Config{Bind: "127.0.0.1", Store: tempStore(t)})
+	if err := srv.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	addr := srv.Addr().String()
+	c, r := dial(t, srv)
+
+	cancel()
+	srv.Wait()
+
+	if _, err := r.ReadByte(); err == nil {
+		t.Errorf("connection still open after shutdown")
+	}
+	c.Close()
+	if _, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		t.Errorf("listener still accepting after shutdown")
+	}
+	if err := srv.Start(context.Background()); err != ErrServerClosed {
+		t.Errorf("Start after Close: got %v want %v", err, ErrServerClosed)
+	}
+}
+
+func Test__ServerShutdownCommand(t *testing.T) {
+	srv := New(Config{Bind: "127.0.0.1", Store: tempStore(t)})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	other, otherR := dial(t, srv)
+	other.Write([]byte("*1\r\n$4\r\nPING\r\n"))
+	expect(t, otherR, "+PONG\r\n")
+
+	c, r := dial(t, srv)
+	c.Write([]byte("*2\r\n$8\r\nSHUTDOWN\r\n$5\r\nFORCE\r\n"))
+	expect(t, r, "-ERR syntax error\r\n")
+	c.Write([]byte("*2\r\n$8\r\nSHUTDOWN\r\n$6\r\nNOSAVE\r\n"))
+
+	if err := srv.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("SHUTDOWN: got %v want connection closed", err)
+	}
+	if _, err := otherR.ReadByte(); err == nil {
+		t.Errorf("idle connection still open after SHUTDOWN")
+	}
+}
+
+func Test__ServerShutdownDrains(t *testing.T) {
+	srv := New(Config{Bind: "127.0.0.1", Store: tempStore(t)})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c, r := dial(t, srv)
+	c.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"))
+	expect(t, r, "+OK\r\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx, false); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if v, _ := srv.Store().Get([]byte("k")); string(v) != "v" {
+		t.Errorf("write before shutdown lost: got %q", v)
+	}
+	if err := srv.Shutdown(ctx, false); err != ErrServerClosed {
+		t.Errorf("second Shutdown: got %v want %v", err, ErrServerClosed)
+	}
+}
+
+func Test__ServerHELLO(t *testing.T) {
+	srv := startServer(t)
+	c, r := dial(t, srv)
+	c.Write([]byte("*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n"))
+	expect(t, r, "$-1\r\n")
+	c.Write([]byte("*2\r\n$5\r\nHELLO\r\n$1\r\n3\r\n"))
+	expect(t, r, "%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.0.0\r\n"+
+		"$5\r\nproto\r\n:3\r\n$2\r\nid\r\n:1\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n"+
+		"$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n")
+	c.Write([]byte("*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n"))
+	expect(t, r, "_\r\n")
+}
+
+func Test__ServerBinaryValues(t *testing.T) {
+	srv := startServer(t)
+	c, r := dial(t, srv)
+
+	value := "foo\r\nbar\x00\xff"
+	c.Write([]byte(fmt.Sprintf("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$%d\r\n%s\r\n", len(value), value)))
+	expect(t, r, "+OK\r\n")
+	c.Write([]byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"))
+	expect(t, r, fmt.Sprintf("$%d\r\n%s\r\n", len(value), value))
+
+	// a value larger than a single read, sent in pieces
+	big := strings.Repeat("x", 3*readBufferSize)
+	cmd := fmt.Sprintf("*3\r\n$3\r\nSET\r\n$3\r\nbig\r\n$%d\r\n%s\r\n", len(big), big)
+	for i := 0; i < len(cmd); i += 1000 {
+		end := i + 1000
+		if end > len(cmd) {
+			end = len(cmd)
+		}
+		c.Write([]byte(cmd[i:end]))
+	}
+	expect(t, r, "+OK\r\n")
+	if v, _ := srv.Store().Get([]byte("big")); string(v) != big {
+		t.Errorf("big value: got %d bytes want %d", len(v), len(big))
+	}
+}
+
+func Test__ServerMaxBulkLen(t *testing.T) {
+	srv := startServer(t)
+	if err := srv.Registry().Set("proto-max-bulk-len", "1mb"); err != nil {
+		t.Fatal(err)
+	}
+	c, r := dial(t, srv)
+	c.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1048577\r\n"))
+	expect(t, r, "-ERR Protocol error: invalid bulk length\r\n")
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("connection still open after protocol error: %v", err)
+	}
+}
+
+func Test__ServerMaxMemory(t *testing.T) {
+	srv := startServer(t)
+	c, r := dial(t, srv)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"SET a 1\r\n", "+OK\r\n"},
+		{"CONFIG SET maxmemory 1\r\n", "+OK\r\n"},
+		{"SET b 1\r\n", "-OOM command not allowed when used memory > 'maxmemory'.\r\n"},
+		{"GET a\r\n", "$1\r\n1\r\n"},
+		// every key is evicted, the buckets alone are over the limit
+		{"CONFIG SET maxmemory-policy allkeys-lru\r\n", "+OK\r\n"},
+		{"GET a\r\n", "$-1\r\n"},
+		{"CONFIG SET maxmemory 0\r\n", "+OK\r\n"},
+		{"SET b 1\r\n", "+OK\r\n"},
+	}
+	for _, tt := range tests {
+		c.Write([]byte(tt.input))
+		expect(t, r, tt.expected)
+	}
+	if n := srv.Store().EvictedKeys(); n != 1 {
+		t.Errorf("got %d evicted keys", n)
+	}
+}
+
+func Test__ServerProtocolErrors(t *testing.T) {
+	srv := startServer(t)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"*1\r\n:1\r\n", "-ERR Protocol error: expected '$', got ':'\r\n"},
+		{"*x\r\n", "-ERR Protocol error: invalid multibulk length\r\n"},
+		{"*1\r\n$x\r\n", "-ERR Protocol error: invalid bulk length\r\n"},
+		{"*1\r\n$4\r\nPINGPONG\r\n", "-ERR Protocol error: invalid bulk length\r\n"},
+		// replies to the commands before the error are sent
+		{"*1\r\n$4\r\nPING\r\n*" + strings.Repeat("1", resp.InlineMaxSize+1), "+PONG\r\n-ERR Protocol error: too big mbulk count string\r\n"},
+	}
+	for _, tt := range tests {
+		c, r := dial(t, srv)
+		c.Write([]byte(tt.input))
+		expect(t, r, tt.expected)
+		if _, err := r.ReadByte(); err != io.EOF {
+			t.Errorf("%.20q: connection still open after protocol error: %v", tt.input, err)
+		}
+	}
+}
+
+func Test__ServerQueryBufferLimit(t *testing.T) {
+	srv := startServer(t)
+	if err := srv.Registry().Set("client-query-buffer-limit", "1mb"); err != nil {
+		t.Fatal(err)
+	}
+	c, r := dial(t, srv)
+	// a valid bulk length, but the value would go past the limit
+	c.Write([]byte(fmt.Sprintf("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$%d\r\n", 2*1024*1024)))
+	chunk := []byte(strings.Repeat("x", 64*1024))
+	for i := 0; i < 32; i++ {
+		if _, err := c.Write(chunk); err != nil {
+			break
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF && !isReset(err) {
+		t.Errorf("connection still open past the query buffer limit: %v", err)
+	}
+	if _, ok := srv.Store().Get([]byte("k")); ok {
+		t.Errorf("k was set")
+	}
+}
+
+// isReset reports whether err is a connection reset, which closing a
+// connection with unread data may cause.
+func isReset(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "connection reset")
+}
+
+func Test__ServerAuth(t *testing.T) {
+	srv := startServer(t)
+	if err := srv.Registry().Set("requirepass", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	c, r := dial(t, srv)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"PING\r\n", "-NOAUTH Authentication required.\r\n"},
+		{"AUTH wrong\r\n", "-WRONGPASS invalid username-password pair or user is disabled.\r\n"},
+		{"AUTH secret\r\n", "+OK\r\n"},
+		{"ACL WHOAMI\r\n", "$7\r\ndefault\r\n"},
+	}
+	for _, tt := range tests {
+		c.Write([]byte(tt.input))
+		expect(t, r, tt.expected)
+	}
+}
+
+func Test__ServerACLFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "users.acl")
+	ioutil.WriteFile(path, []byte("user default off\nuser alice on nopass ~alice:* +@string\n"), 0600)
+
+	reg := config.New()
+	reg.Init("bind", "127.0.0.1")
+	reg.Init("port", "0")
+	reg.Init("aclfile", path)
+	srv := New(Config{Registry: reg, Store: tempStore(t)})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Close()
+	c, r := dial(t, srv)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"PING\r\n", "-NOAUTH Authentication required.\r\n"},
+		{"AUTH alice x\r\n", "+OK\r\n"},
+		{"SET alice:1 v\r\n", "+OK\r\n"},
+		{"SET bob:1 v\r\n", "-NOPERM No permissions to access a key\r\n"},
+		{"PING\r\n", "-NOPERM User alice has no permissions to run the 'ping' command\r\n"},
+	}
+	for _, tt := range tests {
+		c.Write([]byte(tt.input))
+		expect(t, r, tt.expected)
+	}
+
+	ioutil.WriteFile(path, []byte("user alice on +nosuch\n"), 0600)
+	bad := New(Config{Registry: reg, Store: tempStore(t)})
+	if err := bad.Start(context.Background()); err == nil {
+		bad.Close()
+		t.Errorf("Start with an invalid ACL file: got no error")
+	}
+}
+
+func Test__ServerUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tiny-redis.sock")
+	// a socket left behind by a previous run
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	tests := []struct {
+		bind string
+		tcp  bool
+	}{
+		{"127.0.0.1", true},
+		// an empty bind only listens on the socket
+		{"", false},
+	}
+	for _, tt := range tests {
+		reg := config.New()
+		reg.Init("bind", tt.bind)
+		reg.Init("port", "0")
+		reg.Init("unixsocket", path)
+		reg.Init("unixsocketperm", "700")
+		srv := New(Config{Registry: reg, Store: tempStore(t)})
+		if err := srv.Start(context.Background()); err != nil {
+			t.Fatalf("bind %q: Start: %v", tt.bind, err)
+		}
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0700 {
+			t.Errorf("bind %q: socket permissions: got %v, %v want 0700", tt.bind, fi.Mode(), err)
+		}
+		if _, ok := srv.Addr().(*net.TCPAddr); ok != tt.tcp {
+			t.Errorf("bind %q: got address %v", tt.bind, srv.Addr())
+		}
+		c, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatalf("bind %q: Dial: %v", tt.bind, err)
+		}
+		c.SetDeadline(time.Now().Add(5 * time.Second))
+		c.Write([]byte("PING\r\n"))
+		expect(t, bufio.NewReader(c), "+PONG\r\n")
+		c.Close()
+		srv.Close()
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("bind %q: the socket wasn't removed on Close: %v", tt.bind, err)
+		}
+	}
+}
+
+func Test__Redact(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"AUTH secret", "AUTH (redacted)"},
+		{"auth bob secret", "auth (redacted) (redacted)"},
+		{"HELLO 3 AUTH bob secret SETNAME cli", "HELLO 3 AUTH (redacted) (redacted) SETNAME cli"},
+		{"ACL SETUSER bob on >secret +@all", "ACL SETUSER bob (redacted) (redacted) (redacted)"},
+		{"ACL WHOAMI", "ACL WHOAMI"},
+		{"CONFIG SET maxmemory 1mb requirepass secret", "CONFIG SET maxmemory 1mb requirepass (redacted)"},
+		{"SET AUTH secret", "SET AUTH secret"},
+	}
+	for _, tt := range tests {
+		fields := strings.Fields(tt.input)
+		var args [][]byte
+		for _, a := range fields {
+			args = append(args, []byte(a))
+		}
+		var got []string
+		for _, a := range redact(args) {
+			got = append(got, string(a))
+		}
+		if strings.Join(got, " ") != tt.expected {
+			t.Errorf("redact(%q): got %q want %q", tt.input, strings.Join(got, " "), tt.expected)
+		}
+		for i, a := range args {
+			if string(a) != fields[i] {
+				t.Errorf("redact(%q) modified its input", tt.input)
+			}
+		}
+	}
+}
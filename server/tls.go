@@ -0,0 +1,52 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/tinfoil-knight/tiny-redis/config"
+)
+
+// tlsConfig builds the configuration of the TLS listeners from the tls-*
+// parameters of reg.
+func tlsConfig(reg *config.Registry) (*tls.Config, error) {
+	certFile, keyFile := reg.String("tls-cert-file"), reg.String("tls-key-file")
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("tls-cert-file and tls-key-file must be set")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if caFile := reg.String("tls-ca-cert-file"); caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+	}
+	switch reg.String("tls-auth-clients") {
+	case "yes":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return cfg, nil
+	}
+	// without a CA the system roots would be trusted to sign client
+	// certificates
+	if cfg.ClientCAs == nil {
+		return nil, errors.New("tls-ca-cert-file must be set to authenticate clients")
+	}
+	return cfg, nil
+}
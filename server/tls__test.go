@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tinfoil-knight/tiny-redis/config"
+)
+
+// testCA is a self-signed CA issuing the certificates of a test.
+type testCA struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	ca := &testCA{dir: dir, pool: x509.NewCertPool()}
+	ca.cert, ca.key = ca.issue(t, "ca", &x509.Certificate{
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	})
+	ca.pool.AddCert(ca.cert)
+	return ca
+}
+
+// issue signs tmpl with the CA, or self-signs it when the CA has no key
+// yet, and saves it as name.crt and name.key.
+func (ca *testCA) issue(t *testing.T, name string, tmpl *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.Subject = pkix.Name{CommonName: name}
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	parent, signer := tmpl, key
+	if ca.key != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(ca.path(name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(ca.path(name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return cert, key
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+// freePort returns a TCP port that was free a moment ago, since tls-port 0
+// disables TLS rather than picking one.
+func freePort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
+
+func Test__ServerTLS(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "server", &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	})
+	ca.issue(t, "client", &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	clientCert, err := tls.LoadX509KeyPair(ca.path("client.crt"), ca.path("client.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		authClients string
+		withCert    bool
+		ok          bool
+	}{
+		{"yes", true, true},
+		{"yes", false, false},
+		{"optional", true, true},
+		{"optional", false, true},
+		{"no", false, true},
+	}
+	for _, tt := range tests {
+		reg := config.New()
+		reg.Init("bind", "127.0.0.1")
+		reg.Init("port", "0")
+		reg.Init("tls-port", freePort(t))
+		reg.Init("tls-cert-file", ca.path("server.crt"))
+		reg.Init("tls-key-file", ca.path("server.key"))
+		reg.Init("tls-ca-cert-file", ca.path("ca.crt"))
+		reg.Init("tls-auth-clients", tt.authClients)
+		srv := New(Config{Registry: reg, Store: tempStore(t)})
+		if err := srv.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+
+		cfg := &tls.Config{RootCAs: ca.pool}
+		if tt.withCert {
+			cfg.Certificates = []tls.Certificate{clientCert}
+		}
+		c, err := tls.Dial("tcp", srv.TLSAddr().String(), cfg)
+		if err != nil {
+			t.Fatalf("%s: Dial: %v", tt.authClients, err)
+		}
+		c.SetDeadline(time.Now().Add(5 * time.Second))
+		c.Write([]byte("PING\r\n"))
+		line, err := bufio.NewReader(c).ReadString('\n')
+		if tt.ok && (err != nil || line != "+PONG\r\n") {
+			t.Errorf("tls-auth-clients %s, client cert %v: got %q, %v want +PONG", tt.authClients, tt.withCert, line, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("tls-auth-clients %s, client cert %v: got %q want a handshake error", tt.authClients, tt.withCert, line)
+		}
+		c.Close()
+
+		// plaintext is still served on port
+		pc, r := dial(t, srv)
+		pc.Write([]byte("PING\r\n"))
+		expect(t, r, "+PONG\r\n")
+		srv.Close()
+	}
+}
+
+func Test__ServerTLSConfigErrors(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "server", &x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+
+	tests := []struct {
+		params map[string]string
+	}{
+		{map[string]string{}},
+		{map[string]string{"tls-cert-file": ca.path("server.crt"), "tls-key-file": ca.path("missing.key")}},
+		// tls-auth-clients defaults to yes, which needs a CA
+		{map[string]string{"tls-cert-file": ca.path("server.crt"), "tls-key-file": ca.path("server.key")}},
+		{map[string]string{"tls-cert-file": ca.path("server.crt"), "tls-key-file": ca.path("server.key"), "tls-ca-cert-file": ca.path("server.key")}},
+	}
+	for _, tt := range tests {
+		reg := config.New()
+		reg.Init("bind", "127.0.0.1")
+		reg.Init("port", "0")
+		reg.Init("tls-port", freePort(t))
+		for k, v := range tt.params {
+			reg.Init(k, v)
+		}
+		srv := New(Config{Registry: reg, Store: tempStore(t)})
+		if err := srv.Start(context.Background()); err == nil {
+			srv.Close()
+			t.Errorf("Start(%v): got no error", tt.params)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DB is one of the numbered databases of a Store. It is safe for concurrent
+// use.
+type DB struct {
+	kv *Store
+
+	mu sync.RWMutex
+	d  *dict
+}
+
+func newDB(kv *Store) *DB {
+	return &DB{kv: kv, d: newDict()}
+}
+
+// Set stores the string value at key, which counts as an access for the
+// eviction policies.
+func (db *DB) Set(key []byte, value []byte) {
+	db.SetValue(key, value)
+}
+
+// SetValue is like Set for a value of any type: a []byte or a *ZSet.
+func (db *DB) SetValue(key []byte, value interface{}) {
+	ev := db.kv.eviction()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.set(ev, string(key), value)
+}
+
+func (db *DB) set(ev Eviction, key string, value interface{}) *entry {
+	size := db.d.size
+	e := db.d.set(key, value)
+	if db.d.size > size {
+		e.clock = ev.initClock()
+	} else {
+		e.clock = ev.accessClock(e.clock)
+	}
+	return e
+}
+
+// Update calls fn with the string value of key, padded with zero bytes to
+// at least n bytes, and stores the result. fn modifies the value in place
+// and, unlike with Get and Set, the value is only copied when it may be
+// referenced elsewhere, e.g. by a reply, so that SETBIT on a large value
+// doesn't copy it every time. key must hold a string if it exists.
+func (db *DB) Update(key []byte, n int64, fn func(v []byte)) {
+	ev := db.kv.eviction()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var v []byte
+	owned := false
+	if e := db.d.find(string(key)); e != nil {
+		v, _ = e.value.([]byte)
+		owned = e.owned != 0
+	}
+	if n < int64(len(v)) {
+		n = int64(len(v))
+	}
+	if owned && n <= int64(cap(v)) {
+		// the capacity past the end is still zeroed, owned values only grow
+		v = v[:n]
+	} else {
+		size := n
+		if n > int64(len(v)) {
+			// grows greedily like the sds strings of Redis
+			if n < 1<<20 {
+				size = 2 * n
+			} else {
+				size = n + 1<<20
+			}
+		}
+		r := make([]byte, n, size)
+		copy(r, v)
+		v = r
+	}
+	fn(v)
+	db.set(ev, string(key), v).owned = 1
+}
+
+// Get returns the value of key and records the access for the eviction
+// policies. ok reports whether key exists, value is nil when it isn't a
+// string.
+func (db *DB) Get(key []byte) (value []byte, ok bool) {
+	v, ok := db.Value(key)
+	value, _ = v.([]byte)
+	return value, ok
+}
+
+// Value is like Get for a value of any type.
+func (db *DB) Value(key []byte) (value interface{}, ok bool) {
+	return db.value(key, true)
+}
+
+// Borrow is like Value, but the value must neither be modified nor used
+// after the next Update of key, e.g. in a reply. Update can then still
+// modify it in place.
+func (db *DB) Borrow(key []byte) (value interface{}, ok bool) {
+	return db.value(key, false)
+}
+
+func (db *DB) value(key []byte, share bool) (interface{}, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	e := db.d.find(string(key))
+	if e == nil {
+		return nil, false
+	}
+	// concurrent readers may lose an access, it is an approximation anyway
+	ev := db.kv.eviction()
+	atomic.StoreUint32(&e.clock, ev.accessClock(atomic.LoadUint32(&e.clock)))
+	if share {
+		e.share()
+	}
+	return e.value, true
+}
+
+func (db *DB) Del(key []byte) {
+	db.delete(string(key))
+}
+
+func (db *DB) delete(key string) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.d.del(key)
+}
+
+// Rename moves the value of src to dst, replacing dst unless nx is set. It
+// reports whether src exists and whether the value was moved.
+func (db *DB) Rename(src, dst []byte, nx bool) (found, renamed bool) {
+	ev := db.kv.eviction()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	v, ok := db.d.get(string(src))
+	if !ok {
+		return false, false
+	}
+	if _, exists := db.d.get(string(dst)); exists && nx {
+		return true, false
+	}
+	db.d.del(string(src))
+	db.set(ev, string(dst), v)
+	return true, true
+}
+
+// Len returns the number of keys.
+func (db *DB) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.d.size
+}
+
+// memory returns an estimate of the memory used by the keys and values.
+func (db *DB) memory() int64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.d.mem + int64(len(db.d.table))*8
+}
+
+// Flush removes every key.
+func (db *DB) Flush() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.d = newDict()
+}
+
+// Range calls fn for every key until it returns false. fn must not modify
+// the database.
+func (db *DB) Range(fn func(key string, value interface{}) bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, e := range db.d.table {
+		for ; e != nil; e = e.next {
+			e.share()
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// Scan continues the iteration at cursor, calling fn for the keys of the
+// buckets it visits until count keys were seen or the iteration is over.
+// It returns the cursor to continue from, 0 at the end. Keys present for
+// the whole iteration are returned at least once even when others are
+// added or removed in between, see dict.scan.
+func (db *DB) Scan(cursor uint64, count int, fn func(key string, value interface{})) uint64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return scanDict(db.d, cursor, count, func(e *entry) {
+		e.share()
+		fn(e.key, e.value)
+	})
+}
+
+// scanDict calls d.scan from cursor until count entries were seen or the
+// iteration is over and returns the cursor to continue from.
+func scanDict(d *dict, cursor uint64, count int, fn func(e *entry)) uint64 {
+	seen := 0
+	// bounds the work on a sparse table, like Redis, without overflowing
+	// for a huge count
+	buckets := count
+	if buckets <= maxInt/10 {
+		buckets *= 10
+	}
+	for ; buckets > 0; buckets-- {
+		cursor = d.scan(cursor, func(e *entry) {
+			fn(e)
+			seen++
+		})
+		if cursor == 0 || seen >= count {
+			break
+		}
+	}
+	return cursor
+}
+
+// RandomKey returns a random key, ok is false when the database is empty.
+func (db *DB) RandomKey() (key string, ok bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if e := db.d.random(); e != nil {
+		return e.key, true
+	}
+	return "", false
+}
+
+func (db *DB) snapshot() map[string]record {
+	tmp := make(map[string]record)
+	db.Range(func(k string, v interface{}) bool {
+		switch v := v.(type) {
+		case []byte:
+			tmp[k] = record{String: v}
+		case *ZSet:
+			tmp[k] = record{ZSet: v.Members()}
+		}
+		return true
+	})
+	return tmp
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"math/rand"
+	"sync/atomic"
+)
+
+// minTableSize is the smallest number of buckets of a dict.
+const minTableSize = 4
+
+type entry struct {
+	key string
+	// value is a []byte for strings or a *ZSet, or the *zslNode of a
+	// member in the dict of a ZSet
+	value interface{}
+	// size is the memory of value when it was stored
+	size int64
+	next *entry
+	// clock tracks the accesses for the eviction policies, it is accessed
+	// atomically, see Eviction.accessClock
+	clock uint32
+	// owned is 1 while the []byte value is referenced by the dict only, it
+	// can then be modified in place by DB.Update. It is cleared atomically
+	// when the value is handed out.
+	owned uint32
+}
+
+// share records that the value of e may be referenced outside the dict.
+func (e *entry) share() {
+	if atomic.LoadUint32(&e.owned) != 0 {
+		atomic.StoreUint32(&e.owned, 0)
+	}
+}
+
+func (e *entry) memory() int64 {
+	return entryOverhead + int64(len(e.key)) + e.size
+}
+
+// valueSize estimates the memory used by a value.
+func valueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case *ZSet:
+		return v.memory()
+	}
+	return 0
+}
+
+// dict is a chained hash table with a power of two number of buckets. It
+// is iterated with a cursor like Redis dicts, see scan. It isn't safe for
+// concurrent use.
+type dict struct {
+	seed  maphash.Seed
+	table []*entry
+	size  int
+	// mem is the memory of the entries, see entry.memory
+	mem int64
+}
+
+func newDict() *dict {
+	return &dict{
+		seed:  maphash.MakeSeed(),
+		table: make([]*entry, minTableSize),
+	}
+}
+
+func (d *dict) bucket(key string) uint64 {
+	var h maphash.Hash
+	h.SetSeed(d.seed)
+	h.WriteString(key)
+	return h.Sum64() & uint64(len(d.table)-1)
+}
+
+func (d *dict) find(key string) *entry {
+	for e := d.table[d.bucket(key)]; e != nil; e = e.next {
+		if e.key == key {
+			return e
+		}
+	}
+	return nil
+}
+
+func (d *dict) get(key string) (interface{}, bool) {
+	if e := d.find(key); e != nil {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// set stores value at key and returns its entry.
+func (d *dict) set(key string, value interface{}) *entry {
+	size := valueSize(value)
+	if e := d.find(key); e != nil {
+		d.mem += size - e.size
+		e.value, e.size, e.owned = value, size, 0
+		return e
+	}
+	i := d.bucket(key)
+	e := &entry{key: key, value: value, size: size, next: d.table[i]}
+	d.table[i] = e
+	d.size++
+	d.mem += e.memory()
+	if d.size > len(d.table) {
+		d.resize(len(d.table) * 2)
+	}
+	return e
+}
+
+func (d *dict) del(key string) bool {
+	i := d.bucket(key)
+	for p := &d.table[i]; *p != nil; p = &(*p).next {
+		if (*p).key == key {
+			d.mem -= (*p).memory()
+			*p = (*p).next
+			d.size--
+			if len(d.table) > minTableSize && d.size < len(d.table)/8 {
+				d.resize(len(d.table) / 2)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (d *dict) resize(n int) {
+	old := d.table
+	d.table = make([]*entry, n)
+	for _, e := range old {
+		for e != nil {
+			next := e.next
+			i := d.bucket(e.key)
+			e.next = d.table[i]
+			d.table[i] = e
+			e = next
+		}
+	}
+}
+
+// scan calls fn for the entries of the bucket at cursor and returns the
+// cursor of the next bucket, 0 once every bucket was visited.
+//
+// The cursor counts with its bits reversed, so that when the table grows
+// or shrinks between calls the buckets already visited map to buckets that
+// are before the new cursor. Every key present during the whole iteration
+// is returned, some may be returned more than once.
+func (d *dict) scan(cursor uint64, fn func(e *entry)) uint64 {
+	mask := uint64(len(d.table) - 1)
+	for e := d.table[cursor&mask]; e != nil; e = e.next {
+		fn(e)
+	}
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
+
+// random returns a random entry, or nil when the dict is empty.
+func (d *dict) random() *entry {
+	if d.size == 0 {
+		return nil
+	}
+	var head *entry
+	for head == nil {
+		head = d.table[rand.Intn(len(d.table))]
+	}
+	n := 0
+	for e := head; e != nil; e = e.next {
+		n++
+	}
+	e := head
+	for i := rand.Intn(n); i > 0; i-- {
+		e = e.next
+	}
+	return e
+}
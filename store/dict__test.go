@@ -0,0 +1,69 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test__dict(t *testing.T) {
+	d := newDict()
+	for i := 0; i < 1000; i++ {
+		d.set(strconv.Itoa(i), []byte{byte(i)})
+	}
+	d.set("7", []byte("x"))
+	if v, ok := d.get("7"); !ok || string(v.([]byte)) != "x" || d.size != 1000 {
+		t.Errorf("get(7): got %q, %v with %d keys", v, ok, d.size)
+	}
+	for i := 0; i < 990; i++ {
+		if !d.del(strconv.Itoa(i)) {
+			t.Fatalf("del(%d): not found", i)
+		}
+	}
+	if d.del("0") || d.size != 10 || len(d.table) > 64 {
+		t.Errorf("got %d keys in %d buckets", d.size, len(d.table))
+	}
+	if e := d.random(); e == nil || e.key < "990" {
+		t.Errorf("random: got %v", e)
+	}
+}
+
+func Test__dictScan(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(d *dict, step int)
+	}{
+		{"grow", func(d *dict, step int) {
+			d.set("new"+strconv.Itoa(step), nil)
+		}},
+		{"shrink", func(d *dict, step int) {
+			for i := 0; i < 10; i++ {
+				d.del("tmp" + strconv.Itoa(step*10+i))
+			}
+		}},
+	}
+	for _, tt := range tests {
+		d := newDict()
+		for i := 0; i < 100; i++ {
+			d.set("key"+strconv.Itoa(i), nil)
+		}
+		for i := 0; i < 2000; i++ {
+			d.set("tmp"+strconv.Itoa(i), nil)
+		}
+		seen := map[string]bool{}
+		cursor, step := uint64(0), 0
+		for {
+			cursor = d.scan(cursor, func(e *entry) { seen[e.key] = true })
+			if cursor == 0 {
+				break
+			}
+			tt.change(d, step)
+			step++
+		}
+		// keys that were there for the whole iteration are all returned
+		for i := 0; i < 100; i++ {
+			if !seen["key"+strconv.Itoa(i)] {
+				t.Errorf("%s: key%d wasn't returned", tt.name, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package store
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// entryOverhead approximates the memory an entry takes besides its key and
+// value: the entry itself and its bucket pointer.
+const entryOverhead = 64
+
+// Policy decides which keys are evicted once a Store uses more memory than
+// its limit.
+type Policy int
+
+const (
+	NoEviction Policy = iota
+	AllKeysLRU
+	AllKeysLFU
+	AllKeysRandom
+	VolatileLRU
+	VolatileLFU
+	VolatileRandom
+	VolatileTTL
+)
+
+var policyNames = map[string]Policy{
+	"noeviction":      NoEviction,
+	"allkeys-lru":     AllKeysLRU,
+	"allkeys-lfu":     AllKeysLFU,
+	"allkeys-random":  AllKeysRandom,
+	"volatile-lru":    VolatileLRU,
+	"volatile-lfu":    VolatileLFU,
+	"volatile-random": VolatileRandom,
+	"volatile-ttl":    VolatileTTL,
+}
+
+// ParsePolicy returns the policy called name in the maxmemory-policy format,
+// e.g. "allkeys-lru".
+func ParsePolicy(name string) (Policy, bool) {
+	p, ok := policyNames[name]
+	return p, ok
+}
+
+func (p Policy) String() string {
+	for name, q := range policyNames {
+		if p == q {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func (p Policy) lfu() bool {
+	return p == AllKeysLFU || p == VolatileLFU
+}
+
+// Eviction configures the memory limit of a Store. The zero value sets no
+// limit.
+type Eviction struct {
+	// MaxMemory is the limit in bytes, 0 for none.
+	MaxMemory int64
+	Policy    Policy
+	// Samples is the number of keys sampled by the LRU and LFU policies
+	// for each database, 5 if 0.
+	Samples int
+	// LFULogFactor and LFUDecayTime tune the LFU counters like the
+	// lfu-log-factor and lfu-decay-time parameters of Redis.
+	LFULogFactor int
+	LFUDecayTime int
+}
+
+// SetEviction changes the memory limit of the store. It is enforced by
+// Evict.
+func (kv *Store) SetEviction(ev Eviction) {
+	if ev.Samples <= 0 {
+		ev.Samples = 5
+	}
+	kv.ev.Store(ev)
+}
+
+func (kv *Store) eviction() Eviction {
+	ev, _ := kv.ev.Load().(Eviction)
+	return ev
+}
+
+// UsedMemory returns an estimate of the memory used by the keys and values
+// of every database.
+func (kv *Store) UsedMemory() int64 {
+	kv.mu.Lock()
+	dbs := append([]*DB(nil), kv.dbs...)
+	kv.mu.Unlock()
+	var n int64
+	for _, db := range dbs {
+		n += db.memory()
+	}
+	return n
+}
+
+// EvictedKeys returns the number of keys evicted since the store was
+// opened or ResetStats was called.
+func (kv *Store) EvictedKeys() int64 {
+	return atomic.LoadInt64(&kv.evicted)
+}
+
+// ResetStats zeroes the eviction counter.
+func (kv *Store) ResetStats() {
+	atomic.StoreInt64(&kv.evicted, 0)
+}
+
+// evictionPoolSize is the number of candidates kept between evictions, as
+// in Redis.
+const evictionPoolSize = 16
+
+// candidate is a key that may be evicted. The key with the highest idle
+// score goes first.
+type candidate struct {
+	idle uint64
+	db   int
+	key  string
+}
+
+// Evict removes keys following the eviction policy until the used memory
+// is under the limit. It reports false when the memory is still over the
+// limit, because of the noeviction policy or because nothing could be
+// evicted.
+//
+// Like Redis, the LRU and LFU policies are approximated: a few keys are
+// sampled from every database and the best candidates are kept in a pool
+// that improves over successive evictions.
+func (kv *Store) Evict() bool {
+	ev := kv.eviction()
+	if ev.MaxMemory == 0 {
+		return true
+	}
+	kv.evictMu.Lock()
+	defer kv.evictMu.Unlock()
+	for kv.UsedMemory() > ev.MaxMemory {
+		if !kv.evictOne(ev) {
+			return false
+		}
+		atomic.AddInt64(&kv.evicted, 1)
+	}
+	return true
+}
+
+func (kv *Store) evictOne(ev Eviction) bool {
+	kv.mu.Lock()
+	dbs := append([]*DB(nil), kv.dbs...)
+	kv.mu.Unlock()
+	switch ev.Policy {
+	case AllKeysRandom:
+		for range dbs {
+			kv.nextDB = (kv.nextDB + 1) % len(dbs)
+			db := dbs[kv.nextDB]
+			if key, ok := db.RandomKey(); ok {
+				db.Del([]byte(key))
+				return true
+			}
+		}
+		return false
+	case AllKeysLRU, AllKeysLFU:
+	default:
+		// the volatile policies only evict keys with an expire, which
+		// tiny-redis doesn't have
+		return false
+	}
+	for {
+		total := 0
+		for i, db := range dbs {
+			total += db.Len()
+			for _, c := range db.sample(ev) {
+				c.db = i
+				kv.addCandidate(c)
+			}
+		}
+		if total == 0 {
+			return false
+		}
+		// the best candidate that still exists
+		for len(kv.pool) > 0 {
+			c := kv.pool[len(kv.pool)-1]
+			kv.pool = kv.pool[:len(kv.pool)-1]
+			if dbs[c.db].delete(c.key) {
+				return true
+			}
+		}
+	}
+}
+
+// addCandidate inserts c into the pool, which is sorted by increasing idle
+// score. When the pool is full c replaces the worst candidate, if it is
+// better.
+func (kv *Store) addCandidate(c candidate) {
+	i := 0
+	for i < len(kv.pool) && kv.pool[i].idle < c.idle {
+		i++
+	}
+	for j := range kv.pool {
+		if kv.pool[j].key == c.key && kv.pool[j].db == c.db {
+			// already a candidate, its score is refreshed
+			kv.pool = append(kv.pool[:j], kv.pool[j+1:]...)
+			if j < i {
+				i--
+			}
+			break
+		}
+	}
+	if len(kv.pool) == evictionPoolSize {
+		if i == 0 {
+			return
+		}
+		kv.pool = kv.pool[1:]
+		i--
+	}
+	kv.pool = append(kv.pool, candidate{})
+	copy(kv.pool[i+1:], kv.pool[i:])
+	kv.pool[i] = c
+}
+
+// The access clock of an entry holds, depending on the policy, either the
+// LRU clock of its last access or, for LFU, the minute it was last
+// decremented in the high 16 bits and a logarithmic access counter in the
+// low 8 bits. It is the layout of the lru field of Redis objects.
+const (
+	lruClockMax = 1<<24 - 1
+	lfuInitVal  = 5
+)
+
+// now is replaced by tests to control the clocks.
+var now = time.Now
+
+func lruClock() uint32 {
+	return uint32(now().Unix() & lruClockMax)
+}
+
+func lfuMinutes() uint32 {
+	return uint32(now().Unix()/60) & 65535
+}
+
+// initClock returns the clock of a new entry.
+func (ev Eviction) initClock() uint32 {
+	if ev.Policy.lfu() {
+		return lfuMinutes()<<8 | lfuInitVal
+	}
+	return lruClock()
+}
+
+// accessClock returns the clock of an entry accessed now.
+func (ev Eviction) accessClock(clock uint32) uint32 {
+	if !ev.Policy.lfu() {
+		return lruClock()
+	}
+	counter := ev.lfuDecr(clock)
+	if counter < 255 {
+		base := float64(counter) - lfuInitVal
+		if base < 0 {
+			base = 0
+		}
+		if rand.Float64() < 1/(base*float64(ev.LFULogFactor)+1) {
+			counter++
+		}
+	}
+	return lfuMinutes()<<8 | counter
+}
+
+// lfuDecr returns the LFU counter of clock, decremented once for every
+// LFUDecayTime minutes since it was last decremented.
+func (ev Eviction) lfuDecr(clock uint32) uint32 {
+	ldt, counter := clock>>8, clock&255
+	if ev.LFUDecayTime == 0 {
+		return counter
+	}
+	minutes := lfuMinutes()
+	elapsed := minutes - ldt
+	if minutes < ldt {
+		elapsed = 65535 - ldt + minutes
+	}
+	periods := elapsed / uint32(ev.LFUDecayTime)
+	if periods > counter {
+		return 0
+	}
+	return counter - periods
+}
+
+// idle returns the eviction score of an entry: the higher, the sooner it
+// is evicted.
+func (ev Eviction) idle(clock uint32) uint64 {
+	if ev.Policy.lfu() {
+		return uint64(255 - ev.lfuDecr(clock))
+	}
+	c := lruClock()
+	if c >= clock {
+		return uint64(c - clock)
+	}
+	return uint64(c + lruClockMax - clock)
+}
+
+// sample returns the eviction candidates among a few random keys of db.
+func (db *DB) sample(ev Eviction) []candidate {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	n := ev.Samples
+	if n > db.d.size {
+		n = db.d.size
+	}
+	r := make([]candidate, 0, n)
+	for i := 0; i < n; i++ {
+		e := db.d.random()
+		r = append(r, candidate{idle: ev.idle(atomic.LoadUint32(&e.clock)), key: e.key})
+	}
+	return r
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test__Evict(t *testing.T) {
+	defer func() { now = time.Now }()
+	start := time.Now()
+	tests := []struct {
+		policy Policy
+		// access makes 0-29 the keys to evict first and 50-99 the most used
+		access func(kv *Store)
+	}{
+		{AllKeysLRU, func(kv *Store) {
+			for i := 0; i < 100; i++ {
+				now = func() time.Time { return start.Add(time.Duration(i) * time.Second) }
+				kv.Get([]byte(strconv.Itoa(i)))
+			}
+		}},
+		{AllKeysLFU, func(kv *Store) {
+			for i := 30; i < 100; i++ {
+				n := 1
+				if i >= 50 {
+					n = 100
+				}
+				for j := 0; j < n; j++ {
+					kv.Get([]byte(strconv.Itoa(i)))
+				}
+			}
+		}},
+	}
+	for _, tt := range tests {
+		now = func() time.Time { return start }
+		kv := OpenDatabases(filepath.Join(os.TempDir(), "nosuch.trdb"), 2)
+		kv.SetEviction(Eviction{Policy: tt.policy, Samples: 64, LFULogFactor: 1, LFUDecayTime: 1})
+		for i := 0; i < 100; i++ {
+			kv.Set([]byte(strconv.Itoa(i)), make([]byte, 100))
+		}
+		tt.access(kv)
+		limit := kv.UsedMemory() - 20*(entryOverhead+100)
+		kv.SetEviction(Eviction{MaxMemory: limit, Policy: tt.policy, Samples: 64, LFULogFactor: 1, LFUDecayTime: 1})
+		if !kv.Evict() || kv.UsedMemory() > limit {
+			t.Fatalf("%v: the memory is still over the limit", tt.policy)
+		}
+		if kv.EvictedKeys() < 20 {
+			t.Errorf("%v: got %d evicted keys", tt.policy, kv.EvictedKeys())
+		}
+		// the approximation may spare a few of the keys to evict first, but
+		// not the most used ones
+		for i := 50; i < 100; i++ {
+			if _, ok := kv.Get([]byte(strconv.Itoa(i))); !ok {
+				t.Errorf("%v: key %d was evicted", tt.policy, i)
+			}
+		}
+	}
+}
+
+func Test__EvictPolicies(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		ok     bool
+	}{
+		{NoEviction, false},
+		{AllKeysRandom, true},
+		// no key has an expire
+		{VolatileLRU, false},
+		{VolatileTTL, false},
+	}
+	for _, tt := range tests {
+		kv := OpenDatabases(filepath.Join(os.TempDir(), "nosuch.trdb"), 4)
+		for i := 0; i < 100; i++ {
+			kv.DB(i%4).Set([]byte(strconv.Itoa(i)), []byte("v"))
+		}
+		limit := kv.UsedMemory() / 2
+		kv.SetEviction(Eviction{MaxMemory: limit, Policy: tt.policy})
+		if ok := kv.Evict(); ok != tt.ok || (kv.UsedMemory() <= limit) != tt.ok {
+			t.Errorf("%v: Evict got %v with %d/%d bytes used", tt.policy, ok, kv.UsedMemory(), limit)
+		}
+		if !tt.ok && kv.EvictedKeys() != 0 {
+			t.Errorf("%v: got %d evicted keys", tt.policy, kv.EvictedKeys())
+		}
+	}
+	if p, ok := ParsePolicy("allkeys-lfu"); !ok || p != AllKeysLFU || p.String() != "allkeys-lfu" {
+		t.Errorf("ParsePolicy(allkeys-lfu): got %v, %v", p, ok)
+	}
+}
@@ -5,90 +5,225 @@ import (
 	"encoding/gob"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 )
 
-// should be part of the Store struct
 var defaultPath = "dump.trdb"
 
+// DefaultDatabases is the number of databases of the stores created by New
+// and Open.
+const DefaultDatabases = 16
+
+// Store is the keyspace of a server: numbered databases persisted together
+// in one snapshot.
 type Store struct {
-	underlying sync.Map
+	// evicted is the number of keys evicted. It is accessed atomically and
+	// kept first for 64 bit alignment.
+	evicted int64
+
+	mu sync.Mutex
+	// path is where Save writes snapshots
+	path string
+	dbs  []*DB
+
+	// ev holds the Eviction settings
+	ev      atomic.Value
+	evictMu sync.Mutex
+	// pool and nextDB are the state of Evict
+	pool   []candidate
+	nextDB int
 }
 
 func New() *Store {
-	kv := Store{
-		underlying: *new(sync.Map),
+	return Open(defaultPath)
+}
+
+// Open creates a store that is persisted at path, loading the snapshot
+// found there if any.
+func Open(path string) *Store {
+	return OpenDatabases(path, DefaultDatabases)
+}
+
+// OpenDatabases is like Open for a store with n databases.
+func OpenDatabases(path string, n int) *Store {
+	kv := &Store{
+		path: path,
+		dbs:  make([]*DB, n),
+	}
+	for i := range kv.dbs {
+		kv.dbs[i] = newDB(kv)
 	}
-	ok := kv.Load(defaultPath)
+	ok := kv.Load(path)
 	if ok {
-		fmt.Printf("DB loaded from disk: %s\n", defaultPath)
+		fmt.Printf("DB loaded from disk: %s\n", path)
+	}
+	return kv
+}
+
+// Path returns the file snapshots are saved to.
+func (kv *Store) Path() string {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.path
+}
+
+// SetPath changes the file snapshots are saved to.
+func (kv *Store) SetPath(path string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.path = path
+}
+
+// Databases returns the number of databases.
+func (kv *Store) Databases() int {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return len(kv.dbs)
+}
+
+// DB returns the database at index i, which must be below Databases.
+func (kv *Store) DB(i int) *DB {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.dbs[i]
+}
+
+// Swap exchanges the contents of the databases at i and j.
+func (kv *Store) Swap(i, j int) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.dbs[i], kv.dbs[j] = kv.dbs[j], kv.dbs[i]
+}
+
+// FlushAll removes every key of every database.
+func (kv *Store) FlushAll() {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	for _, db := range kv.dbs {
+		db.Flush()
 	}
-	// fmt.Printf("%#v\n", kv)
-	return &kv
 }
 
+// record is a value in a snapshot: a string, or a sorted set when ZSet is
+// set.
+type record struct {
+	String []byte
+	ZSet   []ZMember
+}
+
+func (r record) value() interface{} {
+	if r.ZSet == nil {
+		return r.String
+	}
+	z := NewZSet()
+	for _, m := range r.ZSet {
+		z.Add(m.Member, m.Score)
+	}
+	return z
+}
+
+// Load replaces the databases with the snapshot at path. Snapshots written
+// before databases were numbered are loaded into database 0, and the ones
+// written before sorted sets only hold strings.
 func (kv *Store) Load(path string) bool {
-	f, err := os.Open(path)
+	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false
 		}
 		panic(err)
 	}
-	defer f.Close()
-	var tmp map[string][]byte
-	if err = gob.NewDecoder(f).Decode(&tmp); err != nil {
-		panic(err)
+	var tmp []map[string]record
+	if err = gob.NewDecoder(bytes.NewReader(b)).Decode(&tmp); err != nil {
+		var strs []map[string][]byte
+		if gob.NewDecoder(bytes.NewReader(b)).Decode(&strs) != nil {
+			var legacy map[string][]byte
+			if gob.NewDecoder(bytes.NewReader(b)).Decode(&legacy) != nil {
+				panic(err)
+			}
+			strs = []map[string][]byte{legacy}
+		}
+		tmp = make([]map[string]record, len(strs))
+		for i, m := range strs {
+			tmp[i] = make(map[string]record, len(m))
+			for k, v := range m {
+				tmp[i][k] = record{String: v}
+			}
+		}
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if len(tmp) > len(kv.dbs) {
+		panic(fmt.Errorf("store: %s has %d databases, only %d are configured", path, len(tmp), len(kv.dbs)))
+	}
+	for i := range kv.dbs {
+		kv.dbs[i] = newDB(kv)
+		if i < len(tmp) {
+			for k, r := range tmp[i] {
+				kv.dbs[i].SetValue([]byte(k), r.value())
+			}
+		}
 	}
-	kv.underlying = *toSyncMap(&tmp)
 	return true
 }
 
-func (kv *Store) Save() {
-	f, err := os.OpenFile(defaultPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// Save writes a snapshot of the store to disk. The snapshot is written to a
+// temporary file first and renamed over the previous one, so a failed save
+// never leaves a truncated dump behind.
+func (kv *Store) Save() error {
+	b := new(bytes.Buffer)
+	kv.mu.Lock()
+	tmp := make([]map[string]record, len(kv.dbs))
+	for i, db := range kv.dbs {
+		tmp[i] = db.snapshot()
+	}
+	kv.mu.Unlock()
+	if err := gob.NewEncoder(b).Encode(tmp); err != nil {
+		return err
+	}
+	path := kv.Path()
+	dir, file := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "temp-"+file)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer f.Close()
-	b := new(bytes.Buffer)
-	tmp := fromSyncMap(&kv.underlying)
-	if err = gob.NewEncoder(b).Encode(tmp); err != nil {
-		panic(err)
+	defer os.Remove(f.Name())
+	if err = f.Chmod(0644); err != nil {
+		f.Close()
+		return err
 	}
 	if _, err = io.Copy(f, b); err != nil {
-		panic(err)
+		f.Close()
+		return err
 	}
-}
-
-func fromSyncMap(sm *sync.Map) *map[string][]byte {
-	tmp := make(map[string][]byte)
-	sm.Range(func(k, v interface{}) bool {
-		tmp[k.(string)] = v.([]byte)
-		return true
-	})
-	return &tmp
-}
-
-func toSyncMap(m *map[string][]byte) *sync.Map {
-	sm := sync.Map{}
-	for k, v := range *m {
-		sm.Store(k, v)
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
 	}
-	return &sm
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
 }
 
+// Set stores value at key in database 0.
 func (kv *Store) Set(key []byte, value []byte) {
-	kv.underlying.Store(string(key), value)
+	kv.DB(0).Set(key, value)
 }
 
+// Get returns the value of key in database 0.
 func (kv *Store) Get(key []byte) (value []byte, ok bool) {
-	if v, ok := kv.underlying.Load(string(key)); ok {
-		return v.([]byte), true
-	}
-	return nil, false
+	return kv.DB(0).Get(key)
 }
 
+// Del removes key from database 0.
 func (kv *Store) Del(key []byte) {
-	kv.underlying.Delete(string(key))
+	kv.DB(0).Del(key)
 }
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test__SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dump.trdb")
+
+	kv := OpenDatabases(path, 4)
+	kv.DB(0).Set([]byte("a"), []byte("0"))
+	kv.DB(3).Set([]byte("a"), []byte("3"))
+	z := NewZSet()
+	z.Add("m", 1.5)
+	kv.DB(1).SetValue([]byte("z"), z)
+	if err := kv.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := OpenDatabases(path, 4)
+	for i, expected := range []string{"0", "", "", "3"} {
+		v, _ := loaded.DB(i).Get([]byte("a"))
+		if string(v) != expected {
+			t.Errorf("DB %d: got %q want %q", i, v, expected)
+		}
+	}
+	if v, _ := loaded.DB(1).Value([]byte("z")); v == nil || v.(*ZSet).Len() != 1 {
+		t.Errorf("sorted set: got %v", v)
+	} else if score, _ := v.(*ZSet).Score("m"); score != 1.5 {
+		t.Errorf("sorted set: got score %v", score)
+	}
+
+	// snapshots from before sorted sets only hold strings
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gob.NewEncoder(f).Encode([]map[string][]byte{{}, {"strings": []byte("v")}})
+	f.Close()
+	loaded = OpenDatabases(path, 4)
+	if v, ok := loaded.DB(1).Get([]byte("strings")); !ok || string(v) != "v" {
+		t.Errorf("snapshot of strings: got %q, %v", v, ok)
+	}
+
+	// snapshots from before numbered databases hold a single map
+	f, err = os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gob.NewEncoder(f).Encode(map[string][]byte{"legacy": []byte("v")})
+	f.Close()
+	loaded = OpenDatabases(path, 4)
+	if v, ok := loaded.Get([]byte("legacy")); !ok || string(v) != "v" {
+		t.Errorf("legacy snapshot: got %q, %v", v, ok)
+	}
+}
+
+func Test__Swap(t *testing.T) {
+	kv := OpenDatabases(filepath.Join(os.TempDir(), "nosuch.trdb"), 2)
+	kv.DB(0).Set([]byte("k"), []byte("v"))
+	kv.Swap(0, 1)
+	if _, ok := kv.Get([]byte("k")); ok || kv.DB(1).Len() != 1 {
+		t.Errorf("Swap: the databases weren't exchanged")
+	}
+	kv.FlushAll()
+	if kv.DB(1).Len() != 0 {
+		t.Errorf("FlushAll: got %d keys", kv.DB(1).Len())
+	}
+}
+
+func Test__Update(t *testing.T) {
+	db := New().DB(0)
+	key := []byte("k")
+	set := func(i int) func(v []byte) {
+		return func(v []byte) { v[i] = 'x' }
+	}
+	db.Set(key, []byte("ab"))
+	v, _ := db.Get(key)
+	db.Update(key, 4, set(3))
+	if string(v) != "ab" {
+		t.Errorf("Update modified a value returned by Get: %q", v)
+	}
+	if got, _ := db.Get(key); string(got) != "ab\x00x" {
+		t.Errorf("Update: got %q", got)
+	}
+
+	// the capacity grows greedily, later updates are in place
+	db.Update(key, 6, set(5))
+	first := &db.d.find("k").value.([]byte)[0]
+	db.Update(key, 8, set(0))
+	if &db.d.find("k").value.([]byte)[0] != first {
+		t.Errorf("Update copied a value that wasn't shared")
+	}
+	if got, _ := db.Borrow(key); string(got.([]byte)) != "xb\x00x\x00x\x00\x00" {
+		t.Errorf("Update: got %q", got)
+	}
+	db.Update(key, 0, set(2))
+	if &db.d.find("k").value.([]byte)[0] != first {
+		t.Errorf("Update copied a borrowed value")
+	}
+	db.Get(key)
+	db.Update(key, 0, set(1))
+	if &db.d.find("k").value.([]byte)[0] == first {
+		t.Errorf("Update didn't copy a value returned by Get")
+	}
+	if db.d.mem != entryOverhead+1+8 {
+		t.Errorf("Update: got %d bytes of memory", db.d.mem)
+	}
+}
@@ -0,0 +1,295 @@
+package store
+
+import "math/rand"
+
+// zmemberOverhead estimates the memory of a member besides its name: its
+// entry in the dict and its skiplist node.
+const zmemberOverhead = entryOverhead + 80
+
+// ZMember is a member of a sorted set and its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+func (m ZMember) less(o ZMember) bool {
+	if m.Score != o.Score {
+		return m.Score < o.Score
+	}
+	return m.Member < o.Member
+}
+
+// ZSet is a sorted set: unique members ordered by score, then by member.
+// Like in Redis, a dict maps the members to their nodes in a skiplist that
+// keeps them in order, so updates and lookups by rank take O(log n).
+//
+// It isn't safe for concurrent use. A ZSet held by a DB is modified in place
+// and stored again with SetValue, which accounts for its new size.
+type ZSet struct {
+	// dict maps the members to their *zslNode
+	dict *dict
+	zsl  *zskiplist
+	mem  int64
+}
+
+func NewZSet() *ZSet {
+	return &ZSet{dict: newDict(), zsl: newZskiplist()}
+}
+
+// Len returns the number of members.
+func (z *ZSet) Len() int {
+	return z.zsl.length
+}
+
+// Score returns the score of member.
+func (z *ZSet) Score(member string) (score float64, ok bool) {
+	if e := z.dict.find(member); e != nil {
+		return e.value.(*zslNode).Score, true
+	}
+	return 0, false
+}
+
+// Add sets the score of member and reports whether it is a new member.
+func (z *ZSet) Add(member string, score float64) bool {
+	e := z.dict.find(member)
+	if e != nil {
+		n := e.value.(*zslNode)
+		if n.Score == score {
+			return false
+		}
+		z.zsl.delete(n.ZMember)
+		e.value = z.zsl.insert(ZMember{member, score})
+		return false
+	}
+	z.dict.set(member, z.zsl.insert(ZMember{member, score}))
+	z.mem += zmemberOverhead + int64(len(member))
+	return true
+}
+
+// Remove deletes member and reports whether it existed.
+func (z *ZSet) Remove(member string) bool {
+	e := z.dict.find(member)
+	if e == nil {
+		return false
+	}
+	z.zsl.delete(e.value.(*zslNode).ZMember)
+	z.dict.del(member)
+	z.mem -= zmemberOverhead + int64(len(member))
+	return true
+}
+
+// At returns the member of rank i, starting at 0.
+func (z *ZSet) At(i int) ZMember {
+	return z.zsl.byRank(i + 1).ZMember
+}
+
+// Range calls fn in order for the members of rank start to stop included,
+// starting at 0, until it returns false. With rev the ranks are counted
+// from the last member and the members are visited backwards.
+func (z *ZSet) Range(start, stop int, rev bool, fn func(m ZMember) bool) {
+	if start < 0 || start > stop || start >= z.Len() {
+		return
+	}
+	rank := start + 1
+	if rev {
+		rank = z.Len() - start
+	}
+	for x := z.zsl.byRank(rank); x != nil && start <= stop; start++ {
+		if !fn(x.ZMember) {
+			return
+		}
+		if rev {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+}
+
+// Between calls fn in order for the members whose score is at least min
+// and less than max, until it returns false.
+func (z *ZSet) Between(min, max float64, fn func(m ZMember) bool) {
+	for x := z.zsl.firstAtLeast(min); x != nil && x.Score < max; x = x.level[0].forward {
+		if !fn(x.ZMember) {
+			return
+		}
+	}
+}
+
+// Scan continues the iteration of the members at cursor like DB.Scan.
+func (z *ZSet) Scan(cursor uint64, count int, fn func(m ZMember)) uint64 {
+	return scanDict(z.dict, cursor, count, func(e *entry) {
+		fn(e.value.(*zslNode).ZMember)
+	})
+}
+
+// Clone returns a copy of z.
+func (z *ZSet) Clone() *ZSet {
+	c := NewZSet()
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		c.Add(x.Member, x.Score)
+	}
+	return c
+}
+
+// Members returns a copy of the members in order.
+func (z *ZSet) Members() []ZMember {
+	ms := make([]ZMember, 0, z.Len())
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		ms = append(ms, x.ZMember)
+	}
+	return ms
+}
+
+func (z *ZSet) memory() int64 {
+	return z.mem
+}
+
+const (
+	// zslMaxLevel is enough for 2^64 members
+	zslMaxLevel = 32
+	// zslP is the probability of a node to have one more level
+	zslP = 0.25
+)
+
+// zskiplist is the skiplist of zset.c in Redis: the links of every level
+// record the number of nodes they skip, their span, so that the rank of a
+// node is found along with it.
+type zskiplist struct {
+	header, tail *zslNode
+	length       int
+	level        int
+}
+
+type zslNode struct {
+	ZMember
+	backward *zslNode
+	level    []zslLevel
+}
+
+type zslLevel struct {
+	forward *zslNode
+	span    int
+}
+
+func newZskiplist() *zskiplist {
+	return &zskiplist{header: &zslNode{level: make([]zslLevel, zslMaxLevel)}, level: 1}
+}
+
+func zslRandomLevel() int {
+	level := 1
+	for level < zslMaxLevel && rand.Float64() < zslP {
+		level++
+	}
+	return level
+}
+
+// insert adds m, which must not be in zsl yet, and returns its node.
+func (zsl *zskiplist) insert(m ZMember) *zslNode {
+	var update [zslMaxLevel]*zslNode
+	var rank [zslMaxLevel]int
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		// rank is the rank of update[i]
+		if i != zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.less(m) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	level := zslRandomLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+	x = &zslNode{ZMember: m, level: make([]zslLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	// the links above the node now skip it too
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+	return x
+}
+
+// delete removes m and reports whether it was found.
+func (zsl *zskiplist) delete(m ZMember) bool {
+	var update [zslMaxLevel]*zslNode
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.less(m) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x == nil || x.ZMember != m {
+		return false
+	}
+	for i := 0; i < zsl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+	return true
+}
+
+// byRank returns the node of rank r, starting at 1, nil if there is none.
+func (zsl *zskiplist) byRank(r int) *zslNode {
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= r {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == r && x != zsl.header {
+			return x
+		}
+	}
+	return nil
+}
+
+// firstAtLeast returns the first node whose score is at least min, nil if
+// there is none.
+func (zsl *zskiplist) firstAtLeast(min float64) *zslNode {
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.Score < min {
+			x = x.level[i].forward
+		}
+	}
+	return x.level[0].forward
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func Test__ZSet(t *testing.T) {
+	z := NewZSet()
+	for _, m := range []ZMember{{"c", 2}, {"a", 2}, {"b", 1}, {"d", 3}} {
+		if !z.Add(m.Member, m.Score) {
+			t.Errorf("Add(%v): not a new member", m)
+		}
+	}
+	if z.Add("d", 0) || z.Add("d", 0) {
+		t.Errorf("Add: an update added a member")
+	}
+	if !z.Remove("c") || z.Remove("c") {
+		t.Errorf("Remove: got the wrong result")
+	}
+	want := []ZMember{{"d", 0}, {"b", 1}, {"a", 2}}
+	if got := z.Members(); !reflect.DeepEqual(got, want) || z.At(1) != want[1] {
+		t.Errorf("Members: got %v want %v", got, want)
+	}
+	var between []ZMember
+	z.Between(1, 2, func(m ZMember) bool {
+		between = append(between, m)
+		return true
+	})
+	if !reflect.DeepEqual(between, want[1:2]) {
+		t.Errorf("Between(1, 2): got %v", between)
+	}
+	if z.memory() != 3*zmemberOverhead+3 {
+		t.Errorf("memory: got %d", z.memory())
+	}
+
+	db := newDB(New())
+	db.SetValue([]byte("z"), z)
+	mem := db.memory()
+	z.Add("e", 4)
+	db.SetValue([]byte("z"), z)
+	if got := db.memory() - mem; got != zmemberOverhead+1 {
+		t.Errorf("memory of a modified sorted set: grew by %d", got)
+	}
+}
+
+func Test__ZSetOrder(t *testing.T) {
+	z := NewZSet()
+	scores := make(map[string]float64)
+	for i := 0; i < 5000; i++ {
+		m := strconv.Itoa(rand.Intn(1000))
+		if rand.Intn(3) == 0 {
+			_, ok := scores[m]
+			if z.Remove(m) != ok {
+				t.Fatalf("Remove(%s): got %v", m, !ok)
+			}
+			delete(scores, m)
+			continue
+		}
+		score := float64(rand.Intn(100))
+		_, ok := scores[m]
+		if z.Add(m, score) == ok {
+			t.Fatalf("Add(%s): got %v", m, ok)
+		}
+		scores[m] = score
+	}
+	want := make([]ZMember, 0, len(scores))
+	for m, score := range scores {
+		want = append(want, ZMember{m, score})
+	}
+	sort.Slice(want, func(i, j int) bool { return want[i].less(want[j]) })
+	if got := z.Members(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Members: got %d members, want %d", len(got), len(want))
+	}
+	for i, m := range want {
+		if got := z.At(i); got != m {
+			t.Errorf("At(%d): got %v want %v", i, got, m)
+		}
+	}
+	mem := int64(0)
+	for _, m := range want {
+		mem += zmemberOverhead + int64(len(m.Member))
+	}
+	if z.memory() != mem {
+		t.Errorf("memory: got %d want %d", z.memory(), mem)
+	}
+
+	n := len(want)
+	for _, tc := range []struct {
+		start, stop int
+		rev         bool
+		want        []ZMember
+	}{
+		{0, n - 1, false, want},
+		{1, 3, false, want[1:4]},
+		{n - 2, n + 5, false, want[n-2:]},
+		{0, 2, true, []ZMember{want[n-1], want[n-2], want[n-3]}},
+		{n - 1, n - 1, true, want[:1]},
+		{n, n + 1, false, nil},
+		{3, 2, false, nil},
+	} {
+		var got []ZMember
+		z.Range(tc.start, tc.stop, tc.rev, func(m ZMember) bool {
+			got = append(got, m)
+			return true
+		})
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Range(%d, %d, %v): got %v want %v", tc.start, tc.stop, tc.rev, got, tc.want)
+		}
+	}
+
+	seen := make(map[string]float64)
+	cursor := uint64(0)
+	for {
+		cursor = z.Scan(cursor, 10, func(m ZMember) {
+			seen[m.Member] = m.Score
+		})
+		if cursor == 0 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(seen, scores) {
+		t.Errorf("Scan: got %d members want %d", len(seen), len(scores))
+	}
+}